@@ -0,0 +1,87 @@
+package rollbar
+
+import (
+	"time"
+)
+
+const (
+	// occurrencesField is the key of the custom data field that tells how
+	// many identical occurrences a coalesced item stands for.
+	occurrencesField = "occurrences"
+
+	// occurrenceUUIDsField is the key of the custom data field that lists
+	// the UUIDs of the occurrences merged into a coalesced item.
+	occurrenceUUIDsField = "occurrence_uuids"
+)
+
+// batch is a group of identical queued envelopes that are delivered as one
+// item: the first one, which the others are merged into.
+type batch struct {
+	env    *envelope
+	count  int
+	merged []*Item
+}
+
+// collect gathers up to size queued envelopes, starting with first, waiting
+// at most interval for the batch to fill up.
+func (c *Client) collect(first *envelope, size int, interval time.Duration) []*envelope {
+	envs := []*envelope{first}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for len(envs) < size {
+		select {
+		case env, ok := <-c.queue:
+			if !ok {
+				return envs
+			}
+			envs = append(envs, env)
+		case <-timer.C:
+			return envs
+		}
+	}
+	return envs
+}
+
+// coalesce groups identical envelopes together, keeping the first envelope
+// of each group and the order in which groups first appeared. The first
+// envelope of a group with more than one occurrence gets an "occurrences"
+// custom field with the size of the group.
+func coalesce(envs []*envelope) []*batch {
+	var batches []*batch
+	byKey := map[string]*batch{}
+	for _, env := range envs {
+		key := env.key()
+		if b, ok := byKey[key]; ok {
+			b.count++
+			b.merged = append(b.merged, env.item)
+			continue
+		}
+		b := &batch{env: env, count: 1}
+		byKey[key] = b
+		batches = append(batches, b)
+	}
+
+	for _, b := range batches {
+		if b.count > 1 {
+			uuids := make([]string, len(b.merged))
+			for i, item := range b.merged {
+				uuids[i] = item.UUID
+			}
+			if data, ok := b.env.body["data"].(map[string]interface{}); ok {
+				mergeCustom(data, map[string]interface{}{
+					occurrencesField:     b.count,
+					occurrenceUUIDsField: uuids,
+				})
+			}
+		}
+	}
+	return batches
+}
+
+// key identifies envelopes that describe the same occurrence in the same
+// project.
+func (env *envelope) key() string {
+	token, _ := env.body["access_token"].(string)
+	return token + "\x00" + env.item.fingerprint()
+}
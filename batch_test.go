@@ -0,0 +1,112 @@
+package rollbar
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchingCoalescesIdenticalItems(t *testing.T) {
+	var mutex sync.Mutex
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mutex.Lock()
+		received = append(received, body)
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithWorkers(1), WithErrorWriter(nil),
+		WithBatching(51, time.Second))
+
+	err := errors.New("storm")
+	for i := 0; i < 50; i++ {
+		client.ErrorWithStack(ERR, err, Stack{{Filename: "storm.go", Method: "storm", Line: 1}})
+	}
+	client.Message(INFO, "different")
+	client.Wait()
+
+	if len(received) != 2 {
+		t.Fatalf("got %d requests", len(received))
+	}
+	data := received[0]["data"].(map[string]interface{})
+	custom, _ := data["custom"].(map[string]interface{})
+	if got, _ := custom[occurrencesField].(float64); got != 50 {
+		t.Errorf("got %v occurrences", custom[occurrencesField])
+	}
+	data = received[1]["data"].(map[string]interface{})
+	if _, ok := data["custom"]; ok {
+		t.Errorf("single occurrence should not have a count: %v", data["custom"])
+	}
+}
+
+func TestCoalesceKeepsDistinctItems(t *testing.T) {
+	env := func(level, title string) *envelope {
+		return &envelope{
+			item: newMessageItem(level, title),
+			body: map[string]interface{}{"access_token": "token", "data": map[string]interface{}{}},
+		}
+	}
+
+	batches := coalesce([]*envelope{env(ERR, "a"), env(ERR, "b"), env(WARN, "a"), env(ERR, "a")})
+	if len(batches) != 3 {
+		t.Fatalf("got %d batches", len(batches))
+	}
+	if batches[0].count != 2 || batches[1].count != 1 || batches[2].count != 1 {
+		t.Errorf("got counts %d, %d, %d", batches[0].count, batches[1].count, batches[2].count)
+	}
+}
+
+func TestBatchingReportsMergedOccurrences(t *testing.T) {
+	var mutex sync.Mutex
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mutex.Lock()
+		received = append(received, body)
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	sent := map[string]bool{}
+	client := New("token", WithEndpoint(server.URL), WithWorkers(1), WithErrorWriter(nil),
+		WithBatching(3, time.Second),
+		WithAfterSend(func(item *Item, payload []byte, err error) {
+			mutex.Lock()
+			sent[item.UUID] = err == nil
+			mutex.Unlock()
+		}))
+
+	err := errors.New("storm")
+	var uuids []string
+	for i := 0; i < 3; i++ {
+		uuid, _ := client.ErrorWithStack(ERR, err, Stack{{Filename: "storm.go", Method: "storm", Line: 1}})
+		uuids = append(uuids, uuid)
+	}
+	client.Wait()
+
+	if len(received) != 1 {
+		t.Fatalf("got %d requests", len(received))
+	}
+	data := received[0]["data"].(map[string]interface{})
+	if data["uuid"] != uuids[0] {
+		t.Errorf("should send the first occurrence, got UUID %v, want %s", data["uuid"], uuids[0])
+	}
+	custom, _ := data["custom"].(map[string]interface{})
+	merged, _ := custom[occurrenceUUIDsField].([]interface{})
+	if len(merged) != 2 || merged[0] != uuids[1] || merged[1] != uuids[2] {
+		t.Errorf("got merged UUIDs %v, want %v", custom[occurrenceUUIDsField], uuids[1:])
+	}
+	for _, uuid := range uuids {
+		if !sent[uuid] {
+			t.Errorf("should call the after send hook for occurrence %s", uuid)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package rollbar
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker is a circuit breaker around the Rollbar API. After threshold
+// consecutive delivery failures it opens, and items are dropped without being
+// POSTed until cooldown has passed. Then a single item is let through as a
+// probe: if it is delivered, the breaker closes again; if not, it stays open
+// for another cooldown. A breaker with a threshold of 0 never opens.
+type breaker struct {
+	mutex     sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+func (b *breaker) open() bool {
+	return b.threshold > 0 && b.failures >= b.threshold
+}
+
+// allow reports whether a POST may be attempted now.
+func (b *breaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.open() {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record updates the breaker with the outcome of a POST that allow let
+// through. Only failures that suggest Rollbar is unreachable or unhealthy
+// count.
+func (b *breaker) record(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	wasProbing := b.probing
+	b.probing = false
+
+	if err == nil || !retryable(err) {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if wasProbing || b.failures == b.threshold {
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,77 @@
+package rollbar
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	b := newBreaker(2, 20*time.Millisecond)
+	failure := errors.New("connection refused")
+
+	b.record(failure)
+	if !b.allow() {
+		t.Fatal("should stay closed below the threshold")
+	}
+	b.record(failure)
+	if b.allow() {
+		t.Fatal("should open at the threshold")
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("should let a probe through after the cooldown")
+	}
+	if b.allow() {
+		t.Fatal("should let only one probe through")
+	}
+	b.record(failure)
+	if b.allow() {
+		t.Fatal("should reopen after a failed probe")
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("should let a probe through after the cooldown")
+	}
+	b.record(nil)
+	if !b.allow() || !b.allow() {
+		t.Fatal("should close after a successful probe")
+	}
+}
+
+func TestBreakerIgnoresItemErrors(t *testing.T) {
+	b := newBreaker(1, time.Minute)
+	b.record(ErrHTTPError(422))
+	if !b.allow() {
+		t.Error("should not open on errors caused by the item")
+	}
+	if !newBreaker(0, time.Minute).allow() {
+		t.Error("should never open without a threshold")
+	}
+}
+
+func TestCircuitBreakerShortCircuits(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil),
+		WithRetries(0), WithCircuitBreaker(3, time.Minute))
+	for i := 0; i < 10; i++ {
+		_, err := client.Message(INFO, "outage")
+		if i >= 3 && err != ErrCircuitOpen {
+			t.Errorf("item %d: got %v", i, err)
+		}
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("got %d attempts", got)
+	}
+}
@@ -0,0 +1,187 @@
+package rollbar
+
+import (
+	"errors"
+	"reflect"
+)
+
+// maxChainLength bounds how many causes of an error are reported.
+const maxChainLength = 16
+
+// MultiErrorMode decides how errors that combine several errors, such as those
+// returned by errors.Join, are reported.
+type MultiErrorMode int
+
+const (
+	// MultiErrorChain reports each combined error, and its causes, as its own
+	// trace in the trace chain of a single item. It is the default.
+	MultiErrorChain MultiErrorMode = iota
+
+	// MultiErrorItems reports each combined error as a separate item.
+	MultiErrorItems
+)
+
+// Stacker is implemented by errors that carry the stack trace of where they
+// were created. Such errors, and their causes, are reported with their own
+// stack trace rather than the one of where they are reported.
+type Stacker interface {
+	error
+	Stack() Stack
+}
+
+// CauseStacker is implemented by errors that carry a stack trace and wrap the
+// error that caused them. Errors that implement Cause, as the errors of
+// github.com/pkg/errors do, are followed to their cause like those that
+// implement Unwrap, and each cause is reported in the trace chain.
+type CauseStacker interface {
+	Stacker
+	Cause() error
+}
+
+// ErrorData is implemented by errors that carry structured data about what
+// went wrong, such as the IDs of the records involved, so that domain error
+// types can describe themselves:
+//
+//	func (e *ChargeError) RollbarData() map[string]interface{} {
+//		return map[string]interface{}{"charge_id": e.ChargeID, "gateway": e.Gateway}
+//	}
+//
+// The data of a reported error and of each of its causes is merged into the
+// custom data of the item, that of outer errors winning over that of their
+// causes, and Custom Fields reported with the item over both.
+type ErrorData interface {
+	error
+	RollbarData() map[string]interface{}
+}
+
+// errorData returns the merged data of err and its causes that implement
+// ErrorData, or nil if none does.
+func errorData(err error) map[string]interface{} {
+	chain := errorChain(err)
+	var data map[string]interface{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		errData, ok := chain[i].(ErrorData)
+		if !ok {
+			continue
+		}
+		if data == nil {
+			data = make(map[string]interface{})
+		}
+		for key, value := range errData.RollbarData() {
+			data[key] = value
+		}
+	}
+	return data
+}
+
+// joinedErrors returns the errors combined by err, or nil if err does not
+// combine several errors. Besides errors.Join and fmt.Errorf with several %w
+// verbs, it understands the hashicorp/go-multierror and go.uber.org/multierr
+// conventions.
+func joinedErrors(err error) []error {
+	switch err := err.(type) {
+	case interface{ Unwrap() []error }:
+		return err.Unwrap()
+	case interface{ WrappedErrors() []error }:
+		return err.WrappedErrors()
+	case interface{ Errors() []error }:
+		return err.Errors()
+	}
+	return nil
+}
+
+// errorChain returns the given error followed by its causes, as found with
+// unwrap, outermost first. An error that combines several errors is
+// replaced by those errors and their causes, in order.
+func errorChain(err error) []error {
+	var chain []error
+	var walk func(err error)
+	walk = func(err error) {
+		for len(chain) < maxChainLength {
+			if joined := joinedErrors(err); len(joined) > 0 {
+				for _, e := range joined {
+					if e != nil {
+						walk(e)
+					}
+				}
+				return
+			}
+			chain = append(chain, err)
+			if err = unwrap(err); err == nil {
+				return
+			}
+		}
+	}
+	walk(err)
+	if len(chain) == 0 {
+		return []error{err}
+	}
+	return chain
+}
+
+// unwrap returns the error wrapped by err, as found with errors.Unwrap or,
+// failing that, with its Cause method (see CauseStacker), or nil.
+func unwrap(err error) error {
+	if cause := errors.Unwrap(err); cause != nil {
+		return cause
+	}
+	if causer, ok := err.(interface{ Cause() error }); ok {
+		if cause := causer.Cause(); cause != err {
+			return cause
+		}
+	}
+	return nil
+}
+
+// errorStack returns the stack trace of where the given error was created, if
+// it carries one, or nil: the Stack of a Stacker or, for errors returned by
+// Wrap and NewError or created by github.com/pkg/errors, the one returned by
+// their StackTrace method, which is built as configured by the given options.
+func errorStack(err error, opts stackOptions) Stack {
+	if stacker, ok := err.(Stacker); ok {
+		return stacker.Stack()
+	}
+	return stackFromPCs(stackTrace(err), opts)
+}
+
+// stackTrace calls the StackTrace method of errors created by
+// github.com/pkg/errors, which returns an errors.StackTrace: a slice of
+// program counters. It is called through reflection so that this package
+// does not depend on github.com/pkg/errors.
+func stackTrace(err error) []uintptr {
+	if err == nil {
+		return nil
+	}
+	if wrapped, ok := err.(*stackError); ok {
+		return wrapped.pcs
+	}
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() {
+		return nil
+	}
+	methodType := method.Type()
+	if methodType.NumIn() != 0 || methodType.NumOut() != 1 {
+		return nil
+	}
+	if out := methodType.Out(0); out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+
+	trace := method.Call(nil)[0]
+	pcs := make([]uintptr, trace.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(trace.Index(i).Uint())
+	}
+	return pcs
+}
+
+// errorTrace builds the Rollbar trace of a single error, of the given class.
+func errorTrace(err error, class string, stack Stack) map[string]interface{} {
+	return map[string]interface{}{
+		"frames": stack,
+		"exception": map[string]interface{}{
+			"class":   class,
+			"message": errorTitle(err),
+		},
+	}
+}
@@ -0,0 +1,267 @@
+package rollbar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+type multiError []error
+
+func (m multiError) Error() string          { return fmt.Sprintf("%d errors", len(m)) }
+func (m multiError) WrappedErrors() []error { return m }
+
+func chainMessages(chain []error) []string {
+	messages := make([]string, len(chain))
+	for i, err := range chain {
+		messages[i] = err.Error()
+	}
+	return messages
+}
+
+func TestErrorChainExpandsJoinedErrors(t *testing.T) {
+	first := errors.New("first")
+	second := fmt.Errorf("second: %w", errors.New("cause"))
+
+	tests := []error{
+		errors.Join(first, second),
+		multiError{first, second},
+	}
+	for _, err := range tests {
+		got := fmt.Sprint(chainMessages(errorChain(err)))
+		if want := "[first second: cause cause]"; got != want {
+			t.Errorf("%T: got %s, want %s", err, got, want)
+		}
+	}
+}
+
+func TestErrorChainLength(t *testing.T) {
+	var errs []error
+	for i := 0; i < 2*maxChainLength; i++ {
+		errs = append(errs, fmt.Errorf("error %d", i))
+	}
+	if chain := errorChain(errors.Join(errs...)); len(chain) != maxChainLength {
+		t.Errorf("got %d traces", len(chain))
+	}
+}
+
+func TestMultiErrorItems(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil),
+		WithMultiErrors(MultiErrorItems))
+
+	uuid, err := client.Error(ERR, errors.Join(errors.New("first"), errors.New("second")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transport.payloads) != 2 {
+		t.Fatalf("got %d items", len(transport.payloads))
+	}
+
+	var titles []string
+	for _, payload := range transport.payloads {
+		var body struct {
+			Data struct {
+				Title string `json:"title"`
+				UUID  string `json:"uuid"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(payload, &body); err != nil {
+			t.Fatal(err)
+		}
+		titles = append(titles, body.Data.Title)
+		if len(titles) == 1 && body.Data.UUID != uuid {
+			t.Errorf("should return the UUID of the first item, got %s", uuid)
+		}
+	}
+	if fmt.Sprint(titles) != "[first second]" {
+		t.Errorf("got titles %v", titles)
+	}
+}
+
+// pkgError mimics the errors of github.com/pkg/errors.
+type pkgError struct {
+	msg   string
+	cause error
+	stack []uintptr
+}
+
+type pkgFrame uintptr
+type pkgStackTrace []pkgFrame
+
+func newPkgError(msg string, cause error) error {
+	pcs := make([]uintptr, 32)
+	return &pkgError{msg, cause, pcs[:runtime.Callers(2, pcs)]}
+}
+
+func (e *pkgError) Error() string { return e.msg }
+func (e *pkgError) Unwrap() error { return e.cause }
+
+func (e *pkgError) StackTrace() pkgStackTrace {
+	trace := make(pkgStackTrace, len(e.stack))
+	for i, pc := range e.stack {
+		trace[i] = pkgFrame(pc)
+	}
+	return trace
+}
+
+func createdHere() error {
+	return newPkgError("created here", nil)
+}
+
+func TestErrorStack(t *testing.T) {
+	err := createdHere()
+	stack := errorStack(err, stackOptions{})
+	if len(stack) == 0 || stack[0].Method != "rollbar.createdHere" {
+		t.Fatalf("got %+v", stack)
+	}
+	if stack := errorStack(errors.New("plain"), stackOptions{}); stack != nil {
+		t.Errorf("got %+v", stack)
+	}
+
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+	client.Error(ERR, fmt.Errorf("wrapped: %w", err))
+
+	var body struct {
+		Data struct {
+			Body struct {
+				TraceChain []struct {
+					Frames []Frame `json:"frames"`
+				} `json:"trace_chain"`
+			} `json:"body"`
+			Fingerprint string `json:"fingerprint"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(transport.payloads[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	chain := body.Data.Body.TraceChain
+	if len(chain) != 2 {
+		t.Fatalf("got %d traces", len(chain))
+	}
+	if method := chain[0].Frames[0].Method; method != "rollbar.TestErrorStack" {
+		t.Errorf("should report the wrapping error with the reporting stack, got %s", method)
+	}
+	if method := chain[1].Frames[0].Method; method != "rollbar.createdHere" {
+		t.Errorf("should report the cause with its own stack, got %s", method)
+	}
+	if body.Data.Fingerprint != stack.Fingerprint() {
+		t.Error("should fingerprint the item by where the error was created")
+	}
+}
+
+func TestErrorStackAnnotations(t *testing.T) {
+	cause := errors.New("cause")
+	body, _ := errorBody(newPkgError(cause.Error(), cause), nil, configuration{})
+
+	trace, ok := body["trace"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("should collapse errors that only add a stack trace, got %+v", body)
+	}
+	if frames := trace["frames"].(Stack); len(frames) == 0 || frames[0].Method != "rollbar.TestErrorStackAnnotations" {
+		t.Errorf("got %+v", frames)
+	}
+}
+
+type queryError struct {
+	err   error
+	stack Stack
+}
+
+func (e *queryError) Error() string { return "query failed: " + e.err.Error() }
+func (e *queryError) Cause() error  { return e.err }
+func (e *queryError) Stack() Stack  { return e.stack }
+
+func TestCauseStacker(t *testing.T) {
+	var _ CauseStacker = &queryError{}
+
+	stack := Stack{Frame{Filename: "db.go", Method: "db.Query", Line: 42}}
+	err := &queryError{errors.New("connection reset"), stack}
+
+	body, fingerprint := errorBody(err, BuildStack(0), configuration{})
+	chain := body["trace_chain"].([]interface{})
+	if len(chain) != 2 {
+		t.Fatalf("should follow Cause, got %d traces", len(chain))
+	}
+	if frames := chain[0].(map[string]interface{})["frames"].(Stack); frames.Fingerprint() != stack.Fingerprint() {
+		t.Errorf("should report the Stacker with its own stack, got %+v", frames)
+	}
+	if fingerprint == stack.Fingerprint() {
+		t.Error("should fingerprint the item by its innermost cause")
+	}
+}
+
+type chargeError struct {
+	chargeID string
+	err      error
+}
+
+func (e *chargeError) Error() string { return "charge " + e.chargeID + ": " + e.err.Error() }
+func (e *chargeError) Unwrap() error { return e.err }
+func (e *chargeError) RollbarData() map[string]interface{} {
+	return map[string]interface{}{"charge_id": e.chargeID, "layer": "charge"}
+}
+
+type gatewayError struct{}
+
+func (e *gatewayError) Error() string { return "gateway timeout" }
+func (e *gatewayError) RollbarData() map[string]interface{} {
+	return map[string]interface{}{"gateway": "stripe", "layer": "gateway"}
+}
+
+func TestErrorData(t *testing.T) {
+	client := New("token")
+	client.SetCustom(map[string]interface{}{"service": "billing", "gateway": "default"})
+	err := fmt.Errorf("checkout: %w", &chargeError{"ch_1", &gatewayError{}})
+
+	body := client.buildError(ERR, err, BuildStack(0), Custom(map[string]interface{}{"layer": "call site"}))
+	custom := body["data"].(map[string]interface{})["custom"].(map[string]interface{})
+	want := map[string]interface{}{"service": "billing", "gateway": "stripe", "charge_id": "ch_1", "layer": "call site"}
+	if len(custom) != len(want) {
+		t.Errorf("got custom %v", custom)
+	}
+	for key, value := range want {
+		if custom[key] != value {
+			t.Errorf("got %s: %v", key, custom[key])
+		}
+	}
+
+	body = client.buildError(ERR, err, BuildStack(0))
+	if layer := body["data"].(map[string]interface{})["custom"].(map[string]interface{})["layer"]; layer != "charge" {
+		t.Errorf("outer errors should win, got layer %v", layer)
+	}
+	if data := errorData(errors.New("plain")); data != nil {
+		t.Errorf("got %v", data)
+	}
+}
+
+func TestErrorStackOptions(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil), WithCodeCapture(false))
+	client.Error(ERR, createdHere())
+
+	var body struct {
+		Data struct {
+			Body struct {
+				Trace struct {
+					Frames []Frame `json:"frames"`
+				} `json:"trace"`
+			} `json:"body"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(transport.payloads[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	frames := body.Data.Body.Trace.Frames
+	if len(frames) == 0 || frames[0].Method != "rollbar.createdHere" {
+		t.Fatalf("got %+v", frames)
+	}
+	for _, frame := range frames {
+		if frame.Code != "" {
+			t.Errorf("should build the stacks of errors with the Client's options, got code %q", frame.Code)
+		}
+	}
+}
@@ -0,0 +1,819 @@
+package rollbar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Client reports errors and messages to a single Rollbar project. Every
+// Client has its own configuration and its own queue of items waiting to be
+// POSTed, so any number of Clients can be used side by side in one process.
+type Client struct {
+	configMutex sync.RWMutex
+	config      configuration
+
+	// queue holds items waiting to be POSTed by the sender workers. pending
+	// counts items that have been queued but not yet delivered.
+	queue      chan *envelope
+	pending    pending
+	postErrors chan error
+
+	http     *httpTransport
+	breaker  *breaker
+	spool    *spool
+	counters counters
+	limiter  itemLimiter
+	sampler  sampler
+	deduper  deduper
+
+	telemetry *Telemetry
+
+	// rateLimitedUntil is when delivery may resume after Rollbar reported that
+	// the project hit its rate limit.
+	rateLimitMutex   sync.Mutex
+	rateLimitedUntil time.Time
+
+	// closeMutex guards closed and the closing of queue, and sending counts
+	// the POSTs of a synchronous Client in progress. stopping is closed
+	// when a shutdown starts, to wake up reporting calls blocked on a full
+	// queue. Cancelling ctx aborts in-flight deliveries when a shutdown runs
+	// out of time.
+	closeMutex     sync.RWMutex
+	closed         bool
+	sending        sync.WaitGroup
+	stopping       chan struct{}
+	stop           sync.Once
+	closeTransport sync.Once
+	done           chan struct{}
+	ctx            context.Context
+	cancel         context.CancelFunc
+}
+
+// -- Setup
+
+// New returns a Client that reports items to the Rollbar project identified
+// by the given access token. If token is blank, no items will be reported.
+// Options are applied in order and override the defaults (the "development"
+// environment, the public Rollbar API endpoint, http.DefaultClient, etc.).
+func New(token string, opts ...Option) *Client {
+	c := &Client{config: defaultConfiguration(token)}
+	c.configure(opts...)
+
+	c.start()
+
+	return c
+}
+
+// SetToken sets the Rollbar access token under which all items will be
+// reported. If the token is blank, no items will be reported.
+func (c *Client) SetToken(token string) {
+	c.configure(func(config *configuration) {
+		config.token = token
+	})
+}
+
+// SetEnvironment sets the environment under which all items will be reported.
+func (c *Client) SetEnvironment(environment string) {
+	c.configure(WithEnvironment(environment))
+}
+
+// SetPlatform sets the platform reported for all items. The default is the
+// running operating system (darwin, freebsd, linux, etc.) but it can also be
+// application specific (client, heroku, etc.).
+func (c *Client) SetPlatform(platform string) {
+	c.configure(WithPlatform(platform))
+}
+
+// SetNotifier sets the name and version of the notifier reported for all
+// items. See WithNotifier.
+func (c *Client) SetNotifier(name, version string) {
+	c.configure(WithNotifier(name, version))
+}
+
+// SetEndpoint sets the URL destination for all item POST requests.
+func (c *Client) SetEndpoint(endpoint string) {
+	c.configure(WithEndpoint(endpoint))
+}
+
+// SetBaseURL sets the base URL of the Rollbar API (e.g.
+// "https://rollbar.example.com/api/1/" for an on-premise install or a proxy
+// that rewrites hostnames). All API requests, including item POSTs, are made
+// relative to it.
+func (c *Client) SetBaseURL(baseURL string) {
+	c.configure(WithBaseURL(baseURL))
+}
+
+// SetScrubFields sets the field names whose values are scrubbed from request
+// headers, request params and custom data before items are sent. A field is
+// scrubbed if its name contains any of the given names, ignoring case. The
+// default is DefaultScrubFields.
+func (c *Client) SetScrubFields(fields ...string) {
+	c.configure(WithScrubFields(fields...))
+}
+
+// SetScrubPatterns sets regular expressions (e.g. `(?i)api[-_]?key`) that
+// match additional field names whose values are scrubbed from items. They
+// apply on top of the scrub fields.
+func (c *Client) SetScrubPatterns(patterns ...*regexp.Regexp) {
+	c.configure(WithScrubPatterns(patterns...))
+}
+
+// SetScrubFunc sets a custom redaction policy that is applied to every field
+// of an item's data not already scrubbed by the scrub fields and patterns.
+func (c *Client) SetScrubFunc(fn ScrubFunc) {
+	c.configure(WithScrubFunc(fn))
+}
+
+// SetFilterFields sets the regular expression that matches field names that
+// should not be sent to Rollbar. Values for these fields are replaced with
+// FILTERED.
+func (c *Client) SetFilterFields(filterFields *regexp.Regexp) {
+	c.configure(WithFilterFields(filterFields))
+}
+
+// SetErrorWriter sets the destination for errors encountered while POSTing
+// items to Rollbar, e.g. os.Stderr. By default, nothing is written. This can
+// be nil.
+func (c *Client) SetErrorWriter(w io.Writer) {
+	c.configure(WithErrorWriter(w))
+}
+
+// SetLogger sets the Logger that receives the Client's own diagnostics. By
+// default, they are discarded.
+func (c *Client) SetLogger(logger Logger) {
+	c.configure(WithLogger(logger))
+}
+
+// SetCodeVersion sets the code version reported for all items, the VCS
+// revision of the build by default. See WithCodeVersion.
+func (c *Client) SetCodeVersion(codeVersion string) {
+	c.configure(WithCodeVersion(codeVersion))
+}
+
+// SetHostname sets a custom hostname to use instead of os.Hostname() (on
+// Heroku, it can be useful to use `os.Getenv("DYNO")` as the UUID hostname is
+// rarely helpful).
+func (c *Client) SetHostname(hostname string) {
+	c.configure(WithHostname(hostname))
+}
+
+// SetServerRoot sets the path to the application code root on the server,
+// reported as server.root. Rollbar uses it to link stack frames to source
+// files in the repository.
+func (c *Client) SetServerRoot(root string) {
+	c.configure(WithServerRoot(root))
+}
+
+// SetServerBranch sets the checked out source control branch, reported as
+// server.branch.
+func (c *Client) SetServerBranch(branch string) {
+	c.configure(WithServerBranch(branch))
+}
+
+// SetEnabled turns reporting on or off. A disabled Client is a cheap no-op:
+// stack traces aren't built, source files aren't read and nothing is queued.
+func (c *Client) SetEnabled(enabled bool) {
+	c.configure(WithEnabled(enabled))
+}
+
+// SetCustom sets key / value pairs (a region, a service name, a build SHA,
+// etc.) that are merged into the custom section of every item. Custom data
+// passed with an individual item wins over these defaults.
+func (c *Client) SetCustom(custom map[string]interface{}) {
+	c.configure(WithCustom(custom))
+}
+
+// AddRoute sends items matched by match to the Rollbar project identified by
+// token instead of the Client's own project. Routes are checked in the order
+// they were added and the first match wins.
+func (c *Client) AddRoute(token string, match Matcher) {
+	c.configure(WithRoute(token, match))
+}
+
+// SetMinLevel sets the minimum severity level of the items reported. See
+// WithMinLevel.
+func (c *Client) SetMinLevel(level string) {
+	c.configure(WithMinLevel(level))
+}
+
+// SetPackageLevel sets the minimum severity level of the items reported from
+// the packages under the given import path prefix. See WithPackageLevel.
+func (c *Client) SetPackageLevel(prefix, level string) {
+	c.configure(WithPackageLevel(prefix, level))
+}
+
+// SetLevelAlias makes name an alias of the Rollbar severity level. See
+// WithLevelAlias.
+func (c *Client) SetLevelAlias(name, level string) {
+	c.configure(WithLevelAlias(name, level))
+}
+
+// AddLevelRule sets the severity level of the items matched by match. See
+// WithLevelRule.
+func (c *Client) AddLevelRule(level string, match Matcher) {
+	c.configure(WithLevelRule(level, match))
+}
+
+// AddIgnore drops the items matched by match before they are queued. See
+// WithIgnore.
+func (c *Client) AddIgnore(match Matcher) {
+	c.configure(WithIgnore(match))
+}
+
+// SetCheckIgnore sets a function that is called with every item before it is
+// built and queued. If the function returns true, the item is dropped. This
+// can be used to ignore context.Canceled errors, errors from health check
+// routes, messages matching a pattern, etc.
+func (c *Client) SetCheckIgnore(checkIgnore func(item *Item) bool) {
+	c.configure(WithCheckIgnore(checkIgnore))
+}
+
+// SetFrameFilter sets the FrameFilter that decides which frames of the stack
+// traces of reported errors are reported, e.g.
+//
+//	client.SetFrameFilter(rollbar.FrameFilters(rollbar.ExcludeRuntime, rollbar.ExcludeVendor))
+//
+// Frames left out are neither shown in Rollbar nor fingerprinted, so that
+// items are grouped by the frames that matter. A nil FrameFilter, the
+// default, reports every frame.
+func (c *Client) SetFrameFilter(filter FrameFilter) {
+	c.configure(WithFrameFilter(filter))
+}
+
+// SetClassifier sets a function that computes the exception class by which
+// errors are reported and searched in Rollbar, such as "pq.Error:23505" for a
+// PostgreSQL unique violation. If the function returns "", the error keeps
+// its default class: the name of its concrete type, or of the type of the
+// error it wraps for errors returned by fmt.Errorf with %w.
+func (c *Client) SetClassifier(classifier func(err error) string) {
+	c.configure(WithClassifier(classifier))
+}
+
+// SetFingerprinter sets a function that computes the fingerprint by which
+// Rollbar groups every item, instead of the default fingerprint of errors,
+// which covers every frame of their stack trace. For instance, to group
+// errors by class and innermost frame:
+//
+//	client.SetFingerprinter(func(item *rollbar.Item) string {
+//		if len(item.Stack) == 0 {
+//			return ""
+//		}
+//		frame := item.Stack[0]
+//		return fmt.Sprintf("%s:%s:%s", item.Class(), frame.Filename, frame.Method)
+//	})
+//
+// If the function returns "", the item keeps its default fingerprint. A
+// Fingerprint Field reported with an item overrides both.
+func (c *Client) SetFingerprinter(fingerprinter func(item *Item) string) {
+	c.configure(WithFingerprinter(fingerprinter))
+}
+
+// SetTransform sets a function that is called with the data of every item
+// once it has been built, before it is scrubbed and queued. The function can
+// modify the data in place to add fields, rewrite the title, strip
+// information, etc.
+func (c *Client) SetTransform(transform func(data map[string]interface{})) {
+	c.configure(WithTransform(transform))
+}
+
+// SetBeforeSend sets a function that is called with the encoded payload of
+// every item right before it is delivered, after it has been scrubbed and
+// truncated. The payload the function returns is delivered instead, which
+// allows last-moment enrichment or audit logging. Unlike a Transform, which
+// runs when the item is built, the function runs in the sender workers.
+func (c *Client) SetBeforeSend(beforeSend func(item *Item, payload []byte) []byte) {
+	c.configure(WithBeforeSend(beforeSend))
+}
+
+// SetAfterSend sets a function that is called with the encoded payload of
+// every item once it has been delivered, or once delivery failed for good,
+// along with the delivery error. It is not called for items replayed from the
+// spool.
+func (c *Client) SetAfterSend(afterSend func(item *Item, payload []byte, err error)) {
+	c.configure(WithAfterSend(afterSend))
+}
+
+// SetDeliveryErrorHandler sets a function that is called with every item that
+// is ultimately dropped without reaching Rollbar (after retries, or because
+// the queue is full, the Client is closed or the circuit breaker is open),
+// along with the reason. Applications can use it to log items locally, count
+// them or store them elsewhere. Items that are saved to the spool (see
+// WithSpool) are not considered dropped. The handler is called from the
+// sender workers and must not block for long.
+func (c *Client) SetDeliveryErrorHandler(handler func(item *Item, err error)) {
+	c.configure(WithDeliveryErrorHandler(handler))
+}
+
+// SetHTTPClient sets the http.Client used to POST items to Rollbar.
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.configure(WithHTTPClient(httpClient))
+}
+
+// -- Error reporting
+
+// Errorf asynchronously sends an error built from the given format string and
+// arguments, as with fmt.Errorf, to Rollbar with the given severity level.
+// The error is only formatted if the Client is enabled and reports items of
+// that level from the calling package (see WithMinLevel), so that calls that
+// are filtered out cost no formatting.
+func (c *Client) Errorf(level string, format string, args ...interface{}) (string, error) {
+	return c.reportf(level, 1, format, args)
+}
+
+// Criticalf is like Errorf with the CRIT severity level.
+func (c *Client) Criticalf(format string, args ...interface{}) (string, error) {
+	return c.reportf(CRIT, 1, format, args)
+}
+
+// Warningf is like Errorf with the WARN severity level.
+func (c *Client) Warningf(format string, args ...interface{}) (string, error) {
+	return c.reportf(WARN, 1, format, args)
+}
+
+// reportf reports an error built from the given format string and arguments,
+// with the stack trace of the caller skip frames up, unless the item would be
+// dropped before its message is looked at: by its level alone, unless level
+// rules may change it.
+func (c *Client) reportf(level string, skip int, format string, args []interface{}) (string, error) {
+	if !c.enabled() {
+		return "", nil
+	}
+	config := c.snapshot()
+	stack := buildStack(2+skip, config.stackOptions())
+	if len(config.levelRules) == 0 && config.belowMinLevel(&Item{Level: config.aliasedLevel(level), Stack: stack}) {
+		return "", nil
+	}
+	return c.ErrorWithStack(level, fmt.Errorf(format, args...), stack)
+}
+
+// Error asynchronously sends an error to Rollbar with the given severity
+// level. You can pass, optionally, custom Fields to be passed on to Rollbar.
+//
+// Like every reporting method, Error returns the UUID of the occurrence, which
+// applications can show to users as an error reference and which Rollbar
+// links to the item (see OccurrenceURL), or "" if the item was not reported
+// because the Client is disabled or the item is ignored. It also returns an
+// error if the item could not be queued. A synchronous Client (see WithSync)
+// blocks until the item has been POSTed and returns the delivery error
+// instead.
+func (c *Client) Error(level string, err error, fields ...*Field) (string, error) {
+	return c.ErrorWithStackSkip(level, err, 1, fields...)
+}
+
+// ErrorWithStackSkip asynchronously sends an error to Rollbar with the given
+// severity level and a given number of stack trace frames skipped. You can
+// pass, optionally, custom Fields to be passed on to Rollbar.
+func (c *Client) ErrorWithStackSkip(level string, err error, skip int, fields ...*Field) (string, error) {
+	if !c.enabled() {
+		return "", nil
+	}
+	config := c.snapshot()
+	stack := buildStack(2+skip, config.stackOptions())
+	return c.ErrorWithStack(level, err, stack, fields...)
+}
+
+// CallerStack returns the stack trace of the calling goroutine, as the
+// Client captures it (see WithCodeCapture, WithMaxStackDepth, etc.), for
+// integrations that report errors with an explicit Stack, such as logging
+// adapters. If pc, the program counter of a call as returned by
+// runtime.Callers or found in a runtime.Frame, is on the stack, the stack
+// trace starts at its frame;
+// otherwise the frames of this package and of the given wrapper packages at
+// the top are left out, like those of WithWrapperPackages. CallerStack
+// returns nil without capturing anything if the Client is disabled.
+func (c *Client) CallerStack(pc uintptr, wrappers ...string) Stack {
+	if !c.enabled() {
+		return nil
+	}
+	config := c.snapshot()
+	opts := config.stackOptions()
+	pcs := callers(1)
+	if pc != 0 {
+		for i := range pcs {
+			// runtime.Frame.PC is one less than the return address.
+			if pcs[i] == pc || pcs[i]-1 == pc {
+				return stackFromPCs(pcs[i:], opts)
+			}
+		}
+		// The call is not on this goroutine's stack: all that is known is
+		// its frame.
+		return stackFromPCs([]uintptr{pc}, opts)
+	}
+	opts.wrappers = append(append([]string(nil), opts.wrappers...), wrappers...)
+	return stackFromPCs(pcs, opts)
+}
+
+// ErrorWithStack asynchronously sends and error to Rollbar with the given
+// stacktrace and (optionally) custom Fields to be passed on to Rollbar. Errors
+// and causes that carry the stack trace of where they were created, such as
+// Stackers and the errors of github.com/pkg/errors, are reported with that
+// stack trace instead.
+func (c *Client) ErrorWithStack(level string, err error, stack Stack, fields ...*Field) (string, error) {
+	return c.report(newErrorItem(level, err, stack), fields...)
+}
+
+// RequestError asynchronously sends an error to Rollbar with the given
+// severity level and request-specific information. You can pass, optionally,
+// custom Fields to be passed on to Rollbar.
+func (c *Client) RequestError(level string, r *http.Request, err error, fields ...*Field) (string, error) {
+	return c.RequestErrorWithStackSkip(level, r, err, 1, fields...)
+}
+
+// RequestErrorWithStackSkip asynchronously sends an error to Rollbar with the
+// given severity level and a given number of stack trace frames skipped, in
+// addition to extra request-specific information. You can pass, optionally,
+// custom Fields to be passed on to Rollbar.
+func (c *Client) RequestErrorWithStackSkip(level string, r *http.Request, err error, skip int, fields ...*Field) (string, error) {
+	if !c.enabled() {
+		return "", nil
+	}
+	config := c.snapshot()
+	stack := buildStack(2+skip, config.stackOptions())
+	return c.RequestErrorWithStack(level, r, err, stack, fields...)
+}
+
+// RequestErrorWithStack asynchronously sends an error to Rollbar with the
+// given severity level, request-specific information provided by the given
+// http.Request, and a custom Stack. You You can pass, optionally, custom
+// Fields to be passed on to Rollbar.
+func (c *Client) RequestErrorWithStack(level string, r *http.Request, err error, stack Stack, fields ...*Field) (string, error) {
+	item := newErrorItem(level, err, stack)
+	item.Request = r
+	return c.report(item, fields...)
+}
+
+// ErrorWithContext asynchronously sends an error to Rollbar with the given
+// severity level. Fields carried by ctx are attached to the item, followed by
+// any custom Fields passed on to Rollbar.
+func (c *Client) ErrorWithContext(ctx context.Context, level string, err error, fields ...*Field) (string, error) {
+	return c.errorWithContext(ctx, level, nil, err, 1, fields)
+}
+
+// RequestErrorWithContext asynchronously sends an error to Rollbar with the
+// given severity level and request-specific information. Fields carried by
+// ctx are attached to the item, followed by any custom Fields passed on to
+// Rollbar.
+func (c *Client) RequestErrorWithContext(ctx context.Context, level string, r *http.Request, err error, fields ...*Field) (string, error) {
+	return c.errorWithContext(ctx, level, r, err, 1, fields)
+}
+
+// ErrorWithStackSkipContext is like ErrorWithContext, but skips the given
+// number of stack trace frames, like ErrorWithStackSkip.
+func (c *Client) ErrorWithStackSkipContext(ctx context.Context, level string, err error, skip int, fields ...*Field) (string, error) {
+	return c.errorWithContext(ctx, level, nil, err, 1+skip, fields)
+}
+
+// ErrorWithStackContext is like ErrorWithContext, but reports the error with
+// the given Stack, like ErrorWithStack. It lets integrations that know the
+// call site of an error, such as logging adapters, report it with the Fields,
+// Telemetry and person of its context.
+func (c *Client) ErrorWithStackContext(ctx context.Context, level string, err error, stack Stack, fields ...*Field) (string, error) {
+	return c.reportContext(ctx, newErrorItem(level, err, stack), fields)
+}
+
+func (c *Client) buildError(level string, err error, stack Stack, fields ...*Field) map[string]interface{} {
+	body := c.buildBody(level, errorTitle(err))
+	data := body["data"].(map[string]interface{})
+	errBody, fingerprint := errorBody(err, stack, c.snapshot())
+	data["body"] = errBody
+	data["fingerprint"] = fingerprint
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		mergeCustom(data, map[string]interface{}{panicValueField: panicErr.customValue()})
+	}
+	if extras := errorData(err); len(extras) > 0 {
+		mergeCustom(data, extras)
+	}
+
+	applyFields(data, fields)
+
+	return body
+}
+
+// -- Message reporting
+
+// Message asynchronously sends a message to Rollbar with the given severity
+// level. You can pass, optionally, custom Fields to be passed on to Rollbar.
+func (c *Client) Message(level string, msg string, fields ...*Field) (string, error) {
+	return c.report(newMessageItem(level, msg), fields...)
+}
+
+// MessageWithContext asynchronously sends a message to Rollbar with the given
+// severity level. Fields carried by ctx are attached to the item, followed by
+// any custom Fields passed on to Rollbar.
+func (c *Client) MessageWithContext(ctx context.Context, level string, msg string, fields ...*Field) (string, error) {
+	return c.reportContext(ctx, newMessageItem(level, msg), fields)
+}
+
+// MessageWithData asynchronously sends a message to Rollbar with the given
+// severity level, along with structured key / value pairs, which Rollbar
+// shows and makes searchable next to the message rather than as part of an
+// opaque string. Log-style events are best reported with a constant message
+// and their details as data, optionally with a Fingerprint Field to group
+// them:
+//
+//	client.MessageWithData(rollbar.WARN, "slow query", map[string]interface{}{
+//		"table":       "orders",
+//		"duration_ms": 1250,
+//	}, rollbar.Fingerprint("slow-query-orders"))
+func (c *Client) MessageWithData(level string, msg string, data map[string]interface{}, fields ...*Field) (string, error) {
+	item := newMessageItem(level, msg)
+	item.Data = copyCustom(data)
+	return c.report(item, fields...)
+}
+
+func (c *Client) buildMessage(level string, msg string, fields ...*Field) map[string]interface{} {
+	body := c.buildBody(level, msg)
+	data := body["data"].(map[string]interface{})
+	data["body"] = messageBody(msg)
+
+	applyFields(data, fields)
+
+	return body
+}
+
+// -- Item handling
+
+// report builds the JSON body for the given item and queues it (or, for a
+// synchronous Client, delivers it), unless the Client is disabled or the item
+// is ignored. It returns the UUID of the occurrence, or "" if it was not
+// reported.
+func (c *Client) report(item *Item, fields ...*Field) (string, error) {
+	config := c.snapshot()
+	if !config.enabled {
+		return "", nil
+	}
+	if config.multiErrors == MultiErrorItems && !item.isMessage {
+		if joined := joinedErrors(item.Err); len(joined) > 0 {
+			return c.reportJoined(item, joined, fields...)
+		}
+	}
+	if config.classifier != nil && !item.isMessage {
+		item.class = config.errorClass(item.Err)
+	}
+	config.applyLevel(item)
+	if config.belowMinLevel(item) {
+		return "", nil
+	}
+	if config.ignored(item) || config.checkIgnore != nil && config.checkIgnore(item) {
+		return "", nil
+	}
+	sampled, rate := c.sampler.sample(item, config.sampling)
+	if !sampled {
+		atomic.AddUint64(&c.counters.sampled, 1)
+		return "", nil
+	}
+	unique, suppressed := c.deduper.allow(item.fingerprint(), config.dedupWindow)
+	if !unique {
+		atomic.AddUint64(&c.counters.suppressed, 1)
+		return "", nil
+	}
+	if !c.limiter.allow(item.fingerprint(), config.limitWindow, config.limit, config.limitPerFingerprint) {
+		if config.dedupWindow > 0 {
+			c.deduper.unsend(item.fingerprint(), suppressed)
+		}
+		c.dropped(item, ErrRateLimited)
+		return "", ErrRateLimited
+	}
+
+	events, fields := c.telemetryEvents(fields)
+	if item.ctx != nil {
+		fields = config.contextData(item.ctx, item.Request, fields)
+	}
+	var body map[string]interface{}
+	if item.isMessage {
+		body = c.buildMessage(item.Level, item.Title, fields...)
+		addMessageData(body, item.Data)
+	} else {
+		if item.Request != nil {
+			request := c.errorRequest(item.Request)
+			fields = append(fields, &Field{Name: "request", Data: request})
+			if person := config.extractPerson(item.Request.Context(), item.Request, fields); person != nil {
+				fields = append(fields, person)
+			}
+			if route := config.route(item.Request); route != "" {
+				request["route"] = route
+				if !hasField(fields, contextFieldName) {
+					fields = append(fields, ItemContext(route))
+				}
+			}
+		}
+		body = c.buildError(item.Level, item.Err, item.Stack, fields...)
+	}
+
+	data := body["data"].(map[string]interface{})
+	if len(events) > 0 {
+		data["body"].(map[string]interface{})["telemetry"] = events
+	}
+	if config.fingerprinter != nil && !hasField(fields, fingerprintFieldName) {
+		if fingerprint := config.fingerprinter(item); fingerprint != "" {
+			data["fingerprint"] = fingerprint
+		}
+	}
+	if rate < 1 {
+		mergeCustom(data, map[string]interface{}{sampleRateField: rate})
+	}
+	if suppressed > 0 {
+		mergeCustom(data, map[string]interface{}{suppressedField: suppressed})
+	}
+	if config.goroutineInfo {
+		mergeCustom(data, map[string]interface{}{goroutineIDField: goroutineID()})
+	}
+	item.UUID = newUUID()
+	data["uuid"] = item.UUID
+	return item.UUID, c.push(item, body)
+}
+
+// reportJoined reports each of the errors combined by the given item as its
+// own item. It returns the UUID of the first one reported and the first error
+// encountered.
+func (c *Client) reportJoined(item *Item, joined []error, fields ...*Field) (uuid string, err error) {
+	for _, e := range joined {
+		if e == nil {
+			continue
+		}
+		member := newErrorItem(item.Level, e, item.Stack)
+		member.Request = item.Request
+		member.ctx = item.ctx
+		memberUUID, memberErr := c.report(member, fields...)
+		if uuid == "" {
+			uuid = memberUUID
+		}
+		if err == nil {
+			err = memberErr
+		}
+	}
+	return uuid, err
+}
+
+// -- Misc.
+
+// PostErrors returns a channel that receives all errors encountered while
+// POSTing items to the Rollbar API.
+func (c *Client) PostErrors() <-chan error {
+	return c.postErrors
+}
+
+// Wait will block until the queue of errors / messages is empty. This allows
+// you to ensure that errors / messages are sent to Rollbar before exiting an
+// application, e.g. right before os.Exit in a fatal error path. Unlike
+// Close, Wait leaves the Client usable, and it is safe to call while other
+// goroutines keep reporting items: it returns as soon as the queue is
+// momentarily empty and nothing is being POSTed.
+func (c *Client) Wait() {
+	c.pending.wait()
+}
+
+// Flush blocks until every queued error / message has been sent to Rollbar or
+// until ctx is done, whichever happens first. It returns ctx.Err() if the
+// queue could not be drained in time.
+func (c *Client) Flush(ctx context.Context) error {
+	select {
+	case <-c.pending.emptied():
+		return c.transport(c.snapshot()).Flush(ctx)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the Client from accepting new errors / messages and blocks
+// until everything already queued has been sent to Rollbar. Items reported
+// after Close are dropped. Calling Close more than once is safe.
+func (c *Client) Close() error {
+	return c.Shutdown(context.Background())
+}
+
+// Shutdown is like Close, but gives up waiting when ctx is done: in-flight
+// POSTs and retries are then aborted, whatever is left in the queue is
+// dropped (or spooled, if the Client has a spool), and ctx.Err() is returned.
+func (c *Client) Shutdown(ctx context.Context) error {
+	// Reporting calls blocked on a full queue hold closeMutex until they give
+	// up.
+	c.stop.Do(func() { close(c.stopping) })
+	c.closeMutex.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.queue)
+	}
+	c.closeMutex.Unlock()
+
+	var err error
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		c.cancel()
+		<-c.done
+		err = ctx.Err()
+	}
+
+	c.closeTransport.Do(func() {
+		transport := c.transport(c.snapshot())
+		if flushErr := transport.Flush(ctx); flushErr != nil && err == nil {
+			err = flushErr
+		}
+		if closeErr := transport.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	})
+	return err
+}
+
+func getHostname(config configuration) string {
+	if config.hostname != "" {
+		return config.hostname
+	}
+
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// Build the main JSON structure that will be sent to Rollbar with the
+// appropriate metadata.
+func (c *Client) buildBody(level, title string) map[string]interface{} {
+	config := c.snapshot()
+	timestamp := time.Now().Unix()
+	hostname := getHostname(config)
+
+	server := map[string]interface{}{
+		"host": hostname,
+	}
+	if config.serverRoot != "" {
+		server["root"] = config.serverRoot
+	}
+	if config.serverBranch != "" {
+		server["branch"] = config.serverBranch
+	}
+	buildVCS.server(server)
+
+	data := map[string]interface{}{
+		"environment": config.environment,
+		"title":       title,
+		"level":       level,
+		"timestamp":   timestamp,
+		"platform":    config.platform,
+		"language":    config.language,
+		"server":      server,
+		"notifier": map[string]interface{}{
+			"name":    config.notifierName,
+			"version": config.notifierVersion,
+		},
+	}
+	if config.codeVersion != "" {
+		data["code_version"] = config.codeVersion
+	}
+	if len(config.custom) > 0 {
+		mergeCustom(data, config.custom)
+	}
+	if config.person != nil {
+		data["person"] = config.person
+	}
+
+	return map[string]interface{}{
+		"access_token": config.token,
+		"data":         data,
+	}
+}
+
+// errorRequest extracts details from a Request in a format that Rollbar
+// accepts.
+func (c *Client) errorRequest(r *http.Request) map[string]interface{} {
+	config := c.snapshot()
+	s := newScrubber(config)
+	request := errorRequest(s, r)
+	if ip := config.userIP(r); ip != "" {
+		request["user_ip"] = ip
+	} else {
+		// The address is omitted, or could not be found.
+		delete(request, "user_ip")
+	}
+	addRequestBody(s, request, r)
+	return request
+}
+
+// -- POST handling
+
+// Prepare the given JSON body and queue it to be POSTed to Rollbar under the
+// access token the item is routed to. A synchronous Client POSTs it right
+// away instead.
+func (c *Client) push(item *Item, body map[string]interface{}) error {
+	config := c.snapshot()
+	body["access_token"] = c.tokenFor(item)
+	if config.transform != nil {
+		config.transform(body["data"].(map[string]interface{}))
+	}
+	body["data"] = newScrubber(config).scrub(body["data"])
+
+	env := &envelope{item: item, body: body}
+	if config.sync {
+		return c.send(env)
+	}
+	return c.enqueue(env)
+}
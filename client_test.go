@@ -0,0 +1,397 @@
+package rollbar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientFlush(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL))
+	client.Message(INFO, "one")
+	client.Message(INFO, "two")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&received) != 2 {
+		t.Errorf("got %d items", received)
+	}
+}
+
+func TestClientFlushTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := New("token", WithEndpoint(server.URL))
+	client.Message(INFO, "slow")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := client.Flush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got: %v", err)
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithErrorWriter(nil))
+	client.Message(INFO, "before close")
+	client.Close()
+	client.Message(INFO, "after close")
+	client.Close()
+
+	if atomic.LoadInt32(&received) != 1 {
+		t.Errorf("got %d items", received)
+	}
+}
+
+func TestClientServerFields(t *testing.T) {
+	client := New("token",
+		WithCodeVersion("abc123"),
+		WithHostname("web-1"),
+		WithServerRoot("github.com/stvp/rollbar"),
+		WithServerBranch("master"),
+	)
+
+	data := client.buildBody(ERR, "title")["data"].(map[string]interface{})
+	if data["code_version"] != "abc123" {
+		t.Errorf("got code_version: %v", data["code_version"])
+	}
+
+	server := data["server"].(map[string]interface{})
+	expected := map[string]string{
+		"host":   "web-1",
+		"root":   "github.com/stvp/rollbar",
+		"branch": "master",
+	}
+	for key, value := range expected {
+		if server[key] != value {
+			t.Errorf("got server.%s: %v", key, server[key])
+		}
+	}
+
+	server = New("token").buildBody(ERR, "title")["data"].(map[string]interface{})["server"].(map[string]interface{})
+	if _, ok := server["root"]; ok {
+		t.Error("should omit an unset root")
+	}
+}
+
+func TestClientDisabled(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithEnabled(false))
+	client.Message(INFO, "disabled")
+	client.Errorf(ERR, "disabled %d", 1)
+	client.Wait()
+	if atomic.LoadInt32(&received) != 0 {
+		t.Errorf("got %d items", received)
+	}
+
+	client.SetEnabled(true)
+	client.Message(INFO, "enabled")
+	client.Wait()
+	if atomic.LoadInt32(&received) != 1 {
+		t.Errorf("got %d items", received)
+	}
+}
+
+func TestClientCustom(t *testing.T) {
+	defaults := map[string]interface{}{"region": "us-east-1", "service": "api"}
+	client := New("token", WithCustom(defaults))
+	defaults["region"] = "changed"
+
+	body := client.buildError(ERR, nil, BuildStack(0), Custom(map[string]interface{}{"service": "worker"}))
+	custom := body["data"].(map[string]interface{})["custom"].(map[string]interface{})
+	if custom["region"] != "us-east-1" {
+		t.Errorf("got region: %v", custom["region"])
+	}
+	if custom["service"] != "worker" {
+		t.Errorf("per-item custom data should win, got service: %v", custom["service"])
+	}
+
+	// Items must not share the client's map.
+	body = client.buildMessage(INFO, "hello")
+	custom = body["data"].(map[string]interface{})["custom"].(map[string]interface{})
+	if custom["service"] != "api" {
+		t.Errorf("got service: %v", custom["service"])
+	}
+}
+
+func TestClientReconfigureWhileReporting(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL))
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 50; i++ {
+			client.SetEnvironment(fmt.Sprintf("env-%d", i))
+			client.SetCustom(map[string]interface{}{"i": i})
+			client.AddRoute("other", MatchLevels(CRIT))
+		}
+		done <- true
+	}()
+	for i := 0; i < 50; i++ {
+		client.Message(INFO, "hello")
+	}
+	<-done
+	client.Wait()
+}
+
+func TestClientTransform(t *testing.T) {
+	items := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		items <- body
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithTransform(func(data map[string]interface{}) {
+		data["title"] = "rewritten: " + data["title"].(string)
+		data["custom"] = map[string]interface{}{"secret": "injected"}
+	}))
+	client.Message(INFO, "hello")
+	client.Wait()
+
+	data := (<-items)["data"].(map[string]interface{})
+	if data["title"] != "rewritten: hello" {
+		t.Errorf("got title: %v", data["title"])
+	}
+	if secret := data["custom"].(map[string]interface{})["secret"]; secret != FILTERED {
+		t.Errorf("transformed data should still be scrubbed, got: %v", secret)
+	}
+}
+
+func TestClientCheckIgnore(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+	}))
+	defer server.Close()
+
+	var ignored []*Item
+	client := New("token", WithEndpoint(server.URL), WithCheckIgnore(func(item *Item) bool {
+		if item.Err == context.Canceled || (item.Request != nil && item.Request.URL.Path == "/health") {
+			ignored = append(ignored, item)
+			return true
+		}
+		return false
+	}))
+
+	health := httptest.NewRequest("GET", "/health", nil)
+	client.Error(ERR, context.Canceled)
+	client.RequestError(ERR, health, fmt.Errorf("database down"))
+	client.Message(INFO, "kept")
+	client.Wait()
+
+	if len(ignored) != 2 {
+		t.Errorf("got %d ignored items", len(ignored))
+	}
+	if atomic.LoadInt32(&received) != 1 {
+		t.Errorf("got %d items", received)
+	}
+}
+
+func TestClientUUID(t *testing.T) {
+	var reported string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		reported, _ = body["data"].(map[string]interface{})["uuid"].(string)
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil))
+	uuid, err := client.Message(INFO, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(uuid) {
+		t.Errorf("got UUID: %q", uuid)
+	}
+	if reported != uuid {
+		t.Errorf("reported UUID %q, returned %q", reported, uuid)
+	}
+	if other, _ := client.Message(INFO, "hello"); other == uuid {
+		t.Error("should generate a UUID per occurrence")
+	}
+
+	client.SetEnabled(false)
+	if uuid, _ := client.Message(INFO, "hello"); uuid != "" {
+		t.Errorf("got UUID for a disabled client: %q", uuid)
+	}
+}
+
+func TestClientWaitWhileReporting(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithErrorWriter(nil))
+	defer client.Close()
+
+	stop := make(chan struct{})
+	reporting := make(chan struct{})
+	go func() {
+		defer close(reporting)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				client.Message(INFO, "busy")
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		client.Wait()
+	}
+	close(stop)
+	<-reporting
+	client.Wait()
+
+	if depth := client.Stats().QueueDepth; depth != 0 {
+		t.Errorf("got queue depth %d after Wait", depth)
+	}
+}
+
+func TestMessageWithData(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+	data := map[string]interface{}{"table": "orders", "duration_ms": 1250, "body": "ignored"}
+
+	var seen *Item
+	client.SetCheckIgnore(func(item *Item) bool {
+		seen = item
+		return false
+	})
+	client.MessageWithData(WARN, "slow query", data, Fingerprint("slow-query-orders"))
+	data["table"] = "changed"
+
+	var body struct {
+		Data struct {
+			Body struct {
+				Message map[string]interface{} `json:"message"`
+			} `json:"body"`
+			Fingerprint string `json:"fingerprint"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(transport.payloads[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	message := body.Data.Body.Message
+	if message["body"] != "slow query" || message["table"] != "orders" || message["duration_ms"] != float64(1250) {
+		t.Errorf("got message %v", message)
+	}
+	if body.Data.Fingerprint != "slow-query-orders" {
+		t.Errorf("got fingerprint %q", body.Data.Fingerprint)
+	}
+	if seen == nil || seen.Data["table"] != "orders" {
+		t.Errorf("hooks should see the data of the message, got %+v", seen)
+	}
+}
+
+type countingStringer struct{ count *int }
+
+func (s countingStringer) String() string {
+	*s.count++
+	return "formatted"
+}
+
+func TestReportfDefersFormatting(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil), WithMinLevel(ERR))
+	var count int
+
+	client.Warningf("value: %s", countingStringer{&count})
+	if count != 0 || len(transport.payloads) != 0 {
+		t.Fatalf("got %d formattings, %d items", count, len(transport.payloads))
+	}
+
+	client.Criticalf("value: %s", countingStringer{&count})
+	if count != 1 || len(transport.payloads) != 1 {
+		t.Fatalf("got %d formattings, %d items", count, len(transport.payloads))
+	}
+	var body struct {
+		Data struct {
+			Level string `json:"level"`
+			Title string `json:"title"`
+			Body  struct {
+				Trace struct {
+					Frames []Frame `json:"frames"`
+				} `json:"trace"`
+			} `json:"body"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(transport.payloads[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data.Level != CRIT || body.Data.Title != "value: formatted" {
+		t.Errorf("got %+v", body.Data)
+	}
+	frames := body.Data.Body.Trace.Frames
+	if len(frames) == 0 || frames[0].Method != "rollbar.TestReportfDefersFormatting" {
+		t.Errorf("should capture the stack of the call site, got %+v", frames)
+	}
+}
+
+// logHere captures the stack of its call site like a logging adapter, which
+// knows the program counter of the log call.
+func logHere(client *Client) Stack {
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:])
+	return client.CallerStack(pcs[0])
+}
+
+func TestCallerStack(t *testing.T) {
+	client := New("token", WithTransport(&fakeTransport{}), WithSync(), WithErrorWriter(nil), WithCodeCapture(false))
+
+	stack := logHere(client)
+	if len(stack) < 2 || stack[0].Method != "rollbar.TestCallerStack" {
+		t.Fatalf("should start at the frame of pc, got %+v", stack)
+	}
+	if stack[0].Code != "" {
+		t.Errorf("should capture the stack with the Client's options, got code %q", stack[0].Code)
+	}
+	if stack := client.CallerStack(0, "testing"); len(stack) == 0 || stack[0].Method != "rollbar.TestCallerStack" {
+		t.Errorf("got %+v", stack)
+	}
+
+	client.SetEnabled(false)
+	if stack := client.CallerStack(0); stack != nil {
+		t.Errorf("should not capture stacks when disabled, got %+v", stack)
+	}
+}
@@ -0,0 +1,114 @@
+package rollbar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// IPAnonymization decides how much of the IP address of the client that sent
+// a request is reported with its items.
+type IPAnonymization int
+
+const (
+	// IPFull reports the whole address. It is the default.
+	IPFull IPAnonymization = iota
+
+	// IPMasked zeroes the last octet of IPv4 addresses and the last 80 bits
+	// of IPv6 addresses, which leaves the network of the client but not the
+	// client itself.
+	IPMasked
+
+	// IPHashed reports a hash of the address instead, so that items can still
+	// be counted per client. Hashes of IPv4 addresses can be reversed by
+	// hashing every address, so this is pseudonymization rather than
+	// anonymization.
+	IPHashed
+
+	// IPOmitted leaves the address out.
+	IPOmitted
+)
+
+// RemoteAddrIP returns the IP address the request came from, without the
+// port. It is how the client IP of a request is found by default, which is
+// right for servers that clients connect to directly.
+func RemoteAddrIP(r *http.Request) string {
+	return remoteIP(r)
+}
+
+// ForwardedForIP returns a function that finds the client IP of a request in
+// its X-Forwarded-For header, for servers behind the given number of trusted
+// proxies, such as 1 behind a single load balancer. Each proxy appends the
+// address it got the request from, so the client IP is that many addresses
+// from the end of the list, counting the address the last proxy connected
+// from; addresses further left can be forged by the client. Requests that
+// went through fewer proxies are reported with the leftmost address.
+func ForwardedForIP(trustedProxies int) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		var chain []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, address := range strings.Split(header, ",") {
+				if address = strings.TrimSpace(address); address != "" {
+					chain = append(chain, address)
+				}
+			}
+		}
+		chain = append(chain, remoteIP(r))
+
+		i := len(chain) - 1 - trustedProxies
+		if i < 0 {
+			i = 0
+		}
+		return chain[i]
+	}
+}
+
+// HeaderIP returns a function that finds the client IP of a request in the
+// given header, such as "X-Real-IP" or "CF-Connecting-IP", as set by a
+// trusted proxy, falling back to RemoteAddrIP for requests without it.
+func HeaderIP(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if ip := strings.TrimSpace(r.Header.Get(name)); ip != "" {
+			return ip
+		}
+		return remoteIP(r)
+	}
+}
+
+// userIP returns the client IP of r as it is reported, found as configured
+// by WithClientIP and anonymized as configured by WithIPAnonymization.
+func (config *configuration) userIP(r *http.Request) string {
+	clientIP := RemoteAddrIP
+	if config.clientIP != nil {
+		clientIP = config.clientIP
+	}
+	return anonymizeIP(clientIP(r), config.ipAnonymization)
+}
+
+// anonymizeIP applies the given anonymization to ip. Values that aren't IP
+// addresses are only ever hashed or omitted.
+func anonymizeIP(ip string, anonymization IPAnonymization) string {
+	switch anonymization {
+	case IPMasked:
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return ""
+		}
+		if v4 := parsed.To4(); v4 != nil {
+			return v4.Mask(net.CIDRMask(24, 32)).String()
+		}
+		return parsed.Mask(net.CIDRMask(48, 128)).String()
+	case IPHashed:
+		if ip == "" {
+			return ""
+		}
+		sum := sha256.Sum256([]byte(ip))
+		return hex.EncodeToString(sum[:8])
+	case IPOmitted:
+		return ""
+	default:
+		return ip
+	}
+}
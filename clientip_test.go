@@ -0,0 +1,68 @@
+package rollbar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.2:51234"
+	r.Header.Add("X-Forwarded-For", "6.6.6.6, 203.0.113.7")
+	r.Header.Add("X-Forwarded-For", "10.0.0.1")
+	r.Header.Set("X-Real-IP", "203.0.113.8")
+
+	tests := []struct {
+		clientIP func(r *http.Request) string
+		want     string
+	}{
+		{RemoteAddrIP, "10.0.0.2"},
+		{ForwardedForIP(0), "10.0.0.2"},
+		{ForwardedForIP(2), "203.0.113.7"},
+		{ForwardedForIP(10), "6.6.6.6"},
+		{HeaderIP("X-Real-IP"), "203.0.113.8"},
+		{HeaderIP("CF-Connecting-IP"), "10.0.0.2"},
+	}
+	for i, test := range tests {
+		if got := test.clientIP(r); got != test.want {
+			t.Errorf("tests[%d]: got %q", i, got)
+		}
+	}
+}
+
+func TestIPAnonymization(t *testing.T) {
+	tests := []struct {
+		ip            string
+		anonymization IPAnonymization
+		want          string
+	}{
+		{"203.0.113.7", IPFull, "203.0.113.7"},
+		{"203.0.113.7", IPMasked, "203.0.113.0"},
+		{"2001:db8:85a3:1:2:8a2e:370:7334", IPMasked, "2001:db8:85a3::"},
+		{"not an ip", IPMasked, ""},
+		{"203.0.113.7", IPOmitted, ""},
+	}
+	for i, test := range tests {
+		if got := anonymizeIP(test.ip, test.anonymization); got != test.want {
+			t.Errorf("tests[%d]: got %q", i, got)
+		}
+	}
+
+	hashed := anonymizeIP("203.0.113.7", IPHashed)
+	if len(hashed) != 16 || hashed != anonymizeIP("203.0.113.7", IPHashed) || hashed == anonymizeIP("203.0.113.8", IPHashed) {
+		t.Errorf("should hash addresses consistently, got %q", hashed)
+	}
+
+	client := New("token", WithClientIP(HeaderIP("X-Real-IP")), WithIPAnonymization(IPMasked))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Real-IP", "203.0.113.8")
+	if got := client.errorRequest(r)["user_ip"]; got != "203.0.113.0" {
+		t.Errorf("got user_ip %v", got)
+	}
+
+	client.configure(WithIPAnonymization(IPOmitted))
+	if got, ok := client.errorRequest(r)["user_ip"]; ok {
+		t.Errorf("should leave out omitted addresses, got user_ip %q", got)
+	}
+}
@@ -0,0 +1,155 @@
+// Command rollbar reports to Rollbar from shell scripts and CI jobs, where
+// importing package rollbar isn't possible:
+//
+//	rollbar notify -level error -message "nightly backup failed"
+//	rollbar report-deploy -revision "$(git rev-parse HEAD)" -user "$USER"
+//	rollbar replay /var/spool/myapp/rollbar
+//
+// The access token and environment are read from the -token and
+// -environment flags, or from the ROLLBAR_TOKEN and ROLLBAR_ENVIRONMENT
+// environment variables. Reporting deploys needs a token with the
+// "post_server_item" scope, like notifying. Replaying a spool written by a
+// Client configured with rollbar.WithSpool needs no token: spooled items carry
+// their own.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/stvp/rollbar"
+)
+
+const usage = `usage: rollbar [flags] <command> [command flags]
+
+Commands:
+  notify         report a message
+  report-deploy  register a deploy
+  replay DIR     deliver the items saved to a spool directory
+
+Flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+// run runs the command line args and returns the exit status: 0 on success,
+// 1 if reporting failed and 2 on usage errors.
+func run(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("rollbar", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	token := flags.String("token", os.Getenv("ROLLBAR_TOKEN"), "project access token")
+	environment := flags.String("environment", os.Getenv("ROLLBAR_ENVIRONMENT"), "environment to report under")
+	baseURL := flags.String("base-url", rollbar.DefaultBaseURL, "base URL of the Rollbar API")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	opts := []rollbar.Option{rollbar.WithSync(), rollbar.WithBaseURL(*baseURL), rollbar.WithErrorWriter(nil)}
+	if *environment != "" {
+		opts = append(opts, rollbar.WithEnvironment(*environment))
+	}
+	client := rollbar.New(*token, opts...)
+
+	command, args := flags.Arg(0), flags.Args()[1:]
+	var err error
+	switch command {
+	case "notify":
+		err = notify(client, args, stderr)
+	case "report-deploy":
+		err = reportDeploy(client, args, stderr)
+	case "replay":
+		err = replay(*baseURL, args, stderr)
+	default:
+		fmt.Fprintf(stderr, "rollbar: unknown command %q\n", command)
+		flags.Usage()
+		return 2
+	}
+	if err == flag.ErrHelp || err == errUsage {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "rollbar %s: %s\n", command, err)
+		return 1
+	}
+	return 0
+}
+
+// errUsage is returned by commands whose flags or arguments are invalid, once
+// they have printed why.
+var errUsage = errors.New("usage error")
+
+// notify reports a message, given with -message or as the arguments.
+func notify(client *rollbar.Client, args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("notify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	level := flags.String("level", rollbar.ERR, "severity level: critical, error, warning, info or debug")
+	message := flags.String("message", "", "message to report; defaults to the arguments")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *message == "" {
+		*message = strings.Join(flags.Args(), " ")
+	}
+	if *message == "" {
+		fmt.Fprintln(stderr, "rollbar notify: no message")
+		return errUsage
+	}
+
+	_, err := client.Message(*level, *message)
+	return err
+}
+
+// reportDeploy registers a deploy of the environment of the client.
+func reportDeploy(client *rollbar.Client, args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("report-deploy", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	revision := flags.String("revision", "", "revision deployed, e.g. a commit SHA")
+	user := flags.String("user", os.Getenv("USER"), "user who deployed")
+	comment := flags.String("comment", "", "comment about the deploy")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *revision == "" {
+		// The code version of the client would be that of this command.
+		fmt.Fprintln(stderr, "rollbar report-deploy: no -revision")
+		return errUsage
+	}
+
+	return client.ReportDeploy("", *revision, *user, *comment)
+}
+
+// replay delivers the items spooled in the directory given as the argument.
+func replay(baseURL string, args []string, stderr io.Writer) error {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "usage: rollbar replay DIR")
+		return errUsage
+	}
+	if _, err := os.Stat(args[0]); err != nil {
+		return err
+	}
+
+	client := rollbar.New("", rollbar.WithBaseURL(baseURL), rollbar.WithErrorWriter(nil), rollbar.WithSpool(args[0], 0))
+	defer client.Close()
+	left, err := client.ReplaySpool()
+	if err != nil {
+		return err
+	}
+	if left > 0 {
+		return fmt.Errorf("%d items could not be delivered", left)
+	}
+	return nil
+}
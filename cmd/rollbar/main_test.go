@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newServer(t *testing.T) (*httptest.Server, *[]map[string]interface{}) {
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		body["path"] = r.URL.Path
+		bodies = append(bodies, body)
+		w.Write([]byte(`{"err": 0, "result": {}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+func TestNotify(t *testing.T) {
+	server, bodies := newServer(t)
+	var stderr bytes.Buffer
+
+	status := run([]string{"-token", "abc", "-environment", "ci", "-base-url", server.URL, "notify", "-level", "warning", "disk", "almost", "full"}, &stderr)
+	if status != 0 || len(*bodies) != 1 {
+		t.Fatalf("got status %d, %d requests: %s", status, len(*bodies), stderr.String())
+	}
+	body := (*bodies)[0]
+	data := body["data"].(map[string]interface{})
+	if body["path"] != "/item/" || body["access_token"] != "abc" || data["environment"] != "ci" || data["level"] != "warning" || data["title"] != "disk almost full" {
+		t.Errorf("got %v", body)
+	}
+}
+
+func TestReportDeploy(t *testing.T) {
+	server, bodies := newServer(t)
+	var stderr bytes.Buffer
+
+	status := run([]string{"-token", "abc", "-environment", "production", "-base-url", server.URL, "report-deploy", "-revision", "abc123", "-user", "ci"}, &stderr)
+	if status != 0 || len(*bodies) != 1 {
+		t.Fatalf("got status %d, %d requests: %s", status, len(*bodies), stderr.String())
+	}
+	if body := (*bodies)[0]; body["path"] != "/deploy/" || body["revision"] != "abc123" || body["environment"] != "production" || body["local_username"] != "ci" {
+		t.Errorf("got %v", body)
+	}
+
+	if status := run([]string{"report-deploy"}, &stderr); status != 2 {
+		t.Errorf("should require a revision, got status %d", status)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	server, bodies := newServer(t)
+	dir, err := ioutil.TempDir("", "rollbar-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	item := []byte(`{"access_token": "abc", "data": {"level": "error"}}`)
+	if err := ioutil.WriteFile(filepath.Join(dir, "00000000000000000001-0000000001.json"), item, 0600); err != nil {
+		t.Fatal(err)
+	}
+	var stderr bytes.Buffer
+
+	status := run([]string{"-base-url", server.URL, "replay", dir}, &stderr)
+	if status != 0 || len(*bodies) != 1 || (*bodies)[0]["access_token"] != "abc" {
+		t.Fatalf("got status %d, %v: %s", status, *bodies, stderr.String())
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(files) != 0 {
+		t.Errorf("got %d spooled items left", len(files))
+	}
+}
+
+func TestUsage(t *testing.T) {
+	var stderr bytes.Buffer
+	for _, args := range [][]string{nil, {"bogus"}, {"notify"}, {"replay"}} {
+		if status := run(args, &stderr); status != 2 {
+			t.Errorf("%v: got status %d", args, status)
+		}
+	}
+}
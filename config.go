@@ -0,0 +1,295 @@
+package rollbar
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime"
+	"time"
+)
+
+// configuration holds every setting of a Client. The settings can be changed
+// at any time, even while the Client is reporting items from other
+// goroutines: changes are made under the Client's lock and readers work from
+// a copy taken with Client.snapshot.
+type configuration struct {
+	token        string
+	environment  string
+	platform     string
+	baseURL      string
+	endpoint     string
+	codeVersion  string
+	hostname     string
+	serverRoot   string
+	serverBranch string
+	enabled      bool
+	custom       map[string]interface{}
+	person       *Person
+	routes       []Route
+	ignores      []Matcher
+	minLevel     string
+	checkIgnore  func(item *Item) bool
+	transform    func(data map[string]interface{})
+	beforeSend   func(item *Item, payload []byte) []byte
+	afterSend    func(item *Item, payload []byte, err error)
+	onDropped    func(item *Item, err error)
+
+	statsInterval time.Duration
+	statsHandler  func(Stats)
+	filterFields  *regexp.Regexp
+	// scrubPatterns and scrubFunc extend filterFields; see newScrubber.
+	scrubPatterns       []*regexp.Regexp
+	scrubFunc           ScrubFunc
+	logger              Logger
+	httpClient          *http.Client
+	transport           Transport
+	fingerprinter       func(item *Item) string
+	classifier          func(err error) string
+	multiErrors         MultiErrorMode
+	dedupWindow         time.Duration
+	sampling            []SamplingRule
+	limit               int
+	limitPerFingerprint int
+	limitWindow         time.Duration
+	queuePolicy         QueuePolicy
+	queueTimeout        time.Duration
+	buffer              int
+	workers             int
+	sync                bool
+
+	// retries is the number of times a transient failure is retried, waiting
+	// between retryBackoff and maxRetryBackoff between attempts.
+	retries         int
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+
+	// notifierName, notifierVersion and language are reported in every item;
+	// see WithNotifier and WithLanguage.
+	notifierName    string
+	notifierVersion string
+	language        string
+
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	timeout          time.Duration
+	maxPayloadSize   int
+	maxStackDepth    int
+	wrapperPackages  []string
+	contextLines     int
+	codeCapture      bool
+	sourceRoots      []sourceRoot
+	frameFilter      FrameFilter
+	goroutineDump    int
+	goroutineInfo    bool
+	maxTelemetry     int
+	packageLevels    []packageLevel
+	levelAliases     map[string]string
+	levelRules       []levelRule
+	routePattern     func(r *http.Request) string
+	requestBodyMax   int
+	clientIP         func(r *http.Request) string
+	ipAnonymization  IPAnonymization
+	personExtractor  func(ctx context.Context, r *http.Request) *Person
+
+	// gzipMinSize is the smallest encoded payload that is gzipped, or a
+	// negative number if payloads are never gzipped.
+	gzipMinSize int
+
+	spoolDir      string
+	spoolMaxBytes int64
+
+	batchSize     int
+	batchInterval time.Duration
+
+	// fingerprintWithoutLines leaves line numbers out of fingerprints, and
+	// sha256Fingerprints uses SHA-256 rather than CRC32 to compute them.
+	fingerprintWithoutLines bool
+	sha256Fingerprints      bool
+
+	// optionErrors are the errors of options that could not be applied,
+	// which are logged once every option has been, so that they reach a
+	// Logger set by a later option.
+	optionErrors []string
+}
+
+func defaultConfiguration(token string) configuration {
+	return configuration{
+		token:        token,
+		environment:  DefaultEnvironment,
+		platform:     runtime.GOOS,
+		baseURL:      DefaultBaseURL,
+		endpoint:     DefaultEndpoint,
+		codeVersion:  buildVCS.revision,
+		filterFields: regexp.MustCompile(DefaultFilterFields),
+		logger:       nopLogger{},
+		httpClient:   http.DefaultClient,
+		buffer:       DefaultBuffer,
+		workers:      DefaultWorkers,
+
+		retries:         DefaultRetries,
+		retryBackoff:    DefaultRetryBackoff,
+		maxRetryBackoff: DefaultMaxRetryBackoff,
+		notifierName:    NAME,
+		notifierVersion: VERSION,
+		language:        "go",
+
+		breakerThreshold: DefaultBreakerThreshold,
+		breakerCooldown:  DefaultBreakerCooldown,
+		timeout:          DefaultTimeout,
+		maxPayloadSize:   DefaultMaxPayloadSize,
+		maxStackDepth:    DefaultMaxStackDepth,
+		codeCapture:      true,
+		maxTelemetry:     DefaultMaxTelemetryEvents,
+		gzipMinSize:      -1,
+		enabled:          true,
+	}
+}
+
+// addRoute appends a Route without touching the backing array of earlier
+// snapshots.
+func (config *configuration) addRoute(token string, match Matcher) {
+	routes := make([]Route, len(config.routes), len(config.routes)+1)
+	copy(routes, config.routes)
+	config.routes = append(routes, Route{Token: token, Match: match})
+}
+
+// addIgnore appends a Matcher of ignored items without touching the backing
+// array of earlier snapshots.
+func (config *configuration) addIgnore(match Matcher) {
+	ignores := make([]Matcher, len(config.ignores), len(config.ignores)+1)
+	copy(ignores, config.ignores)
+	config.ignores = append(ignores, match)
+}
+
+// ignored reports whether item is matched by any of the ignore Matchers.
+func (config *configuration) ignored(item *Item) bool {
+	for _, match := range config.ignores {
+		if match(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// addSourceRoot appends a sourceRoot without touching the backing array of
+// earlier snapshots.
+func (config *configuration) addSourceRoot(root, prefix string) {
+	sourceRoots := make([]sourceRoot, len(config.sourceRoots), len(config.sourceRoots)+1)
+	copy(sourceRoots, config.sourceRoots)
+	config.sourceRoots = append(sourceRoots, sourceRoot{root: root, prefix: prefix})
+}
+
+// configure applies the given Options to the Client's configuration. It is
+// safe to call while the Client is in use.
+func (c *Client) configure(opts ...Option) {
+	c.configMutex.Lock()
+	for _, opt := range opts {
+		opt(&c.config)
+	}
+	logger, errs := c.config.logger, c.config.optionErrors
+	c.config.optionErrors = nil
+	c.configMutex.Unlock()
+
+	for _, err := range errs {
+		logger.Errorf("%s", err)
+	}
+}
+
+// snapshot returns a copy of the Client's current configuration.
+func (c *Client) snapshot() configuration {
+	c.configMutex.RLock()
+	defer c.configMutex.RUnlock()
+
+	return c.config
+}
+
+// enabled reports whether the Client is currently reporting items.
+func (c *Client) enabled() bool {
+	c.configMutex.RLock()
+	defer c.configMutex.RUnlock()
+
+	return c.config.enabled
+}
+
+// stackOptions returns how stack traces are captured.
+func (config *configuration) stackOptions() stackOptions {
+	return stackOptions{
+		maxDepth:     config.maxStackDepth,
+		wrappers:     config.wrapperPackages,
+		contextLines: config.contextLines,
+		skipCode:     !config.codeCapture,
+		sourceRoots:  config.sourceRoots,
+	}
+}
+
+// errorClass returns the exception class of the given error, as computed by
+// the configured classifier or, failing that, by errorClass.
+func (config *configuration) errorClass(err error) string {
+	if config.classifier != nil {
+		if class := config.classifier(err); class != "" {
+			return class
+		}
+	}
+	return errorClass(err)
+}
+
+// route returns the route pattern of a request: the one returned by the
+// routePattern function, if any, or else the pattern of the http.ServeMux
+// route that matched it.
+func (config *configuration) route(r *http.Request) string {
+	if config.routePattern != nil {
+		if route := config.routePattern(r); route != "" {
+			return route
+		}
+	}
+	return r.Pattern
+}
+
+// fingerprint returns the fingerprint of an error reported with the given
+// stack trace.
+func (config *configuration) fingerprint(stack Stack) string {
+	if config.sha256Fingerprints {
+		return stack.sha256Fingerprint(!config.fingerprintWithoutLines)
+	}
+	if config.fingerprintWithoutLines {
+		return stack.fingerprintWithoutLines()
+	}
+	return stack.Fingerprint()
+}
+
+// adjustTransport applies adjust to a copy of the *http.Transport used by the
+// configured http.Client, or of http.DefaultTransport if it has none, so that
+// options can tweak it without affecting anyone else. Any other RoundTripper
+// can't be adjusted: it is kept, and the given option reports an error.
+func (config *configuration) adjustTransport(option string, adjust func(transport *http.Transport)) {
+	var roundTripper http.RoundTripper
+	if config.httpClient != nil {
+		roundTripper = config.httpClient.Transport
+	}
+	if roundTripper == nil {
+		roundTripper = http.DefaultTransport
+	}
+	transport, ok := roundTripper.(*http.Transport)
+	if !ok {
+		config.optionErrors = append(config.optionErrors,
+			fmt.Sprintf("%s has no effect on a %T RoundTripper, keeping it as is", option, roundTripper))
+		return
+	}
+
+	transport = transport.Clone()
+	adjust(transport)
+	config.setTransport(transport)
+}
+
+// setTransport replaces the configured http.Client with a copy that uses the
+// given RoundTripper, keeping the timeout, redirect policy and cookie jar of
+// the current one.
+func (config *configuration) setTransport(transport http.RoundTripper) {
+	var httpClient http.Client
+	if config.httpClient != nil {
+		httpClient = *config.httpClient
+	}
+	httpClient.Transport = transport
+	config.httpClient = &httpClient
+}
@@ -0,0 +1,112 @@
+package rollbar
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+)
+
+type contextKey int
+
+const (
+	fieldsContextKey contextKey = iota
+	telemetryContextKey
+	requestBodyContextKey
+)
+
+const (
+	// goroutineIDField and pprofLabelsField are the custom fields that hold
+	// the ID of the reporting goroutine and the pprof labels of the context of
+	// an item; see WithGoroutineInfo.
+	goroutineIDField = "goroutine_id"
+	pprofLabelsField = "pprof_labels"
+)
+
+// ContextWithFields returns a copy of ctx that carries the given Fields in
+// addition to any Fields already carried by ctx. Fields carried by a context
+// are attached to every item reported with that context, which makes it easy
+// to report request-scoped data (a request ID, the current person, etc.) from
+// code that has no direct access to the request.
+func ContextWithFields(ctx context.Context, fields ...*Field) context.Context {
+	return context.WithValue(ctx, fieldsContextKey, append(FieldsFromContext(ctx), fields...))
+}
+
+// FieldsFromContext returns the Fields carried by ctx, if any.
+func FieldsFromContext(ctx context.Context) []*Field {
+	fields, _ := ctx.Value(fieldsContextKey).([]*Field)
+	// Return a copy so that callers can safely append to it.
+	return append([]*Field(nil), fields...)
+}
+
+// contextFields returns the Fields carried by ctx followed by the given ones,
+// along with the Telemetry of ctx, if any.
+func contextFields(ctx context.Context, fields []*Field) []*Field {
+	fields = append(FieldsFromContext(ctx), fields...)
+	if telemetry := TelemetryFromContext(ctx); telemetry != nil {
+		fields = append(fields, &Field{Name: telemetryFieldName, Data: telemetry})
+	}
+	return fields
+}
+
+// contextData returns the given Fields of an item reported with ctx (and r,
+// which may be nil), along with the person found by the person extractor, if
+// any, and the pprof labels of ctx if the Client is configured to report them.
+// It is called once the item has gone through the filters, so that dropped
+// items cost neither.
+func (config *configuration) contextData(ctx context.Context, r *http.Request, fields []*Field) []*Field {
+	if person := config.extractPerson(ctx, r, fields); person != nil {
+		fields = append(fields, person)
+	}
+	if !config.goroutineInfo {
+		return fields
+	}
+
+	labels := make(map[string]interface{})
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		labels[key] = value
+		return true
+	})
+	if len(labels) > 0 {
+		fields = append(fields, Custom(map[string]interface{}{pprofLabelsField: labels}))
+	}
+	return fields
+}
+
+// reportContext reports an item with ctx, whose Fields are attached to it
+// followed by the given ones. A nil ctx is ignored.
+func (c *Client) reportContext(ctx context.Context, item *Item, fields []*Field) (string, error) {
+	if ctx == nil {
+		return c.report(item, fields...)
+	}
+	item.ctx = ctx
+	return c.report(item, contextFields(ctx, fields)...)
+}
+
+// errorWithContext reports an error with ctx and r, either of which may be
+// nil, and the stack trace of where it was called, skip frames up.
+func (c *Client) errorWithContext(ctx context.Context, level string, r *http.Request, err error, skip int, fields []*Field) (string, error) {
+	if !c.enabled() {
+		return "", nil
+	}
+	config := c.snapshot()
+	stack := buildStack(2+skip, config.stackOptions())
+	item := newErrorItem(level, err, stack)
+	item.Request = r
+	return c.reportContext(ctx, item, fields)
+}
+
+// goroutineID returns the ID of the calling goroutine, as printed at the top
+// of its stack trace ("goroutine 42 [running]:"), or 0 if it can't be found.
+func goroutineID() int {
+	var buf [64]byte
+	line := buf[:runtime.Stack(buf[:], false)]
+	line = bytes.TrimPrefix(line, []byte("goroutine "))
+	if end := bytes.IndexByte(line, ' '); end != -1 {
+		line = line[:end]
+	}
+	id, _ := strconv.Atoi(string(line))
+	return id
+}
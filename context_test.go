@@ -0,0 +1,65 @@
+package rollbar
+
+import (
+	"context"
+	"encoding/json"
+	"runtime/pprof"
+	"testing"
+)
+
+func TestContextWithFields(t *testing.T) {
+	ctx := ContextWithFields(context.Background(), &Field{Name: "request_id", Data: "abc"})
+	ctx = ContextWithFields(ctx, &Field{Name: "tenant", Data: "acme"})
+
+	fields := FieldsFromContext(ctx)
+	if len(fields) != 2 {
+		t.Fatalf("got %d fields", len(fields))
+	}
+	if fields[0].Name != "request_id" || fields[1].Name != "tenant" {
+		t.Errorf("got fields: %s, %s", fields[0].Name, fields[1].Name)
+	}
+
+	if len(FieldsFromContext(context.Background())) != 0 {
+		t.Error("should carry no fields")
+	}
+}
+
+func TestContextFieldsAreAttached(t *testing.T) {
+	ctx := ContextWithFields(context.Background(), &Field{Name: "request_id", Data: "abc"})
+
+	body := std.buildError(ERR, nil, BuildStack(0), FieldsFromContext(ctx)...)
+	data := body["data"].(map[string]interface{})
+	if data["request_id"] != "abc" {
+		t.Errorf("got: %v", data["request_id"])
+	}
+
+	body = std.buildMessage(INFO, "hello", FieldsFromContext(ctx)...)
+	data = body["data"].(map[string]interface{})
+	if data["request_id"] != "abc" {
+		t.Errorf("got: %v", data["request_id"])
+	}
+}
+
+func TestGoroutineInfo(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil), WithGoroutineInfo())
+
+	pprof.Do(context.Background(), pprof.Labels("tenant", "acme"), func(ctx context.Context) {
+		client.MessageWithContext(ctx, INFO, "hello")
+	})
+
+	var body struct {
+		Data struct {
+			Custom map[string]interface{} `json:"custom"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(transport.payloads[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := body.Data.Custom[goroutineIDField].(float64); !ok || int(id) != goroutineID() || id == 0 {
+		t.Errorf("got goroutine ID %v, expected %d", body.Data.Custom[goroutineIDField], goroutineID())
+	}
+	if labels, _ := body.Data.Custom[pprofLabelsField].(map[string]interface{}); labels["tenant"] != "acme" {
+		t.Errorf("got labels %v", body.Data.Custom[pprofLabelsField])
+	}
+}
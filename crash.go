@@ -0,0 +1,253 @@
+package rollbar
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+const (
+	// crashGoroutinesField is the custom field that holds the traces of the
+	// goroutines of a crash other than the one that crashed.
+	crashGoroutinesField = "goroutines"
+
+	// maxCrashLog is the largest crash output a CrashWatcher keeps; the
+	// traces of the goroutines past it are lost.
+	maxCrashLog = 1 << 20
+
+	// maxLineStart is how much of a line a CrashWatcher keeps while looking
+	// for the start of a crash.
+	maxLineStart = 64
+)
+
+// crashPrefixes are the prefixes of the line the Go runtime prints when a
+// program crashes, along with the kind of crash.
+var crashPrefixes = []struct {
+	prefix string
+	kind   string
+}{
+	{"panic: ", "panic"},
+	{"fatal error: ", "fatal error"},
+}
+
+// Crash is a panic or a fatal runtime error that crashed a Go program, as
+// parsed from the program's standard error by ParseCrash. Fatal runtime
+// errors, such as concurrent map writes or running out of memory, cannot be
+// recovered, so watching the output of the program from another process is
+// the only way to report them.
+//
+// A Crash is reported as a critical error of class Kind, with the stack trace
+// of the goroutine that crashed. The traces of the other goroutines are
+// kept in the "goroutines" custom field.
+type Crash struct {
+	// Kind is "panic" or "fatal error".
+	Kind string
+
+	// Message is the rest of the line that announced the crash, e.g.
+	// "concurrent map writes".
+	Message string
+
+	// Goroutines are the goroutines printed with the crash, starting with
+	// the one that crashed.
+	Goroutines []Goroutine
+}
+
+// Goroutine is the trace of a goroutine printed with a Crash.
+type Goroutine struct {
+	ID    int    `json:"id"`
+	State string `json:"state"`
+	Stack Stack  `json:"frames"`
+}
+
+// Error returns the message of the crash.
+func (c *Crash) Error() string {
+	return c.Kind + ": " + c.Message
+}
+
+// ParseCrash looks for a crash of a Go program in text, such as its standard
+// error or a crash log, and parses it along with the traces of its
+// goroutines. It reports false if text holds no crash.
+func ParseCrash(text []byte) (*Crash, bool) {
+	start := crashStart(text)
+	if start == -1 {
+		return nil, false
+	}
+	text = text[start:]
+
+	header := string(text)
+	if end := strings.IndexByte(header, '\n'); end != -1 {
+		header = header[:end]
+	}
+	crash := &Crash{}
+	for _, p := range crashPrefixes {
+		if strings.HasPrefix(header, p.prefix) {
+			crash.Kind = p.kind
+			crash.Message = strings.TrimSpace(strings.TrimPrefix(header, p.prefix))
+		}
+	}
+	crash.Goroutines = parseGoroutines(string(text))
+	return crash, true
+}
+
+// crashStart returns the offset of the first line of text that announces a
+// crash, or -1 if there is none.
+func crashStart(text []byte) int {
+	for offset := 0; offset < len(text); {
+		line := text[offset:]
+		for _, p := range crashPrefixes {
+			if bytes.HasPrefix(line, []byte(p.prefix)) {
+				return offset
+			}
+		}
+		end := bytes.IndexByte(line, '\n')
+		if end == -1 {
+			break
+		}
+		offset += end + 1
+	}
+	return -1
+}
+
+// ReportCrash reports a Crash as a critical error.
+func (c *Client) ReportCrash(crash *Crash, fields ...*Field) (string, error) {
+	var stack Stack
+	if len(crash.Goroutines) > 0 {
+		stack = crash.Goroutines[0].Stack
+		if others := crash.Goroutines[1:]; len(others) > 0 {
+			fields = append(fields, Custom(map[string]interface{}{crashGoroutinesField: others}))
+		}
+	}
+	if stack == nil {
+		stack = make(Stack, 0)
+	}
+	return c.ErrorWithStack(CRIT, crash, stack, fields...)
+}
+
+// ReportCrashLog reads the file at path, such as the saved standard error of
+// a crashed program, and reports the crash it holds, if any. It returns an
+// empty UUID and a nil error if there is no crash in the file.
+func (c *Client) ReportCrashLog(path string, fields ...*Field) (string, error) {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	crash, ok := ParseCrash(text)
+	if !ok {
+		return "", nil
+	}
+	return c.ReportCrash(crash, fields...)
+}
+
+// RunCommand runs cmd, like cmd.Run, and reports its crash, if its standard
+// error shows one. The standard error of cmd is still written to cmd.Stderr,
+// or to os.Stderr if it is nil:
+//
+//	cmd := exec.Command("./worker")
+//	if err := client.RunCommand(cmd); err != nil {
+//		log.Printf("worker exited: %s", err)
+//	}
+func (c *Client) RunCommand(cmd *exec.Cmd, fields ...*Field) error {
+	stderr := cmd.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	watcher := c.NewCrashWatcher(stderr, fields...)
+	cmd.Stderr = watcher
+	err := cmd.Run()
+	watcher.Close()
+	return err
+}
+
+// RunCommand runs cmd and reports its crash, if any, using the package-level
+// configuration. See Client.RunCommand.
+func RunCommand(cmd *exec.Cmd, fields ...*Field) error {
+	return std.RunCommand(cmd, fields...)
+}
+
+// ReportCrashLog reports the crash held by the file at path, if any, using
+// the package-level configuration. See Client.ReportCrashLog.
+func ReportCrashLog(path string, fields ...*Field) (string, error) {
+	return std.ReportCrashLog(path, fields...)
+}
+
+// CrashWatcher is an io.Writer that passes on the standard error of a Go
+// program, written to it by a supervisor, and reports the crash of the
+// program, if any, when closed.
+type CrashWatcher struct {
+	client *Client
+	out    io.Writer
+	fields []*Field
+
+	mutex sync.Mutex
+	// line is the start of the current line, until a crash is found, and
+	// crash everything written from the crash on.
+	line   []byte
+	crash  []byte
+	closed bool
+}
+
+// NewCrashWatcher returns a CrashWatcher that writes the output of a program
+// to out, if not nil, and reports its crash with the given Fields.
+func (c *Client) NewCrashWatcher(out io.Writer, fields ...*Field) *CrashWatcher {
+	return &CrashWatcher{client: c, out: out, fields: fields}
+}
+
+// Write implements io.Writer.
+func (w *CrashWatcher) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	w.watch(p)
+	w.mutex.Unlock()
+
+	if w.out == nil {
+		return len(p), nil
+	}
+	return w.out.Write(p)
+}
+
+// watch looks for the start of a crash in p, and keeps what follows it.
+func (w *CrashWatcher) watch(p []byte) {
+	if w.crash != nil {
+		if room := maxCrashLog - len(w.crash); room > 0 {
+			if len(p) > room {
+				p = p[:room]
+			}
+			w.crash = append(w.crash, p...)
+		}
+		return
+	}
+
+	w.line = append(w.line, p...)
+	if start := crashStart(w.line); start != -1 {
+		w.crash = append([]byte(nil), w.line[start:]...)
+		w.line = nil
+		return
+	}
+	// Only the last, possibly incomplete, line may start a crash.
+	if end := bytes.LastIndexByte(w.line, '\n'); end != -1 {
+		w.line = append(w.line[:0], w.line[end+1:]...)
+	}
+	if len(w.line) > maxLineStart {
+		w.line = w.line[:maxLineStart]
+	}
+}
+
+// Close reports the crash written to the CrashWatcher, if any. Calling it
+// more than once has no effect.
+func (w *CrashWatcher) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if crash, ok := ParseCrash(w.crash); ok {
+		_, err := w.client.ReportCrash(crash, w.fields...)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package rollbar
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+const fatalErrorText = `starting worker
+fatal error: concurrent map writes
+
+runtime stack:
+runtime.throw({0x4b6d4c?, 0x0?})
+	/usr/local/go/src/runtime/panic.go:1023 +0x5c
+
+goroutine 18 [running]:
+runtime.fatal({0x4b6d4c?, 0x0?})
+	/usr/local/go/src/runtime/panic.go:1052 +0x5c
+main.(*Cache).Put(...)
+	/home/jane/app/cache.go:27
+main.worker(0xc000012345)
+	/home/jane/app/worker.go:14 +0x4e
+created by main.main in goroutine 1
+	/home/jane/app/main.go:9 +0x65
+
+goroutine 1 [chan receive, 2 minutes]:
+main.main()
+	/home/jane/app/main.go:11 +0x8d
+exit status 2
+`
+
+func TestParseCrash(t *testing.T) {
+	crash, ok := ParseCrash([]byte(fatalErrorText))
+	if !ok {
+		t.Fatal("should find the crash")
+	}
+	if crash.Kind != "fatal error" || crash.Message != "concurrent map writes" {
+		t.Errorf("got %q: %q", crash.Kind, crash.Message)
+	}
+	if len(crash.Goroutines) != 2 {
+		t.Fatalf("got goroutines %+v", crash.Goroutines)
+	}
+	crashed := crash.Goroutines[0]
+	if crashed.ID != 18 || crashed.State != "running" || len(crashed.Stack) != 3 || crashed.Stack[0].Method != "main.(*Cache).Put" {
+		t.Errorf("got crashed goroutine %+v", crashed)
+	}
+	if other := crash.Goroutines[1]; other.ID != 1 || other.State != "chan receive, 2 minutes" || len(other.Stack) != 1 {
+		t.Errorf("got goroutine %+v", other)
+	}
+
+	if _, ok := ParseCrash([]byte("starting worker\nexit status 0\n")); ok {
+		t.Error("should find no crash")
+	}
+}
+
+func TestCrashWatcher(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+
+	var out bytes.Buffer
+	watcher := client.NewCrashWatcher(&out, Custom(map[string]interface{}{"worker": "cache"}))
+	for _, chunk := range strings.SplitAfter(fatalErrorText, "a") {
+		watcher.Write([]byte(chunk))
+	}
+	watcher.Close()
+	watcher.Close()
+
+	if out.String() != fatalErrorText {
+		t.Errorf("should pass the output on, got %q", out.String())
+	}
+	if len(transport.payloads) != 1 {
+		t.Fatalf("got %d items", len(transport.payloads))
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(transport.payloads[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	data := body["data"].(map[string]interface{})
+	if data["level"] != CRIT {
+		t.Errorf("got level %v", data["level"])
+	}
+	exception := data["body"].(map[string]interface{})["trace"].(map[string]interface{})["exception"].(map[string]interface{})
+	if exception["class"] != "fatal error" || exception["message"] != "fatal error: concurrent map writes" {
+		t.Errorf("got exception %v", exception)
+	}
+	custom := data["custom"].(map[string]interface{})
+	if goroutines, _ := custom[crashGoroutinesField].([]interface{}); len(goroutines) != 1 || custom["worker"] != "cache" {
+		t.Errorf("got custom data %v", custom)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if os.Getenv("ROLLBAR_CRASH_HELPER") == "1" {
+		panic("worker crashed")
+	}
+
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRunCommand$")
+	cmd.Env = append(os.Environ(), "ROLLBAR_CRASH_HELPER=1")
+	cmd.Stderr = &stderr
+	if err := client.RunCommand(cmd); err == nil {
+		t.Fatal("the command should fail")
+	}
+
+	if !strings.Contains(stderr.String(), "panic: worker crashed") {
+		t.Errorf("should pass stderr on, got %q", stderr.String())
+	}
+	if len(transport.payloads) != 1 {
+		t.Fatalf("got %d items", len(transport.payloads))
+	}
+	payload := string(transport.payloads[0])
+	if !strings.Contains(payload, "panic: worker crashed") || !strings.Contains(payload, "TestRunCommand") {
+		t.Errorf("got payload %s", payload)
+	}
+}
@@ -0,0 +1,87 @@
+package rollbar
+
+import (
+	"sync"
+	"time"
+)
+
+// suppressedField is the key of the custom data field that tells how many
+// identical occurrences were suppressed since the item was last sent.
+const suppressedField = "occurrences_suppressed"
+
+// maxDedupFingerprints bounds how many fingerprints a deduper tracks. When
+// there are more, fingerprints whose window is over are forgotten.
+const maxDedupFingerprints = 10000
+
+// deduper suppresses identical items reported within a window of each other.
+type deduper struct {
+	mutex  sync.Mutex
+	recent map[string]*dedupEntry
+}
+
+type dedupEntry struct {
+	sent       time.Time
+	suppressed int
+
+	// previous is when an identical item was sent before the last one, in
+	// case the last one gets dropped after all; see unsend.
+	previous time.Time
+}
+
+// allow reports whether an item with the given fingerprint should be sent,
+// i.e. whether no identical item was sent within the last window. If so, it
+// also returns how many identical items were suppressed since the last one was
+// sent.
+func (d *deduper) allow(fingerprint string, window time.Duration) (bool, int) {
+	if window <= 0 {
+		return true, 0
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	if d.recent == nil {
+		d.recent = map[string]*dedupEntry{}
+	}
+
+	entry, ok := d.recent[fingerprint]
+	if ok && now.Sub(entry.sent) < window {
+		entry.suppressed++
+		return false, 0
+	}
+
+	if !ok {
+		if len(d.recent) >= maxDedupFingerprints {
+			d.forget(now, window)
+		}
+		entry = &dedupEntry{}
+		d.recent[fingerprint] = entry
+	}
+	suppressed := entry.suppressed
+	entry.previous, entry.sent, entry.suppressed = entry.sent, now, 0
+	return true, suppressed
+}
+
+// unsend undoes allow for an item that was dropped after all rather than sent,
+// so that the count of suppressed items it would have carried goes to the
+// next identical item sent instead.
+func (d *deduper) unsend(fingerprint string, suppressed int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if entry, ok := d.recent[fingerprint]; ok {
+		entry.sent = entry.previous
+		entry.suppressed += suppressed
+	}
+}
+
+// forget drops the fingerprints whose window is over and that have nothing
+// suppressed.
+func (d *deduper) forget(now time.Time, window time.Duration) {
+	for fingerprint, entry := range d.recent {
+		if now.Sub(entry.sent) >= window && entry.suppressed == 0 {
+			delete(d.recent, fingerprint)
+		}
+	}
+}
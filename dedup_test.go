@@ -0,0 +1,67 @@
+package rollbar
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDedupWindow(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil),
+		WithDedupWindow(50*time.Millisecond))
+
+	for i := 0; i < 3; i++ {
+		client.Message(INFO, "retry storm")
+	}
+	client.Message(INFO, "something else")
+	time.Sleep(60 * time.Millisecond)
+	client.Message(INFO, "retry storm")
+
+	if len(transport.payloads) != 3 {
+		t.Fatalf("got %d items", len(transport.payloads))
+	}
+	if suppressed := client.Stats().Suppressed; suppressed != 2 {
+		t.Errorf("got %d suppressed items", suppressed)
+	}
+
+	var body struct {
+		Data struct {
+			Custom map[string]interface{} `json:"custom"`
+		} `json:"data"`
+	}
+	json.Unmarshal(transport.payloads[2], &body)
+	if count := body.Data.Custom[suppressedField]; count != float64(2) {
+		t.Errorf("got suppressed count %v", count)
+	}
+}
+
+func TestDedupWindowWithItemLimit(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil),
+		WithDedupWindow(20*time.Millisecond), WithItemLimit(2, 0, time.Minute))
+
+	client.Message(INFO, "retry storm")
+	client.Message(INFO, "retry storm")
+	client.Message(INFO, "something else")
+	time.Sleep(30 * time.Millisecond)
+	if _, err := client.Message(INFO, "retry storm"); err != ErrRateLimited {
+		t.Fatalf("got %v", err)
+	}
+
+	client.configure(WithItemLimit(10, 0, time.Minute))
+	client.Message(INFO, "retry storm")
+	if len(transport.payloads) != 3 {
+		t.Fatalf("got %d items", len(transport.payloads))
+	}
+
+	var body struct {
+		Data struct {
+			Custom map[string]interface{} `json:"custom"`
+		} `json:"data"`
+	}
+	json.Unmarshal(transport.payloads[2], &body)
+	if count := body.Data.Custom[suppressedField]; count != float64(1) {
+		t.Errorf("should keep the suppressed count of items dropped by the item limit, got %v", count)
+	}
+}
@@ -0,0 +1,76 @@
+package rollbar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// ReportDeploy registers a deploy with Rollbar through its deploy API, which
+// links the items reported after it to the deployed revision. An empty
+// environment or revision stands for the Client's environment or code
+// version, which is the VCS revision of the build by default, so that a
+// service can register its own deploy on startup:
+//
+//	client.ReportDeploy("", "", os.Getenv("USER"), "")
+//
+// localUser is the name of the user who deployed, and comment an optional
+// description of the deploy. ReportDeploy blocks until Rollbar responds, or
+// until the Client's timeout.
+func (c *Client) ReportDeploy(environment, revision, localUser, comment string) error {
+	config := c.snapshot()
+	if environment == "" {
+		environment = config.environment
+	}
+	if revision == "" {
+		revision = config.codeVersion
+	}
+
+	deploy := map[string]interface{}{
+		"access_token": config.token,
+		"environment":  environment,
+		"revision":     revision,
+	}
+	if localUser != "" {
+		deploy["local_username"] = localUser
+	}
+	if comment != "" {
+		deploy["comment"] = comment
+	}
+	jsonBody, err := json.Marshal(deploy)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if config.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", config.baseURL+"deploy/", bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := config.httpClient.Do(req)
+	if err != nil {
+		c.errorf("deploy POST failed: %s", err.Error())
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := responseError(resp); err != nil {
+		c.errorf("received deploy response: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// ReportDeploy registers a deploy with Rollbar using the package-level
+// configuration. See Client.ReportDeploy.
+func ReportDeploy(environment, revision, localUser, comment string) error {
+	return std.ReportDeploy(environment, revision, localUser, comment)
+}
@@ -0,0 +1,56 @@
+package rollbar
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReportDeploy(t *testing.T) {
+	var path string
+	var deploy map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&deploy)
+		w.Write([]byte(`{"data": {"deploy_id": 1}}`))
+	}))
+	defer server.Close()
+
+	client := New("token", WithBaseURL(server.URL+"/api/1"), WithEnvironment("production"), WithCodeVersion("abc123"), WithErrorWriter(nil))
+	if err := client.ReportDeploy("", "", "jane", "hotfix"); err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/api/1/deploy/" {
+		t.Errorf("got path %s", path)
+	}
+	expected := map[string]interface{}{
+		"access_token":   "token",
+		"environment":    "production",
+		"revision":       "abc123",
+		"local_username": "jane",
+		"comment":        "hotfix",
+	}
+	for key, value := range expected {
+		if deploy[key] != value {
+			t.Errorf("%s: got %v", key, deploy[key])
+		}
+	}
+}
+
+func TestReportDeployError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"err": 1, "message": "insufficient privileges"}`))
+	}))
+	defer server.Close()
+
+	client := New("token", WithBaseURL(server.URL), WithErrorWriter(nil))
+	err := client.ReportDeploy("staging", "abc123", "", "")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "insufficient privileges" {
+		t.Errorf("got %v", err)
+	}
+}
@@ -1,9 +1,27 @@
 package rollbar
 
 import (
+	"errors"
 	"fmt"
 )
 
+var (
+	// ErrBufferFull is returned when an item is dropped because the Client's
+	// queue is full.
+	ErrBufferFull = errors.New("rollbar: buffer full, item dropped")
+
+	// ErrClosed is returned when an item is reported to a closed Client.
+	ErrClosed = errors.New("rollbar: client closed")
+
+	// ErrCircuitOpen is returned when an item is dropped without being POSTed
+	// because too many recent deliveries failed.
+	ErrCircuitOpen = errors.New("rollbar: circuit breaker open, item dropped")
+
+	// ErrRateLimited is returned when an item is dropped because it exceeds
+	// the Client's own item limits (see WithItemLimit).
+	ErrRateLimited = errors.New("rollbar: item limit exceeded, item dropped")
+)
+
 // ErrHTTPError is an HTTP error status code as defined by
 // http://www.w3.org/Protocols/rfc2616/rfc2616-sec10.html
 type ErrHTTPError int
@@ -12,3 +30,24 @@ type ErrHTTPError int
 func (e ErrHTTPError) Error() string {
 	return fmt.Sprintf("rollbar: service returned status: %d", e)
 }
+
+// APIError is an error reported by the Rollbar API in its response body, such
+// as an invalid access token or a malformed item.
+type APIError struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+
+	// Message is the API's explanation of the error.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("rollbar: service returned status: %d: %s", e.StatusCode, e.Message)
+}
+
+// Unwrap returns the ErrHTTPError for the response status, so that
+// errors.Is(err, ErrHTTPError(403)) holds for API errors too.
+func (e *APIError) Unwrap() error {
+	return ErrHTTPError(e.StatusCode)
+}
@@ -0,0 +1,71 @@
+package rollbar
+
+import (
+	"strings"
+)
+
+// FrameFilter reports whether a frame of a stack trace is reported. Frames
+// left out by a Client's FrameFilter are neither shown in Rollbar nor taken
+// into account when fingerprinting items.
+type FrameFilter func(frame Frame) bool
+
+var (
+	// ExcludeRuntime leaves out frames of the Go runtime, such as
+	// runtime.goexit or runtime.gopanic.
+	ExcludeRuntime FrameFilter = func(frame Frame) bool {
+		return !strings.HasPrefix(frame.Method, "runtime.") && !strings.Contains(frame.Filename, "/src/runtime/")
+	}
+
+	// ExcludeVendor leaves out frames of vendored packages.
+	ExcludeVendor FrameFilter = func(frame Frame) bool {
+		return !strings.HasPrefix(frame.Filename, "vendor/") && !strings.Contains(frame.Filename, "/vendor/")
+	}
+
+	// ExcludeGenerated leaves out frames of generated code, as recognized by
+	// common file name conventions: protocol buffers (.pb.go), stringer and
+	// go:generate output (_string.go, _gen.go, .gen.go, _generated.go) and
+	// Kubernetes code generators (zz_generated).
+	ExcludeGenerated FrameFilter = func(frame Frame) bool {
+		name := frame.Filename[strings.LastIndex(frame.Filename, "/")+1:]
+		for _, suffix := range generatedSuffixes {
+			if strings.HasSuffix(name, suffix) {
+				return false
+			}
+		}
+		return !strings.HasPrefix(name, "zz_generated")
+	}
+)
+
+var generatedSuffixes = []string{".pb.go", ".pb.gw.go", "_string.go", "_gen.go", ".gen.go", "_generated.go"}
+
+// FrameFilters combines the given filters into one that reports the frames
+// that every one of them reports.
+func FrameFilters(filters ...FrameFilter) FrameFilter {
+	return func(frame Frame) bool {
+		for _, filter := range filters {
+			if !filter(frame) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// filterFrames returns the frames of the given stack that the filter reports,
+// or the stack itself if there is no filter or it leaves out every frame.
+func filterFrames(stack Stack, filter FrameFilter) Stack {
+	if filter == nil {
+		return stack
+	}
+
+	filtered := make(Stack, 0, len(stack))
+	for _, frame := range stack {
+		if filter(frame) {
+			filtered = append(filtered, frame)
+		}
+	}
+	if len(filtered) == 0 {
+		return stack
+	}
+	return filtered
+}
@@ -0,0 +1,48 @@
+package rollbar
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFrameFilters(t *testing.T) {
+	tests := []struct {
+		filter FrameFilter
+		frame  Frame
+		keep   bool
+	}{
+		{ExcludeRuntime, Frame{Filename: "/usr/local/go/src/runtime/panic.go", Method: "runtime.gopanic"}, false},
+		{ExcludeRuntime, Frame{Filename: "github.com/acme/app/main.go", Method: "main.main"}, true},
+		{ExcludeVendor, Frame{Filename: "github.com/acme/app/vendor/github.com/lib/pq/conn.go"}, false},
+		{ExcludeVendor, Frame{Filename: "github.com/acme/app/vendors.go"}, true},
+		{ExcludeGenerated, Frame{Filename: "github.com/acme/app/api/api.pb.go"}, false},
+		{ExcludeGenerated, Frame{Filename: "github.com/acme/app/apis/zz_generated.deepcopy.go"}, false},
+		{ExcludeGenerated, Frame{Filename: "github.com/acme/app/api/server.go"}, true},
+	}
+	for i, test := range tests {
+		if keep := test.filter(test.frame); keep != test.keep {
+			t.Errorf("tests[%d]: got %v", i, keep)
+		}
+	}
+}
+
+func TestFrameFilterFingerprint(t *testing.T) {
+	app := Frame{Filename: "app.go", Method: "main.handle", Line: 12}
+	stack := Stack{app, {Filename: "/usr/local/go/src/runtime/asm_amd64.s", Method: "runtime.goexit", Line: 1571}}
+	otherRuntime := Stack{app, {Filename: "/opt/go/src/runtime/asm_amd64.s", Method: "runtime.goexit", Line: 1700}}
+
+	err := errors.New("not found")
+	config := configuration{frameFilter: FrameFilters(ExcludeRuntime, ExcludeVendor)}
+	body, fingerprint := errorBody(err, stack, config)
+	_, otherFingerprint := errorBody(err, otherRuntime, config)
+
+	if frames := body["trace"].(map[string]interface{})["frames"].(Stack); len(frames) != 1 || frames[0] != app {
+		t.Errorf("got %+v", frames)
+	}
+	if fingerprint != otherFingerprint {
+		t.Error("should fingerprint items without the frames left out")
+	}
+	if got := filterFrames(stack[1:], ExcludeRuntime); len(got) != 1 {
+		t.Error("should keep stacks the filter leaves nothing of")
+	}
+}
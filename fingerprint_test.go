@@ -0,0 +1,122 @@
+package rollbar
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func reportedFingerprint(t *testing.T, payload []byte) string {
+	var body struct {
+		Data struct {
+			Fingerprint string `json:"fingerprint"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		t.Fatal(err)
+	}
+	return body.Data.Fingerprint
+}
+
+func TestFingerprinter(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+	err := errors.New("timeout")
+
+	client.Error(ERR, err)
+	client.SetFingerprinter(func(item *Item) string {
+		if item.Title == "skip" {
+			return ""
+		}
+		return item.Level + ":" + item.Title
+	})
+	client.Error(ERR, err)
+	client.Message(INFO, "cache warmed up")
+	client.Error(ERR, err, Fingerprint("payments-timeout"))
+	client.Message(INFO, "skip")
+
+	if len(transport.payloads) != 5 {
+		t.Fatalf("got %d items", len(transport.payloads))
+	}
+	want := []string{"", "error:timeout", "info:cache warmed up", "payments-timeout", ""}
+	for i, payload := range transport.payloads {
+		got := reportedFingerprint(t, payload)
+		if i == 0 {
+			if got == "" || got == want[1] {
+				t.Errorf("items[0]: should keep the default fingerprint, got %q", got)
+			}
+			continue
+		}
+		if got != want[i] {
+			t.Errorf("items[%d]: got %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestLineInsensitiveFingerprints(t *testing.T) {
+	before := Stack{{Filename: "app.go", Method: "main.handle", Line: 12}}
+	after := Stack{{Filename: "app.go", Method: "main.handle", Line: 14}}
+	err := errors.New("timeout")
+
+	_, fingerprint := errorBody(err, before, configuration{})
+	_, moved := errorBody(err, after, configuration{})
+	if fingerprint == moved {
+		t.Error("should fingerprint line numbers by default")
+	}
+
+	config := configuration{fingerprintWithoutLines: true}
+	_, fingerprint = errorBody(err, before, config)
+	_, moved = errorBody(err, after, config)
+	if fingerprint != moved {
+		t.Error("should not fingerprint line numbers")
+	}
+	if renamed := (Stack{{Filename: "app.go", Method: "main.serve", Line: 12}}); renamed.fingerprintWithoutLines() == fingerprint {
+		t.Error("should fingerprint methods")
+	}
+}
+
+func TestSHA256Fingerprints(t *testing.T) {
+	stack := Stack{{Filename: "app.go", Method: "main.handle", Line: 12}}
+	err := errors.New("timeout")
+
+	_, fingerprint := errorBody(err, stack, configuration{sha256Fingerprints: true})
+	if len(fingerprint) != maxFingerprintLength || fingerprint == stack.Fingerprint() {
+		t.Errorf("got %s", fingerprint)
+	}
+
+	_, moved := errorBody(err, Stack{{Filename: "app.go", Method: "main.handle", Line: 14}},
+		configuration{sha256Fingerprints: true, fingerprintWithoutLines: true})
+	if _, unmoved := errorBody(err, stack, configuration{sha256Fingerprints: true, fingerprintWithoutLines: true}); moved != unmoved {
+		t.Error("should not fingerprint line numbers")
+	}
+
+	// CRC32 can't tell these apart: the fields run into each other.
+	a := Stack{{Filename: "a", Method: "b1", Line: 2}}
+	b := Stack{{Filename: "ab", Method: "1", Line: 2}}
+	if a.Fingerprint() != b.Fingerprint() || a.sha256Fingerprint(true) == b.sha256Fingerprint(true) {
+		t.Error("should separate the fields of frames")
+	}
+}
+
+func TestTitleField(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+
+	client.Error(ERR, errors.New("card_declined"), Title("Payment capture failed for gateway=stripe"))
+	client.Message(INFO, "hello")
+
+	want := []string{"Payment capture failed for gateway=stripe", "hello"}
+	for i, payload := range transport.payloads {
+		var body struct {
+			Data struct {
+				Title string `json:"title"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(payload, &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Data.Title != want[i] {
+			t.Errorf("items[%d]: got title %q", i, body.Data.Title)
+		}
+	}
+}
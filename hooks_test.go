@@ -0,0 +1,42 @@
+package rollbar
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSendHooks(t *testing.T) {
+	transport := &fakeTransport{}
+	var after []error
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil),
+		WithBeforeSend(func(item *Item, payload []byte) []byte {
+			return bytes.Replace(payload, []byte("draft"), []byte("final"), 1)
+		}),
+		WithAfterSend(func(item *Item, payload []byte, err error) {
+			if !bytes.Contains(payload, []byte("final")) {
+				t.Errorf("got payload %s", payload)
+			}
+			after = append(after, err)
+		}))
+
+	client.Message(INFO, "draft")
+	if !bytes.Contains(transport.payloads[0], []byte("final")) {
+		t.Errorf("should send the payload returned by the hook, got %s", transport.payloads[0])
+	}
+	if len(after) != 1 || after[0] != nil {
+		t.Errorf("got %v", after)
+	}
+}
+
+func TestAfterSendFailure(t *testing.T) {
+	failure := ErrHTTPError(400)
+	var got error
+	client := New("token", WithTransport(failingTransport{failure}), WithSync(), WithErrorWriter(nil),
+		WithAfterSend(func(item *Item, payload []byte, err error) { got = err }))
+
+	client.Message(INFO, "rejected")
+	if !errors.Is(got, failure) {
+		t.Errorf("got %v", got)
+	}
+}
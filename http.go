@@ -0,0 +1,111 @@
+package rollbar
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// httpTransport is the default Transport. It POSTs items to the endpoint of
+// its Client, honoring the Client's current HTTP settings, and pauses the
+// Client's deliveries when the project is rate limited.
+type httpTransport struct {
+	client *Client
+}
+
+// Send POSTs the given encoded JSON body to Rollbar.
+func (t *httpTransport) Send(ctx context.Context, jsonBody []byte) error {
+	c := t.client
+	config := c.snapshot()
+
+	payload, encoding, err := compress(jsonBody, config.gzipMinSize)
+	if err != nil {
+		c.errorf("failed to compress payload: %s", err.Error())
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", config.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		c.errorf("POST failed: %s", err.Error())
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+
+	resp, err := config.httpClient.Do(req)
+	if err != nil {
+		c.errorf("POST failed: %s", err.Error())
+		return err
+	}
+	defer resp.Body.Close()
+
+	if pause, limited := rateLimitPause(resp); limited {
+		c.pauseDelivery(pause)
+	}
+
+	if err := responseError(resp); err != nil {
+		c.errorf("received response: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// maxResponseSize bounds how much of an API response is read.
+const maxResponseSize = 64 * 1024
+
+// responseError returns the error reported by the given Rollbar API
+// response, if any. Rollbar responds with a JSON body such as
+// {"err": 1, "message": "invalid access token"}; when the body carries a
+// message, the error is an *APIError, otherwise it is the ErrHTTPError for
+// the response status.
+func responseError(resp *http.Response) error {
+	var result struct {
+		Err     int    `json:"err"`
+		Message string `json:"message"`
+	}
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&result)
+
+	if resp.StatusCode == http.StatusOK && (decodeErr != nil || result.Err == 0) {
+		return nil
+	}
+	if decodeErr != nil || result.Message == "" {
+		return ErrHTTPError(resp.StatusCode)
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: result.Message}
+}
+
+// compress gzips the given encoded JSON body if it is at least minSize bytes
+// long, returning the payload to POST and its Content-Encoding ("" if it was
+// left alone). A negative minSize disables compression.
+func compress(jsonBody []byte, minSize int) ([]byte, string, error) {
+	if minSize < 0 || len(jsonBody) < minSize {
+		return jsonBody, "", nil
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(jsonBody); err != nil {
+		return nil, "", err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "gzip", nil
+}
+
+// Flush implements Transport. POSTs are synchronous, so there is nothing to
+// flush.
+func (t *httpTransport) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close implements Transport.
+func (t *httpTransport) Close() error {
+	return nil
+}
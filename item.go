@@ -0,0 +1,80 @@
+package rollbar
+
+import (
+	"context"
+	"net/http"
+)
+
+// Item describes a single error or message occurrence reported to Rollbar. It
+// is what routing rules and the CheckIgnore hook look at to decide what
+// happens to an occurrence.
+type Item struct {
+	// Level is the Rollbar severity level (CRIT, ERR, etc.).
+	Level string
+
+	// Title is the error message, or the message body for messages.
+	Title string
+
+	// Err is the reported error. It is nil for messages.
+	Err error
+
+	// Stack is the stack trace of where an error was reported. Errors that
+	// carry their own (see Stacker) are reported with that one instead. It is
+	// nil for messages.
+	Stack Stack
+
+	// Data is the structured key / value pairs reported with a message by
+	// MessageWithData. It is nil for errors.
+	Data map[string]interface{}
+
+	// Request is the HTTP request reported with an error, if any.
+	Request *http.Request
+
+	// UUID identifies the occurrence in Rollbar. It is set once the item has
+	// been built, after the CheckIgnore hook has run.
+	UUID string
+
+	isMessage bool
+
+	// class overrides the exception class computed by errorClass; see
+	// Client.SetClassifier.
+	class string
+
+	// ctx is the context the item was reported with, if any, in which its
+	// person and pprof labels are looked up once it has gone through the
+	// filters.
+	ctx context.Context
+}
+
+func newErrorItem(level string, err error, stack Stack) *Item {
+	return &Item{Level: level, Title: errorTitle(err), Err: err, Stack: stack}
+}
+
+func newMessageItem(level string, msg string) *Item {
+	return &Item{Level: level, Title: msg, isMessage: true}
+}
+
+// Class returns the exception class reported for the item, or "" for
+// messages.
+func (item *Item) Class() string {
+	if item.isMessage {
+		return ""
+	}
+	if item.class != "" {
+		return item.class
+	}
+	return errorClass(item.Err)
+}
+
+// fingerprint identifies items that describe the same occurrence: same level,
+// class, message and stack trace.
+func (item *Item) fingerprint() string {
+	return item.Level + "\x00" + item.Class() + "\x00" + item.Title + "\x00" + item.Stack.Fingerprint()
+}
+
+func errorTitle(err error) string {
+	if err == nil {
+		return nilErrTitle
+	}
+	return err.Error()
+}
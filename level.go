@@ -0,0 +1,132 @@
+package rollbar
+
+import (
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// levelRanks ranks the severity levels, from the least to the most severe.
+var levelRanks = map[string]int{DEBUG: 0, INFO: 1, WARN: 2, ERR: 3, CRIT: 4}
+
+// packageLevel is the minimum level of the items reported from the packages
+// under an import path prefix; see WithPackageLevel.
+type packageLevel struct {
+	prefix string
+	level  string
+}
+
+// addPackageLevel appends a packageLevel without touching the backing array
+// of earlier snapshots.
+func (config *configuration) addPackageLevel(prefix, level string) {
+	packageLevels := make([]packageLevel, len(config.packageLevels), len(config.packageLevels)+1)
+	copy(packageLevels, config.packageLevels)
+	config.packageLevels = append(packageLevels, packageLevel{prefix: strings.TrimSuffix(prefix, "/"), level: level})
+}
+
+// levelRule sets the level of the items matched by match; see WithLevelRule.
+type levelRule struct {
+	match Matcher
+	level string
+}
+
+// addLevelAlias adds a level alias to a copy of the aliases of earlier
+// snapshots.
+func (config *configuration) addLevelAlias(name, level string) {
+	aliases := make(map[string]string, len(config.levelAliases)+1)
+	for alias, aliased := range config.levelAliases {
+		aliases[alias] = aliased
+	}
+	aliases[name] = level
+	config.levelAliases = aliases
+}
+
+// addLevelRule appends a levelRule without touching the backing array of
+// earlier snapshots.
+func (config *configuration) addLevelRule(level string, match Matcher) {
+	levelRules := make([]levelRule, len(config.levelRules), len(config.levelRules)+1)
+	copy(levelRules, config.levelRules)
+	config.levelRules = append(levelRules, levelRule{match: match, level: level})
+}
+
+// aliasedLevel returns the Rollbar level an application-specific level is an
+// alias of, or level itself.
+func (config *configuration) aliasedLevel(level string) string {
+	if aliased, ok := config.levelAliases[level]; ok {
+		return aliased
+	}
+	return level
+}
+
+// applyLevel sets the level item is reported at: that of the first level rule
+// that matches it, or else the Rollbar level its own level is an alias of, if
+// any. Rules are matched against the item with its level unaliased.
+func (config *configuration) applyLevel(item *Item) {
+	item.Level = config.aliasedLevel(item.Level)
+	for _, rule := range config.levelRules {
+		if rule.match(item) {
+			item.Level = rule.level
+			return
+		}
+	}
+}
+
+// StatusLevel returns the severity level of an HTTP response with the given
+// status code: ERR for server errors, WARN for client errors and INFO
+// otherwise.
+//
+//	rollbar.Message(rollbar.StatusLevel(resp.StatusCode), "upstream responded "+resp.Status)
+func StatusLevel(statusCode int) string {
+	switch {
+	case statusCode >= http.StatusInternalServerError:
+		return ERR
+	case statusCode >= http.StatusBadRequest:
+		return WARN
+	default:
+		return INFO
+	}
+}
+
+// belowMinLevel reports whether item is less severe than the minimum level of
+// the package it was reported from, or the Client's minimum level. Items of
+// unknown levels are never dropped.
+func (config *configuration) belowMinLevel(item *Item) bool {
+	rank, ok := levelRanks[item.Level]
+	if !ok {
+		return false
+	}
+	min := config.minLevel
+	if frame, ok := inAppFrame(item.Stack); ok {
+		longest := -1
+		for _, rule := range config.packageLevels {
+			if len(rule.prefix) > longest && underPrefix(frame.Filename, rule.prefix) {
+				min, longest = rule.level, len(rule.prefix)
+			}
+		}
+	}
+	minRank, ok := levelRanks[min]
+	return ok && rank < minRank
+}
+
+// inAppFrame returns the innermost frame of the given stack that isn't of the
+// standard library, whose file paths are either under GOROOT or shortened to
+// their import path, which has no dot in its first element.
+func inAppFrame(stack Stack) (Frame, bool) {
+	gorootSrc := slashPath(runtime.GOROOT()) + "/src/"
+	for _, frame := range stack {
+		if frame.Filename == "..." || strings.HasPrefix(frame.Filename, gorootSrc) {
+			continue
+		}
+		slash := strings.Index(frame.Filename, "/")
+		if slash <= 0 || strings.Contains(frame.Filename[:slash], ".") || strings.Contains(frame.Filename[:slash], ":") {
+			return frame, true
+		}
+	}
+	return Frame{}, false
+}
+
+// underPrefix reports whether the file at path is in the package with the
+// given import path, or in one of its sub-packages.
+func underPrefix(path, prefix string) bool {
+	return strings.HasPrefix(path, prefix+"/")
+}
@@ -0,0 +1,103 @@
+package rollbar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func TestMinLevel(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil),
+		WithMinLevel(WARN),
+		WithPackageLevel("github.com/acme/payments", DEBUG),
+		WithPackageLevel("github.com/acme/payments/retry/", ERR),
+	)
+
+	stack := func(files ...string) Stack {
+		stack := Stack{}
+		for _, file := range files {
+			stack = append(stack, Frame{Filename: file, Method: "f", Line: 1})
+		}
+		return stack
+	}
+	tests := []struct {
+		level    string
+		stack    Stack
+		reported bool
+	}{
+		{INFO, stack("github.com/acme/web/handler.go"), false},
+		{WARN, stack("github.com/acme/web/handler.go"), true},
+		{DEBUG, stack(ShortenFilePath(runtime.GOROOT()+"/src/net/http/server.go"), "github.com/acme/payments/charge.go"), true},
+		{DEBUG, stack(ShortenFilePath(runtime.GOROOT()+"/src/encoding/json/decode.go"), "github.com/acme/web/handler.go"), false},
+		{DEBUG, stack("github.com/acme/paymentsv2/charge.go"), false},
+		{WARN, stack("github.com/acme/payments/retry/retry.go"), false},
+		{CRIT, stack("github.com/acme/payments/retry/retry.go"), true},
+		{DEBUG, stack("/home/jane/tool/main.go"), false},
+		{"custom", stack("github.com/acme/web/handler.go"), true},
+	}
+	for i, test := range tests {
+		transport.payloads = nil
+		client.ErrorWithStack(test.level, errors.New("oops"), test.stack)
+		if reported := len(transport.payloads) == 1; reported != test.reported {
+			t.Errorf("tests[%d]: got reported %t", i, reported)
+		}
+	}
+
+	transport.payloads = nil
+	client.Message(INFO, "hello")
+	client.SetMinLevel(DEBUG)
+	client.Message(INFO, "hello")
+	if len(transport.payloads) != 1 {
+		t.Errorf("should filter messages by the minimum level, got %d items", len(transport.payloads))
+	}
+}
+
+func TestLevelMapping(t *testing.T) {
+	transport := &fakeTransport{}
+	errThrottled := errors.New("throttled")
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil),
+		WithMinLevel(INFO),
+		WithLevelAlias("fatal", CRIT),
+		WithLevelAlias("trace", DEBUG),
+		WithLevelRule(WARN, MatchErrors(errThrottled)),
+	)
+
+	tests := []struct {
+		level string
+		err   error
+		want  string
+	}{
+		{"fatal", errors.New("oops"), CRIT},
+		{"trace", errors.New("oops"), ""},
+		{CRIT, fmt.Errorf("charge: %w", errThrottled), WARN},
+		{ERR, errors.New("oops"), ERR},
+	}
+	for i, test := range tests {
+		transport.payloads = nil
+		client.Error(test.level, test.err)
+		got := ""
+		if len(transport.payloads) == 1 {
+			var body struct {
+				Data struct {
+					Level string `json:"level"`
+				} `json:"data"`
+			}
+			json.Unmarshal(transport.payloads[0], &body)
+			got = body.Data.Level
+		}
+		if got != test.want {
+			t.Errorf("tests[%d]: got level %q", i, got)
+		}
+	}
+}
+
+func TestStatusLevel(t *testing.T) {
+	for status, want := range map[int]string{200: INFO, 302: INFO, 404: WARN, 429: WARN, 500: ERR, 503: ERR} {
+		if got := StatusLevel(status); got != want {
+			t.Errorf("StatusLevel(%d) = %q", status, got)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package rollbar
+
+import (
+	"sync"
+	"time"
+)
+
+// itemLimiter enforces client-side limits on how many items are reported per
+// window, overall and per fingerprint, so that a crash loop can't use up the
+// project's quota in seconds.
+type itemLimiter struct {
+	mutex       sync.Mutex
+	windowStart time.Time
+	total       int
+	counts      map[string]int
+}
+
+// allow reports whether an item with the given fingerprint is within the
+// limits for the current window, counting it if so. A limit of 0 or less is
+// no limit; a window of 0 or less is DefaultItemLimitWindow.
+func (l *itemLimiter) allow(fingerprint string, window time.Duration, limit, perFingerprint int) bool {
+	if limit <= 0 && perFingerprint <= 0 {
+		return true
+	}
+
+	if window <= 0 {
+		window = DefaultItemLimitWindow
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	if l.counts == nil || now.Sub(l.windowStart) >= window {
+		l.windowStart = now
+		l.total = 0
+		l.counts = map[string]int{}
+	}
+
+	if limit > 0 && l.total >= limit {
+		return false
+	}
+	if perFingerprint > 0 && l.counts[fingerprint] >= perFingerprint {
+		return false
+	}
+
+	l.total++
+	if perFingerprint > 0 {
+		l.counts[fingerprint]++
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package rollbar
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestItemLimit(t *testing.T) {
+	transport := &fakeTransport{}
+	var dropped int
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil),
+		WithItemLimit(3, 2, time.Minute),
+		WithDeliveryErrorHandler(func(item *Item, err error) { dropped++ }))
+
+	loop := errors.New("crash loop")
+	for i := 0; i < 3; i++ {
+		client.Error(ERR, loop)
+	}
+	client.Message(INFO, "one")
+	if _, err := client.Message(INFO, "two"); err != ErrRateLimited {
+		t.Errorf("got %v", err)
+	}
+
+	if len(transport.payloads) != 3 {
+		t.Errorf("got %d items", len(transport.payloads))
+	}
+	if stats := client.Stats(); stats.DroppedRateLimited != 2 || dropped != 2 {
+		t.Errorf("got %d rate limited items, %d dropped", stats.DroppedRateLimited, dropped)
+	}
+}
+
+func TestItemLimiterWindow(t *testing.T) {
+	var limiter itemLimiter
+	if !limiter.allow("a", 10*time.Millisecond, 1, 0) || limiter.allow("b", 10*time.Millisecond, 1, 0) {
+		t.Fatal("should allow one item per window")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if !limiter.allow("b", 10*time.Millisecond, 1, 0) {
+		t.Error("should reset the limit once the window is over")
+	}
+}
+
+func TestItemLimiterDefaultWindow(t *testing.T) {
+	var limiter itemLimiter
+	if !limiter.allow("a", 0, 1, 0) {
+		t.Fatal("should allow the first item")
+	}
+	if limiter.allow("b", 0, 1, 0) {
+		t.Error("should default the window rather than reset the limit on every item")
+	}
+}
@@ -0,0 +1,48 @@
+package rollbar
+
+import (
+	"fmt"
+	"io"
+)
+
+// Logger receives a Client's own diagnostics: items dropped because the
+// queue is full, failed POSTs, rate limiting, etc. Implementations can hand
+// them to the application's logging pipeline (log/slog, zap, ...) and must be
+// safe for concurrent use.
+type Logger interface {
+	// Debugf logs routine events, such as a truncated payload or a pause
+	// for rate limiting.
+	Debugf(format string, args ...interface{})
+
+	// Errorf logs failures, such as a POST that failed or an item that was
+	// dropped.
+	Errorf(format string, args ...interface{})
+}
+
+// nopLogger is the default Logger. It discards everything.
+type nopLogger struct{}
+
+func (nopLogger) Debugf(format string, args ...interface{}) {}
+func (nopLogger) Errorf(format string, args ...interface{}) {}
+
+// writerLogger is a Logger that writes errors, one per line, to an
+// io.Writer, and discards debug messages.
+type writerLogger struct {
+	w io.Writer
+}
+
+func (l writerLogger) Debugf(format string, args ...interface{}) {}
+
+func (l writerLogger) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, "Rollbar error: "+format+"\n", args...)
+}
+
+// -- Logging
+
+func (c *Client) errorf(format string, args ...interface{}) {
+	c.snapshot().logger.Errorf(format, args...)
+}
+
+func (c *Client) debugf(format string, args ...interface{}) {
+	c.snapshot().logger.Debugf(format, args...)
+}
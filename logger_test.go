@@ -0,0 +1,54 @@
+package rollbar
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordingLogger struct {
+	mutex  sync.Mutex
+	debugs []string
+	errors []string
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.debugs = append(l.debugs, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+func TestLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	client := New("token", WithTransport(failingTransport{ErrHTTPError(400)}), WithSync(),
+		WithMaxPayloadSize(10), WithLogger(logger))
+	client.Message(INFO, "too large to fit in ten bytes")
+
+	if len(logger.debugs) != 1 || !strings.Contains(logger.debugs[0], "truncated") {
+		t.Errorf("got debug messages %q", logger.debugs)
+	}
+	client.Close()
+	client.Message(INFO, "late")
+	if len(logger.errors) != 1 || !strings.Contains(logger.errors[0], "client closed") {
+		t.Errorf("got error messages %q", logger.errors)
+	}
+}
+
+func TestErrorWriter(t *testing.T) {
+	var buf bytes.Buffer
+	client := New("token", WithErrorWriter(&buf))
+	client.Close()
+	client.Message(INFO, "late")
+
+	if got := buf.String(); got != "Rollbar error: client closed, dropping error on the floor\n" {
+		t.Errorf("got %q", got)
+	}
+}
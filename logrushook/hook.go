@@ -0,0 +1,96 @@
+// Package logrushook provides a logrus Hook that reports log entries to
+// Rollbar:
+//
+//	client := rollbar.New(token)
+//	logrus.AddHook(logrushook.New(client))
+//	logrus.WithError(err).WithField("order", orderID).Error("charge failed")
+//
+// The error attached with WithError is reported with a stack trace of the log
+// call site, and the other fields are reported as custom data. Entries
+// without an error are reported as messages.
+package logrushook
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/stvp/rollbar"
+)
+
+// messageField is the custom data field that holds the log message of
+// entries reported as errors.
+const messageField = "log_message"
+
+// DefaultLevels are the levels of the entries a Hook reports unless it is
+// created with others.
+var DefaultLevels = []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+
+// Hook is a logrus.Hook that reports entries to Rollbar.
+type Hook struct {
+	client *rollbar.Client
+	levels []logrus.Level
+}
+
+// New returns a Hook that reports entries at the given levels (DefaultLevels
+// if none are given) to client.
+func New(client *rollbar.Client, levels ...logrus.Level) *Hook {
+	if len(levels) == 0 {
+		levels = DefaultLevels
+	}
+	return &Hook{client: client, levels: levels}
+}
+
+// Levels implements logrus.Hook.
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire implements logrus.Hook. Fatal and panic entries are delivered before
+// Fire returns, since logrus exits or panics right after.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	custom := map[string]interface{}{}
+	var err error
+	for key, value := range entry.Data {
+		if e, ok := value.(error); ok && key == logrus.ErrorKey {
+			err = e
+			continue
+		}
+		if e, ok := value.(error); ok {
+			value = e.Error()
+		}
+		custom[key] = value
+	}
+
+	var fields []*rollbar.Field
+	var reportErr error
+	level := rollbarLevel(entry.Level)
+	if err == nil {
+		if len(custom) > 0 {
+			fields = append(fields, rollbar.Custom(custom))
+		}
+		_, reportErr = h.client.MessageWithContext(entry.Context, level, entry.Message, fields...)
+	} else {
+		custom[messageField] = entry.Message
+		fields = append(fields, rollbar.Custom(custom))
+		_, reportErr = h.client.ErrorWithStackContext(entry.Context, level, err, h.client.CallerStack(0, "github.com/sirupsen/logrus"), fields...)
+	}
+
+	if entry.Level <= logrus.FatalLevel {
+		h.client.Wait()
+	}
+	return reportErr
+}
+
+// rollbarLevel maps a logrus level to a Rollbar level.
+func rollbarLevel(level logrus.Level) string {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return rollbar.CRIT
+	case logrus.ErrorLevel:
+		return rollbar.ERR
+	case logrus.WarnLevel:
+		return rollbar.WARN
+	case logrus.InfoLevel:
+		return rollbar.INFO
+	default:
+		return rollbar.DEBUG
+	}
+}
@@ -0,0 +1,53 @@
+package logrushook
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stvp/rollbar"
+	"github.com/stvp/rollbar/rollbartest"
+)
+
+func newLogger(client *rollbar.Client) *logrus.Logger {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	logger.AddHook(New(client))
+	return logger
+}
+
+func TestHookReportsErrors(t *testing.T) {
+	client, transport := rollbartest.NewClient()
+	logger := newLogger(client)
+
+	logger.WithError(errors.New("card declined")).WithField("order", 42).Error("charge failed")
+
+	item := transport.AssertMessage(t, "card declined")
+	if item.Level != rollbar.ERR {
+		t.Errorf("got level %s", item.Level)
+	}
+	if item.Custom["order"] != float64(42) || item.Custom[messageField] != "charge failed" {
+		t.Errorf("got custom data %v", item.Custom)
+	}
+
+	frames := item.Data["body"].(map[string]interface{})["trace"].(map[string]interface{})["frames"].([]interface{})
+	if file := frames[0].(map[string]interface{})["filename"].(string); !strings.HasSuffix(file, "hook_test.go") {
+		t.Errorf("should capture the stack at the log call site, got %s", file)
+	}
+}
+
+func TestHookLevels(t *testing.T) {
+	client, transport := rollbartest.NewClient()
+	logger := newLogger(client)
+
+	logger.Warn("ignored")
+	logger.WithField("user", "jane").Error("no error attached")
+
+	transport.AssertCount(t, 1)
+	item := transport.AssertMessage(t, "no error attached")
+	if item.Custom["user"] != "jane" {
+		t.Errorf("got custom data %v", item.Custom)
+	}
+}
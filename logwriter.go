@@ -0,0 +1,77 @@
+package rollbar
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// logOutputField is the custom data field that holds the full output of log
+// messages that span several lines, such as panics with their goroutine
+// dumps.
+const logOutputField = "log_output"
+
+var (
+	// logTimestamp matches the date and time prefix written by the standard
+	// log package.
+	logTimestamp = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} )?\d{2}:\d{2}:\d{2}(\.\d+)? `)
+
+	// logLevels classify log messages, first match wins.
+	logLevels = []struct {
+		pattern *regexp.Regexp
+		level   string
+	}{
+		{regexp.MustCompile(`(?i)\b(panic|fatal)`), CRIT},
+		{regexp.MustCompile(`(?i)\b(error|fail|unable|cannot|can't)`), ERR},
+		{regexp.MustCompile(`(?i)\bwarn`), WARN},
+	}
+)
+
+// logWriter is an io.Writer that reports every message written to it.
+type logWriter struct {
+	client *Client
+}
+
+// LogWriter returns an io.Writer that reports every message written to it as
+// a message, e.g. to capture the output of the standard library's loggers:
+//
+//	server := &http.Server{ErrorLog: log.New(client.LogWriter(), "", 0)}
+//
+// The level of each message is guessed from its words: "panic" and "fatal"
+// are CRIT, "error" or "failed" are ERR, "warning" is WARN and anything else
+// is INFO. Messages that span several lines, like "http: panic serving ..."
+// with its goroutine dump, are reported under their first line, with the full
+// output as custom data.
+func (c *Client) LogWriter() io.Writer {
+	return &logWriter{client: c}
+}
+
+// Write implements io.Writer. log.Logger writes each message with a single
+// call.
+func (w *logWriter) Write(p []byte) (int, error) {
+	output := strings.TrimRight(string(p), "\n")
+	output = logTimestamp.ReplaceAllString(output, "")
+	if strings.TrimSpace(output) == "" {
+		return len(p), nil
+	}
+
+	title := output
+	var fields []*Field
+	if i := strings.IndexByte(output, '\n'); i >= 0 {
+		title = output[:i]
+		fields = append(fields, Custom(map[string]interface{}{logOutputField: output}))
+	}
+
+	w.client.Message(logLevel(title), title, fields...)
+	return len(p), nil
+}
+
+// logLevel guesses the level of a log message.
+func logLevel(message string) string {
+	for _, l := range logLevels {
+		if l.pattern.MatchString(message) {
+			return l.level
+		}
+	}
+	return INFO
+}
@@ -0,0 +1,43 @@
+package rollbar
+
+import (
+	"encoding/json"
+	"log"
+	"testing"
+)
+
+func TestLogWriter(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+	logger := log.New(client.LogWriter(), "", log.LstdFlags)
+
+	logger.Printf("http: panic serving 10.0.0.1:1234: boom\ngoroutine 5 [running]:\nmain.handler()")
+	logger.Printf("http: TLS handshake error from 10.0.0.2:5678: EOF")
+	logger.Printf("server started")
+
+	if len(transport.payloads) != 3 {
+		t.Fatalf("got %d items", len(transport.payloads))
+	}
+
+	type logItem struct {
+		Data struct {
+			Level  string                 `json:"level"`
+			Title  string                 `json:"title"`
+			Custom map[string]interface{} `json:"custom"`
+		} `json:"data"`
+	}
+	items := make([]logItem, len(transport.payloads))
+	for i, payload := range transport.payloads {
+		json.Unmarshal(payload, &items[i])
+	}
+
+	if items[0].Data.Level != CRIT || items[0].Data.Title != "http: panic serving 10.0.0.1:1234: boom" {
+		t.Errorf("got %s: %q", items[0].Data.Level, items[0].Data.Title)
+	}
+	if output, _ := items[0].Data.Custom[logOutputField].(string); output == "" {
+		t.Error("should report the full output of multi-line messages")
+	}
+	if items[1].Data.Level != ERR || items[2].Data.Level != INFO {
+		t.Errorf("got levels %s, %s", items[1].Data.Level, items[2].Data.Level)
+	}
+}
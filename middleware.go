@@ -0,0 +1,89 @@
+package rollbar
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// Middleware returns an http.Handler that calls next and reports any panic it
+// raises to Rollbar as a critical error, along with the request details. The
+// client gets a 500 Internal Server Error response, unless next had already
+// started writing its own, and the server carries on serving other requests.
+// Panics with http.ErrAbortHandler are not reported; they are re-raised so
+// that net/http can abort the response as intended.
+//
+// Wrapped around an http.ServeMux, the Middleware reports panics with the
+// pattern of the route that matched the request as their context (see
+// ItemContext); see WithRoutePattern for other routers. With WithRequestBody,
+// errors reported with the request passed to next include its body.
+func (c *Client) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = c.CaptureRequestBody(r)
+		rw := &responseWriter{ResponseWriter: w}
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+			if value == http.ErrAbortHandler {
+				panic(value)
+			}
+
+			c.RequestErrorWithStackSkip(CRIT, r, panicError(value), 1, c.panicFields(nil)...)
+			if !rw.written {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// Middleware returns an http.Handler that calls next and reports any panic it
+// raises to Rollbar using the package-level configuration. See
+// Client.Middleware.
+func Middleware(next http.Handler) http.Handler {
+	return std.Middleware(next)
+}
+
+// responseWriter records whether a handler has started writing its response,
+// so that the Middleware doesn't write a 500 over it.
+type responseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher if the underlying ResponseWriter does.
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.written = true
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the underlying ResponseWriter does.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("rollbar: the ResponseWriter does not implement http.Hijacker")
+	}
+	w.written = true
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
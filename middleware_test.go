@@ -0,0 +1,181 @@
+package rollbar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	items := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		items <- body
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL))
+	handler := client.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(errors.New("handler exploded"))
+	}))
+
+	r := httptest.NewRequest("GET", "http://example.com/orders?id=1", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	client.Wait()
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("got status: %d", w.Code)
+	}
+
+	data := (<-items)["data"].(map[string]interface{})
+	if data["level"] != CRIT {
+		t.Errorf("got level: %v", data["level"])
+	}
+	if data["title"] != "handler exploded" {
+		t.Errorf("got title: %v", data["title"])
+	}
+	request := data["request"].(map[string]interface{})
+	if request["url"] != "http://example.com/orders?id=1" {
+		t.Errorf("got url: %v", request["url"])
+	}
+	if request["method"] != "GET" {
+		t.Errorf("got method: %v", request["method"])
+	}
+}
+
+func TestMiddlewarePanicAfterWrite(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+	handler := client.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("partial"))
+		panic(errors.New("handler exploded"))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusAccepted || w.Body.String() != "partial" {
+		t.Errorf("should leave responses already written alone, got %d %q", w.Code, w.Body.String())
+	}
+	if len(transport.payloads) != 1 {
+		t.Errorf("got %d items", len(transport.payloads))
+	}
+}
+
+func TestMiddlewareWithoutPanic(t *testing.T) {
+	client := New("token", WithEnabled(false))
+	handler := client.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("got status: %d", w.Code)
+	}
+}
+
+func TestMiddlewareAbortHandler(t *testing.T) {
+	client := New("token", WithEnabled(false))
+	handler := client.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if recover() != http.ErrAbortHandler {
+			t.Error("should re-panic with http.ErrAbortHandler")
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestPanicError(t *testing.T) {
+	err := errors.New("boom")
+	if panicError(err) != err {
+		t.Error("should keep errors as they are")
+	}
+	if panicError("boom").Error() != "boom" {
+		t.Error("should format other values")
+	}
+}
+
+func TestMiddlewareRouteContext(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+	handler := client.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/refunds/42" {
+			client.RequestError(ERR, r, errors.New("refund failed"), ItemContext("refunds#show"))
+			return
+		}
+		panic(errors.New("handler exploded"))
+	}))
+
+	// As set by an http.ServeMux.
+	r := httptest.NewRequest("GET", "/orders/42", nil)
+	r.Pattern = "GET /orders/{id}"
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	r = httptest.NewRequest("GET", "/refunds/42", nil)
+	r.Pattern = "GET /refunds/{id}"
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	want := []string{"GET /orders/{id}", "refunds#show"}
+	if len(transport.payloads) != len(want) {
+		t.Fatalf("got %d items", len(transport.payloads))
+	}
+	for i, payload := range transport.payloads {
+		var body struct {
+			Data struct {
+				Context string `json:"context"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(payload, &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Data.Context != want[i] {
+			t.Errorf("items[%d]: got context %q", i, body.Data.Context)
+		}
+	}
+}
+
+type routeKey struct{}
+
+func TestWithRoutePattern(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil),
+		WithRoutePattern(func(r *http.Request) string {
+			route, _ := r.Context().Value(routeKey{}).(*string)
+			if route == nil {
+				return ""
+			}
+			return *route
+		}))
+	// A router that records the route it matched in the request context, as
+	// chi does, once the middleware has been called.
+	var route string
+	handler := client.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route = "/users/{id}"
+		panic(errors.New("handler exploded"))
+	}))
+
+	r := httptest.NewRequest("GET", "/users/42", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), r.WithContext(context.WithValue(r.Context(), routeKey{}, &route)))
+
+	var body struct {
+		Data struct {
+			Context string                 `json:"context"`
+			Request map[string]interface{} `json:"request"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(transport.payloads[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data.Context != "/users/{id}" || body.Data.Request["route"] != "/users/{id}" {
+		t.Errorf("got context %q, request %v", body.Data.Context, body.Data.Request)
+	}
+}
@@ -0,0 +1,157 @@
+package rollbar
+
+import (
+	"path"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// mainModule is the module path of the main module, as recorded in the binary
+// (see debug.ReadBuildInfo), or "" if it is unknown.
+var mainModule = readMainModule()
+
+// mainModuleDir is the directory the main module was built in, once it has
+// been found out by learnMainModuleDir.
+var mainModuleDir struct {
+	sync.RWMutex
+	dir string
+}
+
+// buildVCS describes the version control revision the binary was built from,
+// as stamped by the Go toolchain (see debug.ReadBuildInfo), if any.
+var buildVCS = readBuildVCS()
+
+type vcsInfo struct {
+	revision string
+	time     string
+	modified bool
+}
+
+func readBuildVCS() vcsInfo {
+	var vcs vcsInfo
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return vcs
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			vcs.revision = setting.Value
+		case "vcs.time":
+			vcs.time = setting.Value
+		case "vcs.modified":
+			vcs.modified = setting.Value == "true"
+		}
+	}
+	return vcs
+}
+
+// server adds the revision to the server section of an item, along with when
+// it was committed and whether the working tree had uncommitted changes.
+func (vcs vcsInfo) server(server map[string]interface{}) {
+	if vcs.revision == "" {
+		return
+	}
+	server["sha"] = vcs.revision
+	if vcs.time != "" {
+		server["vcs_time"] = vcs.time
+	}
+	if vcs.modified {
+		server["vcs_modified"] = true
+	}
+}
+
+func readMainModule() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Path == "" || info.Main.Path == "command-line-arguments" {
+		return ""
+	}
+	return info.Main.Path
+}
+
+// learnMainModuleDir finds out the directory the main module was built in
+// from a frame of one of its packages: the directory of the frame's file is
+// that of its package, which is its import path relative to the module path
+// under the module's directory.
+func learnMainModuleDir(frame runtime.Frame) {
+	if mainModule == "" || frame.File == "" {
+		return
+	}
+	mainModuleDir.RLock()
+	known := mainModuleDir.dir != ""
+	mainModuleDir.RUnlock()
+	if known {
+		return
+	}
+
+	pkg := functionPackage(frame.Function)
+	if pkg != mainModule && !strings.HasPrefix(pkg, mainModule+"/") {
+		return
+	}
+	dir := path.Dir(frame.File)
+	rel := strings.TrimPrefix(pkg, mainModule)
+	if !strings.HasSuffix(dir, rel) || dir == rel {
+		return
+	}
+
+	mainModuleDir.Lock()
+	mainModuleDir.dir = strings.TrimSuffix(dir, rel)
+	mainModuleDir.Unlock()
+}
+
+// shortenModulePath shortens the path of a file of a module: files in the
+// module cache are reported by their module path, without the module version,
+// and files of the main module relative to the directory it was built in, as
+// in its repository. It reports whether the file belonged to a module.
+//
+// Examples:
+//
+//	/home/foo/go/pkg/mod/github.com/!burnt!sushi/toml@v1.2.1/decode.go -> github.com/BurntSushi/toml/decode.go
+//	/home/foo/src/api/internal/db/db.go -> internal/db/db.go
+func shortenModulePath(s string) (string, bool) {
+	if idx := strings.Index(s, "/pkg/mod/"); idx != -1 {
+		rest := s[idx+len("/pkg/mod/"):]
+		if at := strings.Index(rest, "@"); at != -1 {
+			version := rest[at:]
+			if slash := strings.Index(version, "/"); slash != -1 {
+				rest = rest[:at] + version[slash:]
+			} else {
+				rest = rest[:at]
+			}
+		}
+		return unescapeModulePath(rest), true
+	}
+
+	mainModuleDir.RLock()
+	dir := mainModuleDir.dir
+	mainModuleDir.RUnlock()
+	if dir != "" && strings.HasPrefix(s, dir+"/") {
+		return s[len(dir)+1:], true
+	}
+	return s, false
+}
+
+// unescapeModulePath undoes the escaping of upper case letters of module
+// paths in the module cache, where "!b" stands for "B".
+func unescapeModulePath(s string) string {
+	if !strings.Contains(s, "!") {
+		return s
+	}
+	var unescaped strings.Builder
+	bang := false
+	for _, r := range s {
+		switch {
+		case bang:
+			unescaped.WriteRune(unicode.ToUpper(r))
+			bang = false
+		case r == '!':
+			bang = true
+		default:
+			unescaped.WriteRune(r)
+		}
+	}
+	return unescaped.String()
+}
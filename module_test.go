@@ -0,0 +1,69 @@
+package rollbar
+
+import (
+	"encoding/json"
+	"runtime"
+	"testing"
+)
+
+func TestShortenModuleCachePath(t *testing.T) {
+	tests := map[string]string{
+		"/home/foo/go/pkg/mod/github.com/lib/pq@v1.10.9/conn.go":               "github.com/lib/pq/conn.go",
+		"/home/foo/go/pkg/mod/github.com/!burnt!sushi/toml@v1.2.1/decode.go":   "github.com/BurntSushi/toml/decode.go",
+		"/root/go/pkg/mod/golang.org/x/net@v0.0.0-20230101/http2/transport.go": "golang.org/x/net/http2/transport.go",
+		"/home/foo/go/pkg/mod/github.com/jackc/pgx/v5@v5.4.3/pgconn/pgconn.go": "github.com/jackc/pgx/v5/pgconn/pgconn.go",
+	}
+	for given, expected := range tests {
+		if got := shortenFilePath(given); got != expected {
+			t.Errorf("%s: got %s", given, got)
+		}
+	}
+}
+
+func TestShortenMainModulePath(t *testing.T) {
+	defer func(module string) {
+		mainModule = module
+		mainModuleDir.dir = ""
+	}(mainModule)
+	mainModule = "example.com/api"
+	mainModuleDir.dir = ""
+
+	learnMainModuleDir(runtime.Frame{Function: "example.com/other.F", File: "/src/other/f.go"})
+	learnMainModuleDir(runtime.Frame{Function: "example.com/api/internal/db.(*DB).Query", File: "/home/foo/src/api/internal/db/db.go"})
+	if mainModuleDir.dir != "/home/foo/src/api" {
+		t.Fatalf("got %q", mainModuleDir.dir)
+	}
+
+	if got := shortenFilePath("/home/foo/src/api/cmd/server/main.go"); got != "cmd/server/main.go" {
+		t.Errorf("got %s", got)
+	}
+	if got := shortenFilePath("/home/foo/src/apiserver/main.go"); got != "/home/foo/src/apiserver/main.go" {
+		t.Errorf("should not shorten files of other directories, got %s", got)
+	}
+}
+
+func TestBuildVCS(t *testing.T) {
+	defer func(vcs vcsInfo) { buildVCS = vcs }(buildVCS)
+	buildVCS = vcsInfo{revision: "0123456789abcdef0123456789abcdef01234567", time: "2024-05-01T12:00:00Z", modified: true}
+
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+	client.Message(INFO, "deployed")
+	client.SetCodeVersion("v1.2.0")
+	client.Message(INFO, "deployed")
+
+	for i, codeVersion := range []string{buildVCS.revision, "v1.2.0"} {
+		var body map[string]interface{}
+		if err := json.Unmarshal(transport.payloads[i], &body); err != nil {
+			t.Fatal(err)
+		}
+		data := body["data"].(map[string]interface{})
+		if data["code_version"] != codeVersion {
+			t.Errorf("got code version %v, expected %s", data["code_version"], codeVersion)
+		}
+		server := data["server"].(map[string]interface{})
+		if server["sha"] != buildVCS.revision || server["vcs_time"] != buildVCS.time || server["vcs_modified"] != true {
+			t.Errorf("got server %v", server)
+		}
+	}
+}
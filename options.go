@@ -0,0 +1,815 @@
+package rollbar
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// Option configures a Client. Options are passed to New.
+type Option func(*configuration)
+
+// WithEnvironment sets the environment under which all items will be
+// reported. The default is "development".
+func WithEnvironment(environment string) Option {
+	return func(config *configuration) {
+		config.environment = environment
+	}
+}
+
+// WithEndpoint sets the URL destination for all item POST requests. The
+// default is DefaultEndpoint.
+func WithEndpoint(endpoint string) Option {
+	return func(config *configuration) {
+		config.endpoint = endpoint
+	}
+}
+
+// WithBaseURL sets the base URL of the Rollbar API, for self-hosted Rollbar
+// installs and egress proxies. All API requests, including item POSTs, are
+// made relative to it. The default is DefaultBaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(config *configuration) {
+		config.baseURL, config.endpoint = apiURLs(baseURL)
+	}
+}
+
+// WithHTTPClient sets the http.Client used to POST items to Rollbar. The
+// default is http.DefaultClient; passing nil restores it.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(config *configuration) {
+		if httpClient == nil {
+			httpClient = http.DefaultClient
+		}
+		config.httpClient = httpClient
+	}
+}
+
+// WithTransport makes the Client deliver items through the given Transport
+// instead of POSTing them to the Rollbar API itself. HTTP options such as
+// WithEndpoint, WithHTTPClient and WithGzip have no effect then; passing nil
+// restores the default.
+func WithTransport(transport Transport) Option {
+	return func(config *configuration) {
+		config.transport = transport
+	}
+}
+
+// WithRoundTripper makes the Client POST items through the given
+// http.RoundTripper, e.g. a corporate egress gateway, an mTLS transport or an
+// instrumented wrapper around http.DefaultTransport. It replaces the
+// transport of any http.Client set with WithHTTPClient, keeping its other
+// settings.
+func WithRoundTripper(transport http.RoundTripper) Option {
+	return func(config *configuration) {
+		config.setTransport(transport)
+	}
+}
+
+// WithProxyURL makes the Client POST items through the given HTTP(S) proxy,
+// for environments where direct egress to Rollbar is blocked. By default, the
+// proxy named by the HTTPS_PROXY / NO_PROXY environment variables is used.
+// Passing nil disables proxying altogether. It has no effect on Clients that
+// POST through a RoundTripper other than an *http.Transport, which then log
+// an error once all of their options have been applied.
+func WithProxyURL(proxyURL *url.URL) Option {
+	return func(config *configuration) {
+		config.adjustTransport("WithProxyURL", func(transport *http.Transport) {
+			transport.Proxy = http.ProxyURL(proxyURL)
+			if proxyURL == nil {
+				transport.Proxy = nil
+			}
+		})
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used to talk to Rollbar, e.g. to
+// trust a private CA or a TLS-intercepting proxy, or to enforce a minimum TLS
+// version. The given config should not be modified afterwards. Like
+// WithProxyURL, it has no effect on Clients that POST through a RoundTripper
+// other than an *http.Transport, and logs an error then.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(config *configuration) {
+		config.adjustTransport("WithTLSConfig", func(transport *http.Transport) {
+			transport.TLSClientConfig = tlsConfig
+		})
+	}
+}
+
+// WithCodeVersion sets the code version reported for all items. The default is
+// the VCS revision the binary was built from, as stamped by the Go toolchain,
+// so that items are linked to commits. The revision, its time and whether the
+// build had uncommitted changes are also reported in the server section,
+// whatever the code version.
+func WithCodeVersion(codeVersion string) Option {
+	return func(config *configuration) {
+		config.codeVersion = codeVersion
+	}
+}
+
+// WithPlatform sets the platform reported for all items. The default is the
+// running operating system.
+func WithPlatform(platform string) Option {
+	return func(config *configuration) {
+		config.platform = platform
+	}
+}
+
+// WithNotifier sets the name and version of the notifier reported for all
+// items, NAME and VERSION by default, so that SDKs built on top of this
+// package can report themselves:
+//
+//	rollbar.New(token, rollbar.WithNotifier("acme-rollbar", "2.1.0"))
+func WithNotifier(name, version string) Option {
+	return func(config *configuration) {
+		config.notifierName = name
+		config.notifierVersion = version
+	}
+}
+
+// WithLanguage sets the language reported for all items, "go" by default,
+// e.g. for items reported on behalf of scripts run by a Go program.
+func WithLanguage(language string) Option {
+	return func(config *configuration) {
+		config.language = language
+	}
+}
+
+// WithRoutePattern sets a function that returns the pattern of the route that
+// matched a request, such as "/users/{id}", for routers other than
+// http.ServeMux, whose patterns are used by default. Items reported with a
+// request have its route as their context (see ItemContext) and in their
+// request data, rather than only its raw path. With chi or gorilla/mux, whose
+// middleware must then be installed on the router:
+//
+//	rollbar.WithRoutePattern(func(r *http.Request) string {
+//		return chi.RouteContext(r.Context()).RoutePattern()
+//	})
+//
+//	rollbar.WithRoutePattern(func(r *http.Request) string {
+//		template, _ := mux.CurrentRoute(r).GetPathTemplate()
+//		return template
+//	})
+//
+// The function is called when the item is reported, so it sees the route
+// even if the router matched it after the middleware was called. Frameworks
+// that don't keep the route in the request, such as gin, can report it with
+// an ItemContext Field instead.
+func WithRoutePattern(routePattern func(r *http.Request) string) Option {
+	return func(config *configuration) {
+		config.routePattern = routePattern
+	}
+}
+
+// WithRequestBody makes the items reported with a request include up to
+// maxBytes of its body, as read by the handler, which Middleware and
+// CaptureRequestBody keep as it is read. JSON objects and form bodies are
+// reported as params, and scrubbed like the rest of the item; other text
+// bodies are reported as the "body" of the request, which only a ScrubFunc
+// can scrub. Request bodies often hold personal data, so they are left out
+// by default.
+func WithRequestBody(maxBytes int) Option {
+	return func(config *configuration) {
+		config.requestBodyMax = maxBytes
+	}
+}
+
+// WithClientIP sets a function that finds the IP address of the client that
+// sent a request, such as ForwardedForIP or HeaderIP for servers behind
+// proxies, instead of RemoteAddrIP:
+//
+//	rollbar.New(token, rollbar.WithClientIP(rollbar.ForwardedForIP(1)))
+func WithClientIP(clientIP func(r *http.Request) string) Option {
+	return func(config *configuration) {
+		config.clientIP = clientIP
+	}
+}
+
+// WithIPAnonymization sets how much of the client IP of a request is reported
+// with its items: all of it (IPFull, the default), its network (IPMasked), a
+// hash of it (IPHashed) or none of it (IPOmitted).
+func WithIPAnonymization(anonymization IPAnonymization) Option {
+	return func(config *configuration) {
+		config.ipAnonymization = anonymization
+	}
+}
+
+// WithPersonExtractor sets a function that finds the person affected by an
+// item, such as from the claims of a JWT or a session store, unless the item
+// already has one (see ContextWithPerson). Items that have a request, such as
+// those reported by Middleware, pass it along with its context. Items reported
+// with only a context, such as by ErrorWithContext, pass that context and a
+// nil request, so extractors must check r before using it:
+//
+//	rollbar.WithPersonExtractor(func(ctx context.Context, r *http.Request) *rollbar.Person {
+//		if r != nil {
+//			ctx = r.Context()
+//		}
+//		if user, ok := auth.UserFromContext(ctx); ok {
+//			return &rollbar.Person{ID: user.ID, Email: user.Email}
+//		}
+//		return nil
+//	})
+//
+// Middleware reports the request it was given, before any authentication
+// middleware it wraps has added to its context, so extractors should rather
+// authenticate the request itself, e.g. from its Authorization header or
+// session cookie. Returning nil falls back to the person set with WithPerson.
+func WithPersonExtractor(extractor func(ctx context.Context, r *http.Request) *Person) Option {
+	return func(config *configuration) {
+		config.personExtractor = extractor
+	}
+}
+
+// WithHostname sets a custom hostname to use instead of os.Hostname().
+func WithHostname(hostname string) Option {
+	return func(config *configuration) {
+		config.hostname = hostname
+	}
+}
+
+// WithServerRoot sets the path to the application code root on the server,
+// reported as server.root.
+func WithServerRoot(root string) Option {
+	return func(config *configuration) {
+		config.serverRoot = root
+	}
+}
+
+// WithServerBranch sets the checked out source control branch, reported as
+// server.branch.
+func WithServerBranch(branch string) Option {
+	return func(config *configuration) {
+		config.serverBranch = branch
+	}
+}
+
+// WithEnabled turns reporting on or off. A disabled Client is a cheap no-op.
+// The default is enabled.
+func WithEnabled(enabled bool) Option {
+	return func(config *configuration) {
+		config.enabled = enabled
+	}
+}
+
+// WithCustom sets key / value pairs that are merged into the custom section
+// of every item.
+func WithCustom(custom map[string]interface{}) Option {
+	return func(config *configuration) {
+		config.custom = copyCustom(custom)
+	}
+}
+
+// WithPerson sets the person reported with every item.
+func WithPerson(person *Person) Option {
+	return func(config *configuration) {
+		config.person = person
+	}
+}
+
+// WithRoute sends items matched by match to the Rollbar project identified by
+// token instead of the Client's own project. See Route.
+func WithRoute(token string, match Matcher) Option {
+	return func(config *configuration) {
+		config.addRoute(token, match)
+	}
+}
+
+// WithCheckIgnore sets a function that decides whether an item is dropped
+// before it is queued. See Client.SetCheckIgnore.
+func WithCheckIgnore(checkIgnore func(item *Item) bool) Option {
+	return func(config *configuration) {
+		config.checkIgnore = checkIgnore
+	}
+}
+
+// WithMinLevel drops the items less severe than level, such as INFO and DEBUG
+// items for WARN, before they are queued, unless a WithPackageLevel rule
+// says otherwise. Every item is reported by default.
+func WithMinLevel(level string) Option {
+	return func(config *configuration) {
+		config.minLevel = level
+	}
+}
+
+// WithPackageLevel overrides the minimum level of WithMinLevel for the items
+// reported from the packages under the given import path prefix, such as
+// "github.com/acme/payments", so that noisy or critical subsystems can be
+// tuned on their own:
+//
+//	rollbar.New(token,
+//		rollbar.WithMinLevel(rollbar.WARN),
+//		rollbar.WithPackageLevel("github.com/acme/payments", rollbar.DEBUG),
+//		rollbar.WithPackageLevel("github.com/acme/payments/retry", rollbar.ERR),
+//	)
+//
+// The package of an item is that of the innermost frame of its stack trace
+// outside of the standard library, whose file is matched as in
+// MatchPackages; the longest matching prefix wins. Messages have no stack
+// trace, so only WithMinLevel applies to them.
+func WithPackageLevel(prefix, level string) Option {
+	return func(config *configuration) {
+		config.addPackageLevel(prefix, level)
+	}
+}
+
+// WithLevelAlias makes name, an application-specific severity such as
+// "fatal" or "notice", an alias of the Rollbar severity level, so that items
+// reported at that severity are reported at level:
+//
+//	rollbar.New(token,
+//		rollbar.WithLevelAlias("fatal", rollbar.CRIT),
+//		rollbar.WithLevelAlias("notice", rollbar.INFO),
+//	)
+func WithLevelAlias(name, level string) Option {
+	return func(config *configuration) {
+		config.addLevelAlias(name, level)
+	}
+}
+
+// WithLevelRule sets the severity level of the items matched by match, e.g.
+// those of a category of errors, whatever level they are reported at. It may
+// be given several times; the first rule that matches an item wins, before
+// the minimum levels of WithMinLevel and WithPackageLevel apply:
+//
+//	rollbar.WithLevelRule(rollbar.WARN, rollbar.MatchErrors(context.Canceled))
+func WithLevelRule(level string, match Matcher) Option {
+	return func(config *configuration) {
+		config.addLevelRule(level, match)
+	}
+}
+
+// WithIgnore drops the items matched by match before they are queued, so that
+// errors that are not actionable never count against the project's quota.
+// It may be given several times; an item matched by any of the Matchers is
+// dropped, before the CheckIgnore hook is called:
+//
+//	rollbar.WithIgnore(rollbar.MatchErrorClasses("net.OpError"))
+func WithIgnore(match Matcher) Option {
+	return func(config *configuration) {
+		config.addIgnore(match)
+	}
+}
+
+// WithIgnoredErrors drops errors that are, or wrap, any of the given errors,
+// such as io.EOF or context.Canceled. See MatchErrors.
+func WithIgnoredErrors(errs ...error) Option {
+	return WithIgnore(MatchErrors(errs...))
+}
+
+// WithIgnoredMessages drops errors and messages whose title matches any of the
+// given patterns. See MatchMessages.
+func WithIgnoredMessages(patterns ...*regexp.Regexp) Option {
+	return WithIgnore(MatchMessages(patterns...))
+}
+
+// WithFrameFilter sets the FrameFilter that decides which frames of the stack
+// traces of reported errors are reported. See Client.SetFrameFilter.
+func WithFrameFilter(filter FrameFilter) Option {
+	return func(config *configuration) {
+		config.frameFilter = filter
+	}
+}
+
+// WithClassifier sets a function that computes the exception class of
+// reported errors. See Client.SetClassifier.
+func WithClassifier(classifier func(err error) string) Option {
+	return func(config *configuration) {
+		config.classifier = classifier
+	}
+}
+
+// WithFingerprinter sets a function that computes the fingerprint by which
+// Rollbar groups every item. See Client.SetFingerprinter.
+func WithFingerprinter(fingerprinter func(item *Item) string) Option {
+	return func(config *configuration) {
+		config.fingerprinter = fingerprinter
+	}
+}
+
+// WithLineInsensitiveFingerprints makes the Client fingerprint errors by the
+// file and function of each frame of their stack trace, but not by line
+// numbers, so that adding a comment or refactoring code around an error
+// doesn't split its Rollbar item in two. The first items reported after
+// enabling it are grouped into new Rollbar items.
+func WithLineInsensitiveFingerprints() Option {
+	return func(config *configuration) {
+		config.fingerprintWithoutLines = true
+	}
+}
+
+// WithSHA256Fingerprints makes the Client fingerprint errors with SHA-256
+// rather than CRC32, whose fingerprints of unrelated stack traces collide
+// easily on large projects, grouping them into the same Rollbar item. They are
+// fingerprinted with CRC32 by default so that existing Rollbar items keep
+// their occurrences; the first items reported after enabling it are grouped
+// into new Rollbar items.
+func WithSHA256Fingerprints() Option {
+	return func(config *configuration) {
+		config.sha256Fingerprints = true
+	}
+}
+
+// WithTransform sets a function that is called with the data of every item
+// before it is scrubbed and queued. See Client.SetTransform.
+func WithTransform(transform func(data map[string]interface{})) Option {
+	return func(config *configuration) {
+		config.transform = transform
+	}
+}
+
+// WithBeforeSend sets a function that is called with the encoded payload of
+// every item right before it is delivered. See Client.SetBeforeSend.
+func WithBeforeSend(beforeSend func(item *Item, payload []byte) []byte) Option {
+	return func(config *configuration) {
+		config.beforeSend = beforeSend
+	}
+}
+
+// WithAfterSend sets a function that is called with the encoded payload of
+// every item and the result of its delivery. See Client.SetAfterSend.
+func WithAfterSend(afterSend func(item *Item, payload []byte, err error)) Option {
+	return func(config *configuration) {
+		config.afterSend = afterSend
+	}
+}
+
+// WithDeliveryErrorHandler sets a function that is called with every item
+// that is dropped without reaching Rollbar. See
+// Client.SetDeliveryErrorHandler.
+func WithDeliveryErrorHandler(handler func(item *Item, err error)) Option {
+	return func(config *configuration) {
+		config.onDropped = handler
+	}
+}
+
+// WithStatsHandler makes the Client call handler with its Stats every
+// interval, and one last time once it is closed, e.g. to export them to a
+// metrics system.
+func WithStatsHandler(interval time.Duration, handler func(Stats)) Option {
+	return func(config *configuration) {
+		config.statsInterval = interval
+		config.statsHandler = handler
+	}
+}
+
+// WithScrubFields sets the field names whose values are scrubbed from items.
+// A field is scrubbed if its name contains any of the given names, ignoring
+// case. The default is DefaultScrubFields.
+func WithScrubFields(fields ...string) Option {
+	pattern := scrubPattern(fields)
+	return func(config *configuration) {
+		config.filterFields = pattern
+	}
+}
+
+// WithScrubPatterns sets regular expressions that match additional field
+// names whose values are scrubbed from items.
+func WithScrubPatterns(patterns ...*regexp.Regexp) Option {
+	patterns = append([]*regexp.Regexp(nil), patterns...)
+	return func(config *configuration) {
+		config.scrubPatterns = patterns
+	}
+}
+
+// WithScrubFunc sets a custom redaction policy; see ScrubFunc.
+func WithScrubFunc(fn ScrubFunc) Option {
+	return func(config *configuration) {
+		config.scrubFunc = fn
+	}
+}
+
+// WithFilterFields sets the regular expression that matches field names that
+// should not be sent to Rollbar.
+func WithFilterFields(filterFields *regexp.Regexp) Option {
+	return func(config *configuration) {
+		config.filterFields = filterFields
+	}
+}
+
+// WithErrorWriter makes the Client write errors encountered while POSTing
+// items to Rollbar to w, one per line. This can be nil. It replaces any Logger
+// set with WithLogger.
+func WithErrorWriter(w io.Writer) Option {
+	return func(config *configuration) {
+		if w == nil {
+			config.logger = nopLogger{}
+			return
+		}
+		config.logger = writerLogger{w}
+	}
+}
+
+// WithLogger sets the Logger that receives the Client's own diagnostics. By
+// default, they are discarded. Passing nil restores the default.
+func WithLogger(logger Logger) Option {
+	return func(config *configuration) {
+		if logger == nil {
+			logger = nopLogger{}
+		}
+		config.logger = logger
+	}
+}
+
+// WithBuffer sets the maximum number of items that will be queued for
+// sending. When the buffer is full, new items are dropped on the floor until
+// the API can catch up. The default is DefaultBuffer.
+func WithBuffer(buffer int) Option {
+	return func(config *configuration) {
+		config.buffer = buffer
+	}
+}
+
+// WithSampling makes the Client send only a fraction of the occurrences of
+// items matched by the given rules, e.g. 10% of each known error after its
+// first 50 occurrences:
+//
+//	rollbar.WithSampling(rollbar.SamplingRule{
+//		Match: rollbar.MatchErrorClasses("net.OpError"),
+//		After: 50,
+//		Rate:  0.1,
+//	})
+//
+// The first matching rule applies. Items sent once sampling has started carry
+// a "sample_rate" custom field so that their numbers can be scaled back up;
+// the others are counted in Stats.Sampled.
+func WithSampling(rules ...SamplingRule) Option {
+	return func(config *configuration) {
+		config.sampling = rules
+	}
+}
+
+// WithMultiErrors sets how errors that combine several errors, such as those
+// returned by errors.Join, are reported: as one item whose trace chain has a
+// trace for each combined error (MultiErrorChain, the default), or as one item
+// per combined error (MultiErrorItems).
+func WithMultiErrors(mode MultiErrorMode) Option {
+	return func(config *configuration) {
+		config.multiErrors = mode
+	}
+}
+
+// WithMaxStackDepth sets the largest number of frames that the Client reports
+// in a stack trace. Deeper stack traces, such as those of runaway recursion,
+// are reported with their innermost and outermost frames around a frame that
+// says how many were elided; the source of elided frames is never read. The
+// default is DefaultMaxStackDepth; 0 means no limit.
+func WithMaxStackDepth(maxDepth int) Option {
+	return func(config *configuration) {
+		config.maxStackDepth = maxDepth
+	}
+}
+
+// WithContextLines makes the Client report up to n source lines before and
+// after the line of each frame of stack traces, which Rollbar shows around
+// it. Only the line itself is reported by default.
+func WithContextLines(n int) Option {
+	return func(config *configuration) {
+		config.contextLines = n
+	}
+}
+
+// WithCodeCapture sets whether the Client reports the source code of each
+// frame of the stack traces it captures, which it reads from the source files
+// it was built from. Turning it off saves a failed read per frame where the
+// source isn't deployed along with the binary, such as in containers built by
+// multi-stage Dockerfiles. It is on by default.
+func WithCodeCapture(capture bool) Option {
+	return func(config *configuration) {
+		config.codeCapture = capture
+	}
+}
+
+// WithGoroutineDump makes the Client attach the stacks of all goroutines, as
+// returned by runtime.Stack, to the panics it reports from Recover, LogPanic,
+// Go, GoContext and Middleware, in the "goroutine_dump" custom field. The dump
+// is cut off after maxBytes bytes. It helps tell what else was going on, such
+// as a goroutine stuck holding a lock, but stops the world while it is taken,
+// so it is off by default.
+func WithGoroutineDump(maxBytes int) Option {
+	return func(config *configuration) {
+		config.goroutineDump = maxBytes
+	}
+}
+
+// WithGoroutineInfo makes the Client report the ID of the goroutine each item
+// is reported from, in the "goroutine_id" custom field, and the pprof labels
+// of the context of items reported with one (see pprof.Do), such as
+// ErrorWithContext and Recover, in the "pprof_labels" custom field, so that
+// items can be correlated with the labeled request or tenant that produced
+// them.
+func WithGoroutineInfo() Option {
+	return func(config *configuration) {
+		config.goroutineInfo = true
+	}
+}
+
+// WithMaxTelemetryEvents sets how many of the most recent telemetry events
+// the Client keeps and reports with every item, DefaultMaxTelemetryEvents by
+// default; 0 turns telemetry off. It only applies when the Client is created.
+// See Telemetry.
+func WithMaxTelemetryEvents(max int) Option {
+	return func(config *configuration) {
+		config.maxTelemetry = max
+	}
+}
+
+// WithSourceRoot tells the Client that the source files built under prefix,
+// as recorded in the binary, are found under root at run time, e.g.
+//
+//	rollbar.WithSourceRoot("/app", "/builds/acme/api")
+//
+// for sources built in CI and deployed to /app, or
+//
+//	rollbar.WithSourceRoot("/app", "github.com/acme/api")
+//
+// for a binary built with -trimpath. The code of their frames is read from
+// root, and their file names are reported relative to prefix, so that they
+// don't depend on where the binary was built. It can be used several times
+// to relocate several trees.
+func WithSourceRoot(root, prefix string) Option {
+	return func(config *configuration) {
+		config.addSourceRoot(root, prefix)
+	}
+}
+
+// WithWrapperPackages registers the import paths of packages that wrap the
+// Client, such as an application's own error reporting helpers. Like frames
+// of this package, their frames at the top of a stack trace are left out, so
+// that the innermost frame reported is where the error happened, which also
+// keeps items reported through different helpers grouped together. A
+// package's sub-packages are registered along with it.
+func WithWrapperPackages(pkgs ...string) Option {
+	return func(config *configuration) {
+		config.wrapperPackages = pkgs
+	}
+}
+
+// WithDedupWindow makes the Client suppress items identical (same level,
+// class, message and stack trace) to one it sent less than window ago. The
+// next identical item sent carries an "occurrences_suppressed" custom field
+// with the number of items suppressed in between, and suppressed items are
+// counted in Stats.Suppressed. This cuts quota usage dramatically during
+// retry storms. Items are not deduplicated by default.
+func WithDedupWindow(window time.Duration) Option {
+	return func(config *configuration) {
+		config.dedupWindow = window
+	}
+}
+
+// WithItemLimit caps how many items the Client reports per window: limit
+// items overall, and perFingerprint identical items (same level, class,
+// message and stack trace). Items over a limit are dropped with
+// ErrRateLimited before being queued, so that a crash loop can't burn through
+// the project's quota. A limit of 0 is no limit; there are no limits by default.
+// A window of 0 is DefaultItemLimitWindow, one minute.
+func WithItemLimit(limit, perFingerprint int, window time.Duration) Option {
+	return func(config *configuration) {
+		config.limit = limit
+		config.limitPerFingerprint = perFingerprint
+		config.limitWindow = window
+	}
+}
+
+// QueuePolicy decides what happens to an item reported while a Client's queue
+// is full.
+type QueuePolicy int
+
+const (
+	// DropNewest drops the item being reported. It is the default.
+	DropNewest QueuePolicy = iota
+
+	// DropOldest drops the item that has been waiting in the queue the
+	// longest to make room for the item being reported.
+	DropOldest
+
+	// Block makes the reporting call wait for room in the queue, applying
+	// backpressure to the application, and drops the item if the wait times
+	// out.
+	Block
+)
+
+// WithQueuePolicy sets what happens to items reported while the queue is
+// full. timeout only applies to Block: it bounds how long a reporting call
+// waits for room in the queue, 0 meaning that it waits as long as it takes.
+// Items reported to synchronous Clients are never queued.
+func WithQueuePolicy(policy QueuePolicy, timeout time.Duration) Option {
+	return func(config *configuration) {
+		config.queuePolicy = policy
+		config.queueTimeout = timeout
+	}
+}
+
+// WithWorkers sets the number of goroutines that POST queued items to
+// Rollbar concurrently. The default is DefaultWorkers.
+func WithWorkers(workers int) Option {
+	return func(config *configuration) {
+		config.workers = workers
+	}
+}
+
+// WithSync makes every reporting method block until its item has been POSTed
+// to Rollbar and return the delivery error, instead of queueing the item for
+// the background workers. This suits CLIs, cron jobs and serverless functions
+// that must know their errors were delivered before exiting.
+func WithSync() Option {
+	return func(config *configuration) {
+		config.sync = true
+	}
+}
+
+// WithRetries sets the number of times a POST that failed with a network
+// error or a 5xx response is retried before the item is dropped. The default
+// is DefaultRetries.
+func WithRetries(retries int) Option {
+	return func(config *configuration) {
+		config.retries = retries
+	}
+}
+
+// WithRetryBackoff sets the range of the exponential backoff between retries:
+// the first retry waits up to base, and each following retry waits up to twice
+// as long as the previous one, capped at max. Waits are jittered. The
+// defaults are DefaultRetryBackoff and DefaultMaxRetryBackoff.
+func WithRetryBackoff(base, max time.Duration) Option {
+	return func(config *configuration) {
+		config.retryBackoff = base
+		config.maxRetryBackoff = max
+	}
+}
+
+// WithCircuitBreaker sets the number of consecutive delivery failures
+// (network errors, 5xx and 429 responses) after which the Client stops
+// POSTing items for cooldown, and then probes with a single item before
+// resuming. A threshold of 0 disables the circuit breaker. The defaults are
+// DefaultBreakerThreshold and DefaultBreakerCooldown.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(config *configuration) {
+		config.breakerThreshold = threshold
+		config.breakerCooldown = cooldown
+	}
+}
+
+// WithTimeout sets how long a single POST to Rollbar may take before it is
+// aborted and, if retries are enabled, retried. The default is
+// DefaultTimeout; 0 means no timeout other than the http.Client's own.
+func WithTimeout(timeout time.Duration) Option {
+	return func(config *configuration) {
+		config.timeout = timeout
+	}
+}
+
+// WithMaxPayloadSize sets the largest encoded payload, in bytes, that the
+// Client POSTs as is. Larger payloads are truncated, as described by Rollbar,
+// rather than being rejected by the API. The default is DefaultMaxPayloadSize;
+// 0 disables truncation.
+func WithMaxPayloadSize(maxSize int) Option {
+	return func(config *configuration) {
+		config.maxPayloadSize = maxSize
+	}
+}
+
+// WithGzip makes the Client gzip payloads of at least minSize bytes before
+// POSTing them, which cuts egress for items with large stack traces or
+// request bodies. Payloads are sent uncompressed by default.
+func WithGzip(minSize int) Option {
+	return func(config *configuration) {
+		if minSize < 0 {
+			minSize = 0
+		}
+		config.gzipMinSize = minSize
+	}
+}
+
+// WithSpool makes the Client save items that could not be delivered because
+// the network or Rollbar was unavailable to files in dir, and POST them again
+// once deliveries succeed. The spool is bounded to maxBytes (0 means
+// unbounded); when it fills up, the oldest items are dropped. Spooled files
+// contain access tokens and are only readable by their owner.
+func WithSpool(dir string, maxBytes int64) Option {
+	return func(config *configuration) {
+		config.spoolDir = dir
+		config.spoolMaxBytes = maxBytes
+	}
+}
+
+// WithBatching makes the sender workers gather up to size queued items,
+// waiting at most interval for more to arrive, and coalesce identical ones
+// (same level, class, message and stack trace) into a single item with an
+// "occurrences" custom field. During an error storm, thousands of identical
+// panics then cost a handful of requests. The UUIDs returned for the merged
+// occurrences are listed in the item's "occurrence_uuids" custom field: they
+// stand for the item, which is only known to Rollbar by the UUID of the first
+// occurrence. The delivery hooks (see WithAfterSend and
+// WithDeliveryErrorHandler) are called for every merged occurrence. Batching
+// is disabled by default and has no effect on synchronous Clients.
+func WithBatching(size int, interval time.Duration) Option {
+	return func(config *configuration) {
+		config.batchSize = size
+		config.batchInterval = interval
+	}
+}
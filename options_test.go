@@ -0,0 +1,189 @@
+package rollbar
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptions(t *testing.T) {
+	httpClient := &http.Client{}
+	c := New("token",
+		WithEnvironment("production"),
+		WithEndpoint("http://localhost/item/"),
+		WithHTTPClient(httpClient),
+		WithCodeVersion("abc123"),
+		WithBuffer(3),
+	)
+
+	if c.config.environment != "production" {
+		t.Errorf("got environment: %s", c.config.environment)
+	}
+	if c.config.endpoint != "http://localhost/item/" {
+		t.Errorf("got endpoint: %s", c.config.endpoint)
+	}
+	if c.config.httpClient != httpClient {
+		t.Error("should use the given http.Client")
+	}
+	if c.config.codeVersion != "abc123" {
+		t.Errorf("got code version: %s", c.config.codeVersion)
+	}
+	if cap(c.queue) != 3 {
+		t.Errorf("got buffer: %d", cap(c.queue))
+	}
+}
+
+func TestDefaultOptions(t *testing.T) {
+	c := New("token")
+
+	if c.config.environment != DefaultEnvironment {
+		t.Errorf("got environment: %s", c.config.environment)
+	}
+	if c.config.endpoint != DefaultEndpoint {
+		t.Errorf("got endpoint: %s", c.config.endpoint)
+	}
+	if c.config.httpClient != http.DefaultClient {
+		t.Error("should use http.DefaultClient")
+	}
+}
+
+func TestWithNotifier(t *testing.T) {
+	c := New("token", WithNotifier("acme-rollbar", "2.1.0"), WithLanguage("python"), WithPlatform("heroku"))
+	data := c.buildMessage(INFO, "hello")["data"].(map[string]interface{})
+
+	notifier := data["notifier"].(map[string]interface{})
+	if notifier["name"] != "acme-rollbar" || notifier["version"] != "2.1.0" {
+		t.Errorf("got notifier: %v", notifier)
+	}
+	if data["language"] != "python" || data["platform"] != "heroku" {
+		t.Errorf("got language %v, platform %v", data["language"], data["platform"])
+	}
+
+	data = New("token").buildMessage(INFO, "hello")["data"].(map[string]interface{})
+	if notifier := data["notifier"].(map[string]interface{}); notifier["name"] != NAME || notifier["version"] != VERSION || data["language"] != "go" {
+		t.Errorf("got notifier %v, language %v", notifier, data["language"])
+	}
+}
+
+func TestWithBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"https://rollbar.example.com/api/1", "https://rollbar.example.com/api/1/"} {
+		c := New("token", WithBaseURL(baseURL))
+		if c.config.baseURL != "https://rollbar.example.com/api/1/" {
+			t.Errorf("got base URL: %s", c.config.baseURL)
+		}
+		if c.config.endpoint != "https://rollbar.example.com/api/1/item/" {
+			t.Errorf("got endpoint: %s", c.config.endpoint)
+		}
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestWithRoundTripper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var used bool
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		used = true
+		return http.DefaultTransport.RoundTrip(r)
+	})
+
+	c := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil),
+		WithHTTPClient(&http.Client{Timeout: time.Second}), WithRoundTripper(transport))
+	if _, err := c.Message(INFO, "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !used {
+		t.Error("should POST through the given RoundTripper")
+	}
+	if c.config.httpClient.Timeout != time.Second {
+		t.Errorf("should keep the timeout, got %s", c.config.httpClient.Timeout)
+	}
+}
+
+func TestWithProxyURL(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	c := New("token", WithEndpoint("http://rollbar.invalid/api/1/item/"), WithSync(), WithErrorWriter(nil),
+		WithRetries(0), WithProxyURL(proxyURL))
+	if _, err := c.Message(INFO, "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	if proxied != "http://rollbar.invalid/api/1/item/" {
+		t.Errorf("got proxied request: %q", proxied)
+	}
+	if http.DefaultTransport.(*http.Transport).Proxy == nil {
+		t.Error("should not touch http.DefaultTransport")
+	}
+}
+
+func TestWithProxyURLKeepsHTTPClient(t *testing.T) {
+	var logged bytes.Buffer
+	checkRedirect := func(r *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }
+	transport := roundTripperFunc(http.DefaultTransport.RoundTrip)
+	proxyURL, _ := url.Parse("http://proxy.invalid")
+
+	c := New("token", WithErrorWriter(nil),
+		WithHTTPClient(&http.Client{Timeout: time.Second, CheckRedirect: checkRedirect}),
+		WithProxyURL(proxyURL))
+	if c.config.httpClient.CheckRedirect == nil || c.config.httpClient.Timeout != time.Second {
+		t.Error("should keep the settings of the http.Client")
+	}
+
+	c.configure(WithRoundTripper(transport), WithProxyURL(proxyURL), WithErrorWriter(&logged))
+	if _, ok := c.config.httpClient.Transport.(roundTripperFunc); !ok {
+		t.Errorf("should keep other RoundTrippers, got %T", c.config.httpClient.Transport)
+	}
+	if !strings.Contains(logged.String(), "WithProxyURL") {
+		t.Errorf("should log that the proxy was not set whatever the order of options, got %q", logged.String())
+	}
+}
+
+func TestWithTLSConfigLogsErrors(t *testing.T) {
+	var logged bytes.Buffer
+	transport := roundTripperFunc(http.DefaultTransport.RoundTrip)
+	c := New("token", WithRoundTripper(transport), WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}),
+		WithLogger(writerLogger{&logged}))
+
+	if _, ok := c.config.httpClient.Transport.(roundTripperFunc); !ok {
+		t.Errorf("should keep other RoundTrippers, got %T", c.config.httpClient.Transport)
+	}
+	if !strings.Contains(logged.String(), "WithTLSConfig") {
+		t.Errorf("should log that the TLS config was not set, got %q", logged.String())
+	}
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	c := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil), WithRetries(0))
+	if _, err := c.Message(INFO, "hello"); err == nil {
+		t.Fatal("should not trust the test server by default")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	c.configure(WithTLSConfig(&tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}))
+	if _, err := c.Message(INFO, "hello"); err != nil {
+		t.Errorf("should trust the given CA: %s", err)
+	}
+}
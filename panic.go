@@ -0,0 +1,156 @@
+package rollbar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+)
+
+const (
+	// panicValueField is the custom field that holds the value of a panic
+	// that isn't an error.
+	panicValueField = "panic_value"
+
+	// goroutineDumpField is the custom field that holds the stacks of all
+	// goroutines at the time of a panic; see WithGoroutineDump.
+	goroutineDumpField = "goroutine_dump"
+)
+
+// PanicError is the error reported for a recovered panic whose value isn't an
+// error, such as panic("unreachable"). It is reported with the class
+// "panic(T)", T being the type of the value, and the value itself is kept in
+// the "panic_value" custom field.
+type PanicError struct {
+	Value interface{}
+}
+
+// Error formats the panic value: strings as they are, fmt.Stringers with
+// their String method and other values with %+v.
+func (e *PanicError) Error() string {
+	switch value := e.Value.(type) {
+	case string:
+		return value
+	case fmt.Stringer:
+		return value.String()
+	}
+	return fmt.Sprintf("%+v", e.Value)
+}
+
+// class returns the exception class of the panic.
+func (e *PanicError) class() string {
+	return fmt.Sprintf("panic(%T)", e.Value)
+}
+
+// customValue returns the panic value as it is reported in custom data: the
+// value itself if it can be encoded to JSON, or its Go syntax representation.
+func (e *PanicError) customValue() interface{} {
+	if _, err := json.Marshal(e.Value); err != nil {
+		return fmt.Sprintf("%#v", e.Value)
+	}
+	return e.Value
+}
+
+// panicError converts a value recovered from a panic into an error.
+func panicError(value interface{}) error {
+	if err, ok := value.(error); ok {
+		return err
+	}
+	return &PanicError{Value: value}
+}
+
+// Recover recovers from a panic, if any, and reports it to Rollbar as a
+// critical error with the stack trace of where it was raised. Fields carried
+// by ctx are attached to the item, followed by any custom Fields passed on to
+// Rollbar; ctx may be nil. It must be deferred directly, as in:
+//
+//	defer client.Recover(ctx)
+//
+// The panic is not raised again; see LogPanic for that.
+func (c *Client) Recover(ctx context.Context, fields ...*Field) {
+	if value := recover(); value != nil {
+		c.reportPanic(ctx, value, fields)
+	}
+}
+
+// LogPanic is like Recover, but it raises the panic again once it has been
+// reported, after waiting for every queued item to be delivered since the
+// program is likely to crash. It must be deferred directly, as in:
+//
+//	defer client.LogPanic(ctx)
+func (c *Client) LogPanic(ctx context.Context, fields ...*Field) {
+	if value := recover(); value != nil {
+		c.reportPanic(ctx, value, fields)
+		c.Wait()
+		panic(value)
+	}
+}
+
+// Recover recovers from a panic, if any, and reports it using the
+// package-level configuration. It must be deferred directly. See
+// Client.Recover.
+func Recover(ctx context.Context, fields ...*Field) {
+	if value := recover(); value != nil {
+		std.reportPanic(ctx, value, fields)
+	}
+}
+
+// LogPanic recovers from a panic, if any, reports it using the package-level
+// configuration and raises it again. It must be deferred directly. See
+// Client.LogPanic.
+func LogPanic(ctx context.Context, fields ...*Field) {
+	if value := recover(); value != nil {
+		std.reportPanic(ctx, value, fields)
+		std.Wait()
+		panic(value)
+	}
+}
+
+// Go runs fn in a new goroutine, reporting any panic it raises as Recover
+// does instead of letting it crash the program.
+func (c *Client) Go(fn func()) {
+	go func() {
+		defer c.Recover(nil)
+		fn()
+	}()
+}
+
+// GoContext is like Go, but passes ctx to fn and attaches the Fields it
+// carries to the reported panic.
+func (c *Client) GoContext(ctx context.Context, fn func(ctx context.Context)) {
+	go func() {
+		defer c.Recover(ctx)
+		fn(ctx)
+	}()
+}
+
+// Go runs fn in a new goroutine, reporting any panic it raises using the
+// package-level configuration. See Client.Go.
+func Go(fn func()) {
+	std.Go(fn)
+}
+
+// GoContext runs fn in a new goroutine with ctx, reporting any panic it
+// raises using the package-level configuration. See Client.GoContext.
+func GoContext(ctx context.Context, fn func(ctx context.Context)) {
+	std.GoContext(ctx, fn)
+}
+
+// reportPanic reports a value recovered from a panic. It must be called from
+// the deferred function that recovered it, whose frames, along with those of
+// the runtime raising the panic, are left out of the top of the stack trace.
+func (c *Client) reportPanic(ctx context.Context, value interface{}, fields []*Field) {
+	c.errorWithContext(ctx, CRIT, nil, panicError(value), 1, c.panicFields(fields))
+}
+
+// panicFields returns the given Fields of a panic, along with the dump of
+// all goroutines if the Client is configured to attach one.
+func (c *Client) panicFields(fields []*Field) []*Field {
+	maxBytes := c.snapshot().goroutineDump
+	if maxBytes <= 0 {
+		return fields
+	}
+	buf := make([]byte, maxBytes)
+	n := runtime.Stack(buf, true)
+	return append(fields, Custom(map[string]interface{}{goroutineDumpField: string(buf[:n])}))
+}
@@ -0,0 +1,202 @@
+package rollbar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type orderState struct {
+	ID    int
+	State string
+}
+
+func reportedPanic(t *testing.T, value interface{}) map[string]interface{} {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+	handler := client.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(value)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(transport.payloads[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	return body["data"].(map[string]interface{})
+}
+
+func TestPanicValues(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		title string
+		class string
+		json  interface{}
+	}{
+		{"unreachable", "unreachable", "panic(string)", "unreachable"},
+		{42, "42", "panic(int)", 42.0},
+		{orderState{7, "paid"}, "{ID:7 State:paid}", "panic(rollbar.orderState)", map[string]interface{}{"ID": 7.0, "State": "paid"}},
+		{func() {}, "", "panic(func())", nil},
+	}
+	for i, test := range tests {
+		data := reportedPanic(t, test.value)
+		trace := data["body"].(map[string]interface{})["trace"].(map[string]interface{})
+		exception := trace["exception"].(map[string]interface{})
+		if test.title != "" && data["title"] != test.title {
+			t.Errorf("tests[%d]: got title %v", i, data["title"])
+		}
+		if exception["class"] != test.class {
+			t.Errorf("tests[%d]: got class %v", i, exception["class"])
+		}
+
+		value := data["custom"].(map[string]interface{})[panicValueField]
+		if test.json == nil {
+			if _, ok := value.(string); !ok {
+				t.Errorf("tests[%d]: should report values that can't be encoded as strings, got %v", i, value)
+			}
+		} else if b1, b2 := jsonString(value), jsonString(test.json); b1 != b2 {
+			t.Errorf("tests[%d]: got panic value %s", i, b1)
+		}
+
+		frame := trace["frames"].([]interface{})[0].(map[string]interface{})
+		if frame["method"] != "rollbar.reportedPanic.func1" {
+			t.Errorf("tests[%d]: should report the panicking function first, got %v", i, frame["method"])
+		}
+	}
+}
+
+func jsonString(value interface{}) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+func TestRecover(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+	ctx := ContextWithFields(context.Background(), &Field{Name: "job", Data: "reindex"})
+
+	func() {
+		defer client.Recover(ctx, Custom(map[string]interface{}{"attempt": 2}))
+		panic("index corrupted")
+	}()
+	func() {
+		defer client.Recover(ctx)
+	}()
+
+	if len(transport.payloads) != 1 {
+		t.Fatalf("got %d items", len(transport.payloads))
+	}
+	var body struct {
+		Data struct {
+			Level  string                 `json:"level"`
+			Job    string                 `json:"job"`
+			Custom map[string]interface{} `json:"custom"`
+			Body   struct {
+				Trace struct {
+					Frames []Frame `json:"frames"`
+				} `json:"trace"`
+			} `json:"body"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(transport.payloads[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data.Level != CRIT || body.Data.Job != "reindex" || body.Data.Custom["attempt"] != 2.0 {
+		t.Errorf("got %+v", body.Data)
+	}
+	if method := body.Data.Body.Trace.Frames[0].Method; method != "rollbar.TestRecover.func1" {
+		t.Errorf("should report the stack trace of the panic, got %s", method)
+	}
+}
+
+func TestLogPanic(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithErrorWriter(nil))
+
+	defer func() {
+		if value := recover(); value != "index corrupted" {
+			t.Errorf("should raise the panic again, got %v", value)
+		}
+		if len(transport.payloads) != 1 {
+			t.Errorf("should deliver the item before raising the panic again, got %d items", len(transport.payloads))
+		}
+	}()
+	defer client.LogPanic(nil)
+	panic("index corrupted")
+}
+
+func TestGo(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithErrorWriter(nil))
+
+	done := make(chan struct{})
+	client.Go(func() {
+		defer close(done)
+		panic("worker crashed")
+	})
+	<-done
+	ctx := ContextWithFields(context.Background(), &Field{Name: "job", Data: "reindex"})
+	ran := make(chan context.Context, 1)
+	client.GoContext(ctx, func(ctx context.Context) {
+		ran <- ctx
+		panic("job crashed")
+	})
+	if <-ran != ctx {
+		t.Error("should pass ctx on")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for client.Stats().Queued < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	client.Wait()
+	if len(transport.payloads) != 2 {
+		t.Fatalf("got %d items", len(transport.payloads))
+	}
+	for _, payload := range transport.payloads {
+		if strings.Contains(string(payload), "job crashed") && !strings.Contains(string(payload), `"job":"reindex"`) {
+			t.Errorf("should attach the fields of ctx, got %s", payload)
+		}
+	}
+}
+
+func TestGoroutineDump(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil), WithGoroutineDump(1<<16))
+
+	blocked := make(chan struct{})
+	defer close(blocked)
+	go func() { <-blocked }()
+
+	func() {
+		defer client.Recover(nil)
+		panic("deadlocked")
+	}()
+	client.configure(WithGoroutineDump(200))
+	func() {
+		defer client.Recover(nil)
+		panic("deadlocked")
+	}()
+
+	for i, maxBytes := range []int{1 << 16, 200} {
+		var body struct {
+			Data struct {
+				Custom map[string]interface{} `json:"custom"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(transport.payloads[i], &body); err != nil {
+			t.Fatal(err)
+		}
+		dump, _ := body.Data.Custom[goroutineDumpField].(string)
+		if !strings.HasPrefix(dump, "goroutine ") || len(dump) > maxBytes {
+			t.Errorf("got a dump of %d bytes: %q", len(dump), dump)
+		}
+		if maxBytes > 200 && !strings.Contains(dump, "[chan receive]") {
+			t.Errorf("should dump every goroutine, got %q", dump)
+		}
+	}
+}
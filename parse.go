@@ -0,0 +1,155 @@
+package rollbar
+
+import (
+	"errors"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// errNoStack is returned by ParseStack when the text holds no stack frames.
+var errNoStack = errors.New("rollbar: no stack frames found")
+
+// ParseStack parses the textual stack trace of a goroutine, as returned by
+// debug.Stack or printed by the runtime when a program panics, into a Stack,
+// so that a trace found in logs or the standard error of a subprocess can be
+// reported as a structured trace:
+//
+//	stack, err := rollbar.ParseStack(stderr)
+//	if err == nil {
+//		rollbar.ErrorWithStack(rollbar.CRIT, errors.New("worker crashed"), stack)
+//	}
+//
+// Only the first goroutine of the text is parsed; see ParseCrash for the
+// others. As with BuildStack, frames of this package and of the runtime at
+// the top of the trace, such as those of debug.Stack and panic, are left out,
+// and file paths are shortened. The source of frames is not read until the
+// Stack is reported, as configured by the Client reporting it.
+func ParseStack(text []byte) (Stack, error) {
+	goroutines := parseGoroutines(string(text))
+	if len(goroutines) == 0 {
+		return nil, errNoStack
+	}
+	return goroutines[0].Stack, nil
+}
+
+// goroutineHeader matches the line that starts the trace of a goroutine, such
+// as "goroutine 7 [chan receive, 2 minutes]:".
+var goroutineHeader = regexp.MustCompile(`^goroutine (\d+) (?:.* )?\[(.*)\]:$`)
+
+// parseGoroutines parses the traces of the goroutines printed in text. If
+// there are none, the frames of text, if any, are parsed as the trace of a
+// single goroutine with no ID, as found in logs that leave out the header.
+// Frames outside of goroutine traces, such as the "runtime stack:" of the
+// system goroutine of a fatal error, are ignored otherwise.
+func parseGoroutines(text string) []Goroutine {
+	var goroutines []Goroutine
+	var headless, frames []runtime.Frame
+	var current *Goroutine
+	var function string
+
+	end := func() {
+		if current != nil && len(frames) > 0 {
+			current.Stack = parsedStack(frames)
+			goroutines = append(goroutines, *current)
+		} else if current == nil && len(headless) == 0 {
+			headless = frames
+		}
+		current, frames, function = nil, nil, ""
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.TrimSpace(line) == "":
+			end()
+		case goroutineHeader.MatchString(line):
+			end()
+			match := goroutineHeader.FindStringSubmatch(line)
+			id, _ := strconv.Atoi(match[1])
+			current = &Goroutine{ID: id, State: match[2]}
+		case strings.HasPrefix(line, "\t"):
+			if function == "" {
+				continue
+			}
+			if file, lineNumber, ok := parseFileLine(line); ok {
+				frames = append(frames, runtime.Frame{Function: function, File: file, Line: lineNumber})
+			}
+			function = ""
+		default:
+			function = parseFunction(line)
+		}
+	}
+	end()
+
+	if len(goroutines) == 0 && len(headless) > 0 {
+		goroutines = append(goroutines, Goroutine{Stack: parsedStack(headless)})
+	}
+	return goroutines
+}
+
+// parseFunction returns the function name of a line of a textual stack
+// trace, such as "main.(*T).Run(0xc000010000, {0x4b1c2d, 0x3})" or
+// "created by main.main in goroutine 1", without its arguments.
+func parseFunction(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "...") {
+		// "...additional frames elided..."
+		return ""
+	}
+	line = strings.TrimPrefix(line, "created by ")
+	if i := strings.Index(line, " in goroutine "); i != -1 {
+		line = line[:i]
+	}
+	if strings.HasSuffix(line, ")") {
+		if i := strings.LastIndex(line, "("); i > 0 {
+			line = line[:i]
+		}
+	}
+	return line
+}
+
+// parseFileLine parses the file and line number of a line of a textual stack
+// trace, such as "\t/src/main.go:12 +0x1d".
+func parseFileLine(line string) (file string, lineNumber int, ok bool) {
+	line = strings.TrimSpace(line)
+	if i := strings.LastIndex(line, " +0x"); i != -1 {
+		line = line[:i]
+	}
+	colon := strings.LastIndex(line, ":")
+	if colon == -1 {
+		return "", 0, false
+	}
+	lineNumber, err := strconv.Atoi(line[colon+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return line[:colon], lineNumber, true
+}
+
+// parsedStack builds a Stack from parsed frames, leaving out those of this
+// package, of the runtime and of debug.Stack at the top. Their source, which
+// is usually that of another binary, is only read when they are reported.
+func parsedStack(frames []runtime.Frame) Stack {
+	for len(frames) > 1 && (isParsedRuntimeFrame(frames[0]) || isWrapperFrame(frames[0], nil)) {
+		frames = frames[1:]
+	}
+
+	stack := make(Stack, 0, len(frames))
+	for _, frame := range frames {
+		stack = append(stack, Frame{
+			Filename: shortenFilePath(frame.File),
+			Method:   shortFunctionName(frame.Function),
+			Line:     frame.Line,
+			source:   frame.File,
+		})
+	}
+	return stack
+}
+
+// isParsedRuntimeFrame reports whether a parsed frame is one of panic, which
+// is printed as such rather than as runtime.gopanic, or of debug.Stack.
+func isParsedRuntimeFrame(frame runtime.Frame) bool {
+	return frame.Function == "panic" || functionPackage(frame.Function) == "runtime/debug"
+}
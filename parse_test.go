@@ -0,0 +1,79 @@
+package rollbar
+
+import (
+	"errors"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestParseStackDebugStack(t *testing.T) {
+	stack, err := ParseStack(debug.Stack())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stack) < 2 {
+		t.Fatalf("got %d frames", len(stack))
+	}
+	frame := stack[0]
+	if frame.Method != "rollbar.TestParseStackDebugStack" || !strings.HasSuffix(frame.Filename, "parse_test.go") || frame.Line != 11 {
+		t.Errorf("got innermost frame %+v", frame)
+	}
+	if frame.Code != "" {
+		t.Errorf("should not read the source of parsed frames, got %q", frame.Code)
+	}
+
+	for _, codeCapture := range []bool{true, false} {
+		transport := &fakeTransport{}
+		client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil), WithCodeCapture(codeCapture))
+		client.ErrorWithStack(CRIT, errors.New("worker crashed"), stack)
+		frames := decodedTrace(t, transport.payloads[0])["frames"].([]interface{})
+		code, _ := frames[0].(map[string]interface{})["code"].(string)
+		if codeCapture != strings.Contains(code, "debug.Stack()") {
+			t.Errorf("should read the source of parsed frames when reporting them as configured, got %q", code)
+		}
+	}
+}
+
+func TestParseStackPanic(t *testing.T) {
+	text := `panic: boom
+
+goroutine 1 [running]:
+panic({0x4a1f20?, 0x4e8b10?})
+	/usr/local/go/src/runtime/panic.go:770 +0x132
+main.(*Worker).run(0xc000010000, {0x4b1c2d, 0x3})
+	/home/jane/app/worker.go:42 +0x25
+main.main.func1(...)
+	/home/jane/app/main.go:12
+created by main.main in goroutine 1
+	/home/jane/app/main.go:11 +0x1d
+
+goroutine 6 [chan receive]:
+main.other()
+	/home/jane/app/other.go:3 +0x1
+exit status 2
+`
+	stack, err := ParseStack([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Stack{
+		{Filename: "/home/jane/app/worker.go", Method: "main.(*Worker).run", Line: 42},
+		{Filename: "/home/jane/app/main.go", Method: "main.main.func1", Line: 12},
+		{Filename: "/home/jane/app/main.go", Method: "main.main", Line: 11},
+	}
+	if len(stack) != len(expected) {
+		t.Fatalf("got %+v", stack)
+	}
+	for i, frame := range stack {
+		if frame.Filename != expected[i].Filename || frame.Method != expected[i].Method || frame.Line != expected[i].Line {
+			t.Errorf("frame %d: got %+v, expected %+v", i, frame, expected[i])
+		}
+	}
+}
+
+func TestParseStackNoFrames(t *testing.T) {
+	if _, err := ParseStack([]byte("panic: boom\n")); err == nil {
+		t.Error("should fail without frames")
+	}
+}
@@ -0,0 +1,71 @@
+package rollbar
+
+import (
+	"context"
+	"net/http"
+)
+
+// Person identifies the user affected by an item, so that Rollbar can show
+// how many people an error affects. Only ID is required.
+type Person struct {
+	ID       string `json:"id"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+// SetPerson sets the person reported with every item.
+func (c *Client) SetPerson(id, username, email string) {
+	c.configure(WithPerson(&Person{ID: id, Username: username, Email: email}))
+}
+
+// ClearPerson stops reporting the person set with SetPerson, e.g. when a
+// worker moves on to a job for a different user.
+func (c *Client) ClearPerson() {
+	c.configure(WithPerson(nil))
+}
+
+// SetPerson sets the person reported with every item reported by the
+// package-level functions.
+func SetPerson(id, username, email string) {
+	std.SetPerson(id, username, email)
+}
+
+// ClearPerson stops reporting the person set with SetPerson.
+func ClearPerson() {
+	std.ClearPerson()
+}
+
+// ContextWithPerson returns a copy of ctx that carries the given person.
+// Items reported with the returned context are attributed to that person
+// instead of the one set with SetPerson.
+func ContextWithPerson(ctx context.Context, person *Person) context.Context {
+	return ContextWithFields(ctx, &Field{Name: personFieldName, Data: person})
+}
+
+// SetPersonExtractor sets a function that finds the person affected by an
+// item from the context it is reported with, or from its request, if any, so
+// that people are reported from whatever authentication the application uses
+// without doing so at every call site. The request is nil for items reported
+// with only a context. See WithPersonExtractor.
+func (c *Client) SetPersonExtractor(extractor func(ctx context.Context, r *http.Request) *Person) {
+	c.configure(WithPersonExtractor(extractor))
+}
+
+// SetPersonExtractor sets a function that finds the person affected by the
+// items reported by the package-level functions. See WithPersonExtractor.
+func SetPersonExtractor(extractor func(ctx context.Context, r *http.Request) *Person) {
+	std.SetPersonExtractor(extractor)
+}
+
+// extractPerson returns a Field with the person the configured extractor
+// finds for the given context and request, r being nil for items reported
+// without one, unless fields already have a person.
+func (config *configuration) extractPerson(ctx context.Context, r *http.Request, fields []*Field) *Field {
+	if config.personExtractor == nil || hasField(fields, personFieldName) {
+		return nil
+	}
+	if person := config.personExtractor(ctx, r); person != nil {
+		return &Field{Name: personFieldName, Data: person}
+	}
+	return nil
+}
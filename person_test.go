@@ -0,0 +1,111 @@
+package rollbar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPerson(t *testing.T) {
+	client := New("token")
+	client.SetPerson("42", "alice", "alice@example.com")
+
+	data := client.buildMessage(INFO, "hello")["data"].(map[string]interface{})
+	person, ok := data["person"].(*Person)
+	if !ok {
+		t.Fatal("should have a person")
+	}
+	if person.ID != "42" || person.Username != "alice" || person.Email != "alice@example.com" {
+		t.Errorf("got person: %+v", person)
+	}
+
+	client.ClearPerson()
+	data = client.buildMessage(INFO, "hello")["data"].(map[string]interface{})
+	if _, ok := data["person"]; ok {
+		t.Error("should have no person after ClearPerson")
+	}
+}
+
+func TestContextWithPerson(t *testing.T) {
+	client := New("token")
+	client.SetPerson("42", "alice", "")
+
+	ctx := ContextWithPerson(context.Background(), &Person{ID: "7"})
+	data := client.buildMessage(INFO, "hello", FieldsFromContext(ctx)...)["data"].(map[string]interface{})
+	if person := data["person"].(*Person); person.ID != "7" {
+		t.Errorf("context person should win, got: %+v", person)
+	}
+}
+
+func TestPersonExtractor(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+	client.SetPerson("default", "", "")
+	client.SetPersonExtractor(func(ctx context.Context, r *http.Request) *Person {
+		if r != nil {
+			if user := r.Header.Get("X-User"); user != "" {
+				return &Person{ID: user}
+			}
+			return nil
+		}
+		if user, ok := ctx.Value(userKey{}).(string); ok {
+			return &Person{ID: user}
+		}
+		return nil
+	})
+
+	handler := client.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(errors.New("handler exploded"))
+	}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-User", "from-request")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	ctx := context.WithValue(context.Background(), userKey{}, "from-context")
+	client.ErrorWithContext(ctx, ERR, errors.New("oops"))
+	client.ErrorWithContext(ContextWithPerson(ctx, &Person{ID: "explicit"}), ERR, errors.New("oops"))
+	client.Error(ERR, errors.New("oops"))
+
+	want := []string{"from-request", "from-context", "explicit", "default"}
+	if len(transport.payloads) != len(want) {
+		t.Fatalf("got %d items", len(transport.payloads))
+	}
+	for i, payload := range transport.payloads {
+		var body struct {
+			Data struct {
+				Person Person `json:"person"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(payload, &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Data.Person.ID != want[i] {
+			t.Errorf("items[%d]: got person %+v", i, body.Data.Person)
+		}
+	}
+}
+
+func TestPersonExtractorSkipsFilteredItems(t *testing.T) {
+	transport := &fakeTransport{}
+	calls := 0
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil), WithMinLevel(ERR),
+		WithPersonExtractor(func(ctx context.Context, r *http.Request) *Person {
+			calls++
+			return nil
+		}))
+
+	client.ErrorWithContext(context.Background(), WARN, errors.New("oops"))
+	client.MessageWithContext(context.Background(), INFO, "hello")
+	if calls != 0 || len(transport.payloads) != 0 {
+		t.Errorf("should not look for the person of filtered items, got %d calls", calls)
+	}
+	client.ErrorWithContext(context.Background(), ERR, errors.New("oops"))
+	if calls != 1 {
+		t.Errorf("got %d calls", calls)
+	}
+}
+
+type userKey struct{}
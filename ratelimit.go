@@ -0,0 +1,50 @@
+package rollbar
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// rateLimitPause returns how long delivery should pause after the given
+// response, based on Rollbar's X-Rate-Limit-Remaining and
+// X-Rate-Limit-Remaining-Seconds headers. It returns false if the project is
+// not rate limited.
+func rateLimitPause(resp *http.Response) (time.Duration, bool) {
+	limited := resp.StatusCode == http.StatusTooManyRequests ||
+		resp.Header.Get("X-Rate-Limit-Remaining") == "0"
+	if !limited {
+		return 0, false
+	}
+
+	seconds, err := strconv.Atoi(resp.Header.Get("X-Rate-Limit-Remaining-Seconds"))
+	if err != nil || seconds <= 0 {
+		return DefaultRateLimitPause, true
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// pauseDelivery stops the sender workers from POSTing anything for the given
+// duration. Items reported in the meantime wait in the queue.
+func (c *Client) pauseDelivery(pause time.Duration) {
+	until := time.Now().Add(pause)
+
+	c.rateLimitMutex.Lock()
+	defer c.rateLimitMutex.Unlock()
+
+	if until.After(c.rateLimitedUntil) {
+		c.rateLimitedUntil = until
+		c.debugf("rate limited, pausing delivery for %s", pause)
+	}
+}
+
+// waitForRateLimit blocks until the current rate limit window, if any, has
+// reset. It returns early with ctx.Err() if ctx is done first.
+func (c *Client) waitForRateLimit(ctx context.Context) error {
+	c.rateLimitMutex.Lock()
+	until := c.rateLimitedUntil
+	c.rateLimitMutex.Unlock()
+
+	return sleep(ctx, time.Until(until))
+}
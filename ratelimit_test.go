@@ -0,0 +1,59 @@
+package rollbar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimitPause(t *testing.T) {
+	tests := []struct {
+		Status  int
+		Headers map[string]string
+		Pause   time.Duration
+		Limited bool
+	}{
+		{200, map[string]string{"X-Rate-Limit-Remaining": "10"}, 0, false},
+		{200, map[string]string{"X-Rate-Limit-Remaining": "0", "X-Rate-Limit-Remaining-Seconds": "30"}, 30 * time.Second, true},
+		{429, map[string]string{"X-Rate-Limit-Remaining-Seconds": "5"}, 5 * time.Second, true},
+		{429, nil, DefaultRateLimitPause, true},
+	}
+
+	for i, test := range tests {
+		resp := &http.Response{StatusCode: test.Status, Header: http.Header{}}
+		for key, value := range test.Headers {
+			resp.Header.Set(key, value)
+		}
+		pause, limited := rateLimitPause(resp)
+		if pause != test.Pause || limited != test.Limited {
+			t.Errorf("tests[%d]: got %s, %v", i, pause, limited)
+		}
+	}
+}
+
+func TestRateLimitedDeliveryResumes(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("X-Rate-Limit-Remaining-Seconds", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil),
+		WithRetryBackoff(time.Millisecond, time.Millisecond))
+
+	start := time.Now()
+	if _, err := client.Message(INFO, "eventually"); err != nil {
+		t.Errorf("got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("should pause until the window resets, took %s", elapsed)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("got %d attempts", got)
+	}
+}
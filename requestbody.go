@@ -0,0 +1,114 @@
+package rollbar
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// bodyCapture is a request body that keeps the first max bytes read from it,
+// so that they can be reported with the errors of the request once the
+// handler has consumed the body.
+type bodyCapture struct {
+	io.ReadCloser
+	max int
+
+	mutex     sync.Mutex
+	body      []byte
+	truncated bool
+}
+
+// Read implements io.Reader.
+func (b *bodyCapture) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	read := p[:n]
+	if room := b.max - len(b.body); len(read) > room {
+		read = read[:room]
+		b.truncated = true
+	}
+	b.body = append(b.body, read...)
+	return n, err
+}
+
+// captured returns the bytes of the body read so far, up to max, and whether
+// there were more.
+func (b *bodyCapture) captured() ([]byte, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.body, b.truncated
+}
+
+// CaptureRequestBody returns a copy of r whose body keeps the first bytes read
+// from it, if the Client is configured to report request bodies (see
+// WithRequestBody), or r otherwise. Errors reported with the returned
+// request, or with requests derived from it, then include what the handler
+// read of the body. Middleware does so for every request; CaptureRequestBody
+// is for frameworks that wrap handlers their own way.
+func (c *Client) CaptureRequestBody(r *http.Request) *http.Request {
+	max := c.snapshot().requestBodyMax
+	if max <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return r
+	}
+	capture := &bodyCapture{ReadCloser: r.Body, max: max}
+	r = r.WithContext(context.WithValue(r.Context(), requestBodyContextKey, capture))
+	r.Body = capture
+	return r
+}
+
+// addRequestBody adds the captured body of r, if any, to the given request
+// data: JSON objects and form bodies as params, whose fields are scrubbed
+// like any other, and other text bodies as they are, up to the capture limit.
+// JSON and form bodies that were truncated can't be parsed and are left out.
+func addRequestBody(s *scrubber, request map[string]interface{}, r *http.Request) {
+	capture, _ := r.Context().Value(requestBodyContextKey).(*bodyCapture)
+	if capture == nil {
+		return
+	}
+	body, truncated := capture.captured()
+	if len(body) == 0 {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var params interface{}
+		if truncated || json.Unmarshal(body, &params) != nil {
+			return
+		}
+		if object, ok := params.(map[string]interface{}); ok {
+			request["POST"] = object
+		} else {
+			request["body"] = params
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		if truncated || len(r.PostForm) > 0 {
+			return
+		}
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			request["POST"] = flattenValues(filterParams(s, values))
+		}
+	case strings.HasPrefix(mediaType, "multipart/"):
+		// Uploads are left out; their fields are in r.PostForm once parsed.
+	default:
+		if truncated {
+			// Drop a rune cut in the middle.
+			for i := 0; i < utf8.UTFMax && len(body) > 0 && !utf8.Valid(body); i++ {
+				body = body[:len(body)-1]
+			}
+		}
+		if utf8.Valid(body) {
+			request["body"] = string(body)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package rollbar
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestBody(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		post        map[string]interface{}
+		raw         interface{}
+	}{
+		{"application/json", `{"card": "4242", "password": "hunter2"}`, map[string]interface{}{"card": "4242", "password": FILTERED}, nil},
+		{"application/json; charset=utf-8", `[1, 2]`, map[string]interface{}{}, []interface{}{float64(1), float64(2)}},
+		{"application/json", `{"note": "` + strings.Repeat("x", 64) + `"}`, map[string]interface{}{}, nil},
+		{"application/x-www-form-urlencoded", "amount=10&secret=s3cr3t", map[string]interface{}{"amount": "10", "secret": FILTERED}, nil},
+		{"text/plain", strings.Repeat("é", 40), map[string]interface{}{}, strings.Repeat("é", 23)},
+		{"application/octet-stream", "\xff\xfe", map[string]interface{}{}, nil},
+	}
+	for i, test := range tests {
+		transport := &fakeTransport{}
+		client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil), WithRequestBody(47))
+		handler := client.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ioutil.ReadAll(r.Body)
+			client.RequestError(ERR, r, errors.New("bad request"))
+		}))
+		r := httptest.NewRequest("POST", "/charges", strings.NewReader(test.body))
+		r.Header.Set("Content-Type", test.contentType)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		var body struct {
+			Data struct {
+				Request struct {
+					POST map[string]interface{} `json:"POST"`
+					Body interface{}            `json:"body"`
+				} `json:"request"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(transport.payloads[0], &body); err != nil {
+			t.Fatal(err)
+		}
+		request := body.Data.Request
+		if len(request.POST) != len(test.post) {
+			t.Errorf("tests[%d]: got POST %v", i, request.POST)
+		}
+		for key, value := range test.post {
+			if request.POST[key] != value {
+				t.Errorf("tests[%d]: got POST %v", i, request.POST)
+			}
+		}
+		if got, _ := json.Marshal(request.Body); string(got) != mustMarshal(test.raw) {
+			t.Errorf("tests[%d]: got body %s", i, got)
+		}
+	}
+}
+
+func mustMarshal(v interface{}) string {
+	encoded, _ := json.Marshal(v)
+	return string(encoded)
+}
+
+func TestRequestBodyDisabled(t *testing.T) {
+	client := New("token", WithEnabled(false))
+	r := httptest.NewRequest("POST", "/", strings.NewReader("{}"))
+	if client.CaptureRequestBody(r) != r {
+		t.Error("should leave requests alone by default")
+	}
+}
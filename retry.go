@@ -0,0 +1,42 @@
+package rollbar
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// retryable reports whether a failed POST is worth retrying: network errors,
+// 5xx responses and rate limiting usually are, other error responses (a bad
+// access token, a malformed item, etc.) will fail again.
+func retryable(err error) bool {
+	var status ErrHTTPError
+	if errors.As(err, &status) {
+		return status >= 500 || status == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// backoff returns how long to wait before the retry following the given
+// attempt (0 for the first attempt). The wait grows exponentially from base
+// up to max, if any, and is jittered over its whole range so that many clients
+// failing at once don't retry in lockstep.
+func backoff(base, max time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	// A max of 0 or less is no max, but the wait stops doubling before it
+	// overflows.
+	wait := base
+	for i := 0; i < attempt && (max <= 0 || wait < max) && wait <= math.MaxInt64/2; i++ {
+		wait *= 2
+	}
+	if max > 0 && wait > max {
+		wait = max
+	}
+
+	return time.Duration(rand.Int63n(int64(wait)) + 1)
+}
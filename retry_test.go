@@ -0,0 +1,109 @@
+package rollbar
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		Err      error
+		Expected bool
+	}{
+		{ErrHTTPError(500), true},
+		{ErrHTTPError(503), true},
+		{ErrHTTPError(429), true},
+		{ErrHTTPError(400), false},
+		{ErrHTTPError(403), false},
+		{errors.New("dial tcp: no such host"), true},
+	}
+	for i, test := range tests {
+		if got := retryable(test.Err); got != test.Expected {
+			t.Errorf("tests[%d]: got %v", i, got)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		max := 100 * time.Millisecond << uint(attempt)
+		if max > time.Second {
+			max = time.Second
+		}
+		for i := 0; i < 100; i++ {
+			wait := backoff(100*time.Millisecond, time.Second, attempt)
+			if wait <= 0 || wait > max {
+				t.Fatalf("attempt %d: got %s", attempt, wait)
+			}
+		}
+	}
+	if backoff(0, time.Second, 3) != 0 {
+		t.Error("should not wait without a base")
+	}
+	for i := 0; i < 100; i++ {
+		if wait := backoff(time.Second, 0, 100); wait <= 0 {
+			t.Fatalf("should not overflow, got %s", wait)
+		}
+	}
+	waited := false
+	for i := 0; i < 100; i++ {
+		if backoff(100*time.Millisecond, 0, 3) > 100*time.Millisecond {
+			waited = true
+		}
+	}
+	if !waited {
+		t.Error("should keep growing the wait without a max")
+	}
+}
+
+func TestRetryTransientFailures(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil),
+		WithRetries(3), WithRetryBackoff(time.Millisecond, 5*time.Millisecond))
+	if _, err := client.Message(INFO, "eventually"); err != nil {
+		t.Errorf("got: %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("got %d attempts", got)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil),
+		WithRetries(2), WithRetryBackoff(time.Millisecond, time.Millisecond))
+	if _, err := client.Message(INFO, "never"); err != ErrHTTPError(502) {
+		t.Errorf("got: %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("got %d attempts", got)
+	}
+
+	atomic.StoreInt32(&attempts, 0)
+	client = New("token", WithEndpoint(server.URL+"/missing"), WithSync(), WithErrorWriter(nil))
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	client.Message(INFO, "rejected")
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("should not retry a 401, got %d attempts", got)
+	}
+}
@@ -1,18 +1,17 @@
 package rollbar
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"hash/adler32"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
-	"os"
 	"reflect"
 	"regexp"
-	"runtime"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -22,7 +21,7 @@ const (
 
 	// VERSION is the version number of this notifier library as reported to the
 	// Rollbar API.
-	VERSION = "0.4.0"
+	VERSION = "0.5.0"
 
 	// CRIT is the critical Rollbar severity level as reported to the Rollbar
 	// API.
@@ -42,51 +41,92 @@ const (
 
 	// FILTERED is the text that replaces all sensitive values in items sent to
 	// the Rollbar API.
-	FILTERED = "[FILTERED]"
+	FILTERED = "***"
+
+	// DefaultEnvironment is the environment under which items are reported
+	// unless a Client is configured otherwise.
+	DefaultEnvironment = "development"
+
+	// DefaultBaseURL is the base URL of the Rollbar API unless a Client is
+	// configured otherwise.
+	DefaultBaseURL = "https://api.rollbar.com/api/1/"
+
+	// DefaultEndpoint is the URL destination for all Rollbar item POST requests
+	// unless a Client is configured otherwise.
+	DefaultEndpoint = DefaultBaseURL + "item/"
+
+	// DefaultBuffer is the maximum number of errors that will be queued for
+	// sending by a Client. When the buffer is full, new errors are dropped on
+	// the floor until the API can catch up.
+	DefaultBuffer = 1000
+
+	// DefaultWorkers is the number of goroutines that POST queued items to
+	// Rollbar concurrently unless a Client is configured otherwise.
+	DefaultWorkers = 4
+
+	// DefaultRetries is the number of times a transient delivery failure is
+	// retried unless a Client is configured otherwise.
+	DefaultRetries = 3
+
+	// DefaultRetryBackoff is the longest wait before the first retry unless a
+	// Client is configured otherwise.
+	DefaultRetryBackoff = 500 * time.Millisecond
+
+	// DefaultMaxRetryBackoff is the longest wait between two retries unless a
+	// Client is configured otherwise.
+	DefaultMaxRetryBackoff = 30 * time.Second
+
+	// DefaultRateLimitPause is how long delivery pauses after a 429 response
+	// that doesn't say when the rate limit window resets.
+	DefaultRateLimitPause = time.Minute
+
+	// DefaultBreakerThreshold is the number of consecutive delivery failures
+	// that open a Client's circuit breaker unless it is configured otherwise.
+	DefaultBreakerThreshold = 10
+
+	// DefaultBreakerCooldown is how long an open circuit breaker drops items
+	// before probing the API again unless a Client is configured otherwise.
+	DefaultBreakerCooldown = 30 * time.Second
+
+	// DefaultItemLimitWindow is the window of item limits configured without
+	// one; see WithItemLimit.
+	DefaultItemLimitWindow = time.Minute
+
+	// DefaultMaxPayloadSize is the largest encoded payload, in bytes, that a
+	// Client POSTs without truncating it first. It matches the Rollbar API's
+	// limit.
+	DefaultMaxPayloadSize = 512 * 1024
+
+	// DefaultMaxStackDepth is the largest number of frames that a Client
+	// reports in a stack trace unless it is configured otherwise.
+	DefaultMaxStackDepth = 64
+
+	// DefaultTimeout is how long a single POST to Rollbar may take unless a
+	// Client is configured otherwise.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultFilterFields is the default regular expression that matches field
+	// names that should not be sent to Rollbar. It is the compiled form of
+	// DefaultScrubFields.
+	DefaultFilterFields = "(?i)password|secret|token|authorization"
 )
 
 var (
-	// Token is the Rollbar access token under which all items will be reported.
-	// If Token is blank, no errors will be reported to Rollbar.
-	Token = ""
-
-	// Environment is the environment under which all items will be reported.
-	Environment = "development"
-
-	// Platform is the platform reported for all Rollbar items. The default is
-	// the running operating system (darwin, freebsd, linux, etc.) but it can
-	// also be application specific (client, heroku, etc.).
-	Platform = runtime.GOOS
-
-	// Endpoint is the URL destination for all Rollbar item POST requests.
-	Endpoint = "https://api.rollbar.com/api/1/item/"
-
-	// Buffer is the maximum number of errors that will be queued for sending.
-	// When the buffer is full, new errors are dropped on the floor until the API
-	// can catch up.
-	Buffer = 1000
-
-	// FilterFields is a regular expression that matches field names that should
-	// not be sent to Rollbar. Values for these fields are replaced with
-	// "[FILTERED]".
-	FilterFields = regexp.MustCompile("password|secret|token")
-
-	// ErrorWriter is the destination for errors encountered while POSTing items
-	// to Rollbar. By default, this is stderr. This can be nil.
-	ErrorWriter = os.Stderr
-
-	// CodeVersion is the optional code version reported to the Rollbar API for
-	// all items.
-	CodeVersion = ""
-
-	// Hostname is a custom hostname to use instead of os.Hostname() (on Heroku, it
-	// can be useful to use `os.Getenv("DYNO")` as the UUID hostname is rarely helpful).
-	Hostname = ""
-
-	bodyChannel chan map[string]interface{}
-	waitGroup   sync.WaitGroup
-	postErrors  chan error
+	// DefaultScrubFields are the field names whose values are scrubbed from
+	// items unless a Client is configured otherwise. A field is scrubbed if its
+	// name contains any of them, ignoring case.
+	DefaultScrubFields = []string{"password", "secret", "token", "authorization"}
+
+	// std is the Client used by the package-level functions.
+	std = New("")
+
 	nilErrTitle = "<nil>"
+
+	customFieldName      = "custom"
+	fingerprintFieldName = "fingerprint"
+	titleFieldName       = "title"
+	contextFieldName     = "context"
+	personFieldName      = "person"
 )
 
 // Field is a custom data field used to report arbitrary data to the Rollbar
@@ -96,111 +136,351 @@ type Field struct {
 	Data interface{}
 }
 
+// Custom returns a Field that adds the given key / value pairs to the item's
+// "custom" section. Unlike other Fields, several Custom Fields reported with
+// the same item are merged rather than replacing each other:
+//
+//	rollbar.Error(rollbar.ERR, err, rollbar.Custom(map[string]interface{}{
+//	  "order_id": id,
+//	}))
+func Custom(extras map[string]interface{}) *Field {
+	return &Field{Name: customFieldName, Data: extras}
+}
+
+// Fingerprint returns a Field that sets the fingerprint by which Rollbar
+// groups the item into an existing one, overriding both the default
+// fingerprint and the Client's Fingerprinter:
+//
+//	rollbar.Error(rollbar.ERR, err, rollbar.Fingerprint("payments-timeout"))
+func Fingerprint(fingerprint string) *Field {
+	return &Field{Name: fingerprintFieldName, Data: fingerprint}
+}
+
+// Title returns a Field that sets the title Rollbar shows for the item in its
+// lists, instead of the error or message it is derived from, so that items
+// can carry an operationally meaningful one:
+//
+//	rollbar.Error(rollbar.ERR, err, rollbar.Title("Payment capture failed for gateway=stripe"))
+//
+// Matchers and hooks still see the derived title as Item.Title.
+func Title(title string) *Field {
+	return &Field{Name: titleFieldName, Data: title}
+}
+
+// ItemContext returns a Field that sets the context of the item, the feature
+// area it comes from, such as "checkout#confirm" or "worker/emailer", by which
+// Rollbar can filter items:
+//
+//	ctx = rollbar.ContextWithFields(ctx, rollbar.ItemContext("worker/emailer"))
+//
+// Items reported with a request routed by an http.ServeMux have the pattern of
+// the route, such as "POST /checkout/{id}", as their context by default.
+func ItemContext(name string) *Field {
+	return &Field{Name: contextFieldName, Data: name}
+}
+
 // -- Setup
 
-func init() {
-	bodyChannel = make(chan map[string]interface{}, Buffer)
-	postErrors = make(chan error, Buffer)
-
-	go func() {
-		var err error
-		for body := range bodyChannel {
-			err = post(body)
-			if err != nil {
-				if len(postErrors) == cap(postErrors) {
-					<-postErrors
-				}
-				postErrors <- err
-			}
-			waitGroup.Done()
-		}
-		close(postErrors)
-	}()
+// SetToken sets the Rollbar access token under which all items reported by
+// the package-level functions will be reported. If the token is blank, no
+// errors will be reported to Rollbar.
+func SetToken(token string) {
+	std.SetToken(token)
+}
+
+// SetEnvironment sets the environment under which all items reported by the
+// package-level functions will be reported. The default is "development".
+func SetEnvironment(environment string) {
+	std.SetEnvironment(environment)
+}
+
+// SetPlatform sets the platform reported for all items reported by the
+// package-level functions.
+func SetPlatform(platform string) {
+	std.SetPlatform(platform)
+}
+
+// SetNotifier sets the name and version of the notifier reported for all
+// items reported by the package-level functions. See WithNotifier.
+func SetNotifier(name, version string) {
+	std.SetNotifier(name, version)
+}
+
+// SetEndpoint sets the URL destination for all item POST requests made by the
+// package-level functions.
+func SetEndpoint(endpoint string) {
+	std.SetEndpoint(endpoint)
+}
+
+// SetBaseURL sets the base URL of the Rollbar API for all requests made by the
+// package-level functions.
+func SetBaseURL(baseURL string) {
+	std.SetBaseURL(baseURL)
+}
+
+// SetScrubFields sets the field names whose values are scrubbed from items
+// reported by the package-level functions.
+func SetScrubFields(fields ...string) {
+	std.SetScrubFields(fields...)
+}
+
+// SetScrubPatterns sets regular expressions that match additional field names
+// whose values are scrubbed from items reported by the package-level
+// functions.
+func SetScrubPatterns(patterns ...*regexp.Regexp) {
+	std.SetScrubPatterns(patterns...)
+}
+
+// SetScrubFunc sets a custom redaction policy applied to items reported by
+// the package-level functions.
+func SetScrubFunc(fn ScrubFunc) {
+	std.SetScrubFunc(fn)
+}
+
+// SetFilterFields sets the regular expression that matches field names that
+// should not be sent to Rollbar by the package-level functions.
+func SetFilterFields(filterFields *regexp.Regexp) {
+	std.SetFilterFields(filterFields)
+}
+
+// SetErrorWriter sets the destination for errors encountered while POSTing
+// items reported by the package-level functions. This can be nil.
+func SetErrorWriter(w io.Writer) {
+	std.SetErrorWriter(w)
+}
+
+// SetLogger sets the Logger that receives the diagnostics of the
+// package-level functions. See Client.SetLogger.
+func SetLogger(logger Logger) {
+	std.SetLogger(logger)
+}
+
+// SetCodeVersion sets the code version reported for all items reported by the
+// package-level functions, the VCS revision of the build by default.
+func SetCodeVersion(codeVersion string) {
+	std.SetCodeVersion(codeVersion)
+}
+
+// SetHostname sets a custom hostname to use instead of os.Hostname() for all
+// items reported by the package-level functions.
+func SetHostname(hostname string) {
+	std.SetHostname(hostname)
+}
+
+// SetServerRoot sets the path to the application code root on the server for
+// all items reported by the package-level functions.
+func SetServerRoot(root string) {
+	std.SetServerRoot(root)
+}
+
+// SetServerBranch sets the checked out source control branch for all items
+// reported by the package-level functions.
+func SetServerBranch(branch string) {
+	std.SetServerBranch(branch)
+}
+
+// SetEnabled turns reporting by the package-level functions on or off. While
+// disabled, they are cheap no-ops.
+func SetEnabled(enabled bool) {
+	std.SetEnabled(enabled)
+}
+
+// SetCustom sets key / value pairs that are merged into the custom section of
+// every item reported by the package-level functions.
+func SetCustom(custom map[string]interface{}) {
+	std.SetCustom(custom)
+}
+
+// SetMinLevel sets the minimum severity level of the items reported by the
+// package-level functions. See WithMinLevel.
+func SetMinLevel(level string) {
+	std.SetMinLevel(level)
+}
+
+// SetPackageLevel sets the minimum severity level of the items reported by
+// the package-level functions from the packages under the given import path
+// prefix. See WithPackageLevel.
+func SetPackageLevel(prefix, level string) {
+	std.SetPackageLevel(prefix, level)
+}
+
+// SetLevelAlias makes name an alias of the Rollbar severity level for the
+// items reported by the package-level functions. See WithLevelAlias.
+func SetLevelAlias(name, level string) {
+	std.SetLevelAlias(name, level)
+}
+
+// AddLevelRule sets the severity level of the items reported by the
+// package-level functions that are matched by match. See WithLevelRule.
+func AddLevelRule(level string, match Matcher) {
+	std.AddLevelRule(level, match)
+}
+
+// SetCheckIgnore sets a function that decides whether an item reported by the
+// package-level functions is dropped before it is queued.
+func SetCheckIgnore(checkIgnore func(item *Item) bool) {
+	std.SetCheckIgnore(checkIgnore)
+}
+
+// SetBeforeSend sets a function that is called with the encoded payload of
+// every item reported by the package-level functions right before it is
+// delivered. See Client.SetBeforeSend.
+func SetBeforeSend(beforeSend func(item *Item, payload []byte) []byte) {
+	std.SetBeforeSend(beforeSend)
+}
+
+// SetAfterSend sets a function that is called with the encoded payload of
+// every item reported by the package-level functions and the result of its
+// delivery. See Client.SetAfterSend.
+func SetAfterSend(afterSend func(item *Item, payload []byte, err error)) {
+	std.SetAfterSend(afterSend)
+}
+
+// SetDeliveryErrorHandler sets a function that is called with every item
+// reported by the package-level functions that is dropped without reaching
+// Rollbar. See Client.SetDeliveryErrorHandler.
+func SetDeliveryErrorHandler(handler func(item *Item, err error)) {
+	std.SetDeliveryErrorHandler(handler)
+}
+
+// SetTransform sets a function that is called with the data of every item
+// reported by the package-level functions before it is queued.
+func SetTransform(transform func(data map[string]interface{})) {
+	std.SetTransform(transform)
+}
+
+// SetFrameFilter sets the FrameFilter that decides which frames of the stack
+// traces of errors reported by the package-level functions are reported. See
+// Client.SetFrameFilter.
+func SetFrameFilter(filter FrameFilter) {
+	std.SetFrameFilter(filter)
+}
+
+// SetClassifier sets a function that computes the exception class of errors
+// reported by the package-level functions. See Client.SetClassifier.
+func SetClassifier(classifier func(err error) string) {
+	std.SetClassifier(classifier)
+}
+
+// SetFingerprinter sets a function that computes the fingerprint of every item
+// reported by the package-level functions. See Client.SetFingerprinter.
+func SetFingerprinter(fingerprinter func(item *Item) string) {
+	std.SetFingerprinter(fingerprinter)
+}
+
+// SetHTTPClient sets the http.Client used to POST items reported by the
+// package-level functions.
+func SetHTTPClient(httpClient *http.Client) {
+	std.SetHTTPClient(httpClient)
 }
 
 // -- Error reporting
 
-func Errorf(level string, format string, args ...interface{}) {
-	ErrorWithStackSkip(level, fmt.Errorf(format, args), 1)
+// Errorf asynchronously sends an error built from the given format string and
+// arguments to Rollbar with the given severity level. See Client.Errorf.
+func Errorf(level string, format string, args ...interface{}) (string, error) {
+	return std.reportf(level, 1, format, args)
+}
+
+// Criticalf is like Errorf with the CRIT severity level.
+func Criticalf(format string, args ...interface{}) (string, error) {
+	return std.reportf(CRIT, 1, format, args)
+}
+
+// Warningf is like Errorf with the WARN severity level.
+func Warningf(format string, args ...interface{}) (string, error) {
+	return std.reportf(WARN, 1, format, args)
 }
 
 // Error asynchronously sends an error to Rollbar with the given severity
 // level. You can pass, optionally, custom Fields to be passed on to Rollbar.
-func Error(level string, err error, fields ...*Field) {
-	ErrorWithStackSkip(level, err, 1, fields...)
+func Error(level string, err error, fields ...*Field) (string, error) {
+	return std.ErrorWithStackSkip(level, err, 1, fields...)
 }
 
 // ErrorWithStackSkip asynchronously sends an error to Rollbar with the given
 // severity level and a given number of stack trace frames skipped. You can
 // pass, optionally, custom Fields to be passed on to Rollbar.
-func ErrorWithStackSkip(level string, err error, skip int, fields ...*Field) {
-	stack := BuildStack(2 + skip)
-	ErrorWithStack(level, err, stack, fields...)
+func ErrorWithStackSkip(level string, err error, skip int, fields ...*Field) (string, error) {
+	return std.ErrorWithStackSkip(level, err, skip+1, fields...)
 }
 
 // ErrorWithStack asynchronously sends and error to Rollbar with the given
 // stacktrace and (optionally) custom Fields to be passed on to Rollbar.
-func ErrorWithStack(level string, err error, stack Stack, fields ...*Field) {
-	buildAndPushError(level, err, stack, fields...)
+func ErrorWithStack(level string, err error, stack Stack, fields ...*Field) (string, error) {
+	return std.ErrorWithStack(level, err, stack, fields...)
 }
 
 // RequestError asynchronously sends an error to Rollbar with the given
 // severity level and request-specific information. You can pass, optionally,
 // custom Fields to be passed on to Rollbar.
-func RequestError(level string, r *http.Request, err error, fields ...*Field) {
-	RequestErrorWithStackSkip(level, r, err, 1, fields...)
+func RequestError(level string, r *http.Request, err error, fields ...*Field) (string, error) {
+	return std.RequestErrorWithStackSkip(level, r, err, 1, fields...)
 }
 
 // RequestErrorWithStackSkip asynchronously sends an error to Rollbar with the
 // given severity level and a given number of stack trace frames skipped, in
 // addition to extra request-specific information. You can pass, optionally,
 // custom Fields to be passed on to Rollbar.
-func RequestErrorWithStackSkip(level string, r *http.Request, err error, skip int, fields ...*Field) {
-	stack := BuildStack(2 + skip)
-	RequestErrorWithStack(level, r, err, stack, fields...)
+func RequestErrorWithStackSkip(level string, r *http.Request, err error, skip int, fields ...*Field) (string, error) {
+	return std.RequestErrorWithStackSkip(level, r, err, skip+1, fields...)
 }
 
 // RequestErrorWithStack asynchronously sends an error to Rollbar with the
 // given severity level, request-specific information provided by the given
 // http.Request, and a custom Stack. You You can pass, optionally, custom
 // Fields to be passed on to Rollbar.
-func RequestErrorWithStack(level string, r *http.Request, err error, stack Stack, fields ...*Field) {
-	buildAndPushError(level, err, stack, append(fields, &Field{Name: "request", Data: errorRequest(r)})...)
+func RequestErrorWithStack(level string, r *http.Request, err error, stack Stack, fields ...*Field) (string, error) {
+	return std.RequestErrorWithStack(level, r, err, stack, fields...)
 }
 
-func buildError(level string, err error, stack Stack, fields ...*Field) map[string]interface{} {
-	title := nilErrTitle
-	if err != nil {
-		title = err.Error()
-	}
-
-	body := buildBody(level, title)
-	data := body["data"].(map[string]interface{})
-	errBody, fingerprint := errorBody(err, stack)
-	data["body"] = errBody
-	data["fingerprint"] = fingerprint
+// ErrorWithContext asynchronously sends an error to Rollbar with the given
+// severity level. Fields carried by ctx are attached to the item, followed by
+// any custom Fields passed on to Rollbar.
+func ErrorWithContext(ctx context.Context, level string, err error, fields ...*Field) (string, error) {
+	return std.errorWithContext(ctx, level, nil, err, 1, fields)
+}
 
-	for _, field := range fields {
-		data[field.Name] = field.Data
-	}
+// RequestErrorWithContext asynchronously sends an error to Rollbar with the
+// given severity level and request-specific information. Fields carried by
+// ctx are attached to the item, followed by any custom Fields passed on to
+// Rollbar.
+func RequestErrorWithContext(ctx context.Context, level string, r *http.Request, err error, fields ...*Field) (string, error) {
+	return std.errorWithContext(ctx, level, r, err, 1, fields)
+}
 
-	return body
+// ErrorWithStackSkipContext is like ErrorWithContext, but skips the given
+// number of stack trace frames, like ErrorWithStackSkip.
+func ErrorWithStackSkipContext(ctx context.Context, level string, err error, skip int, fields ...*Field) (string, error) {
+	return std.errorWithContext(ctx, level, nil, err, 1+skip, fields)
 }
 
-func buildAndPushError(level string, err error, stack Stack, fields ...*Field) {
-	push(buildError(level, err, stack, fields...))
+// ErrorWithStackContext is like ErrorWithContext, but reports the error with
+// the given Stack, like ErrorWithStack.
+func ErrorWithStackContext(ctx context.Context, level string, err error, stack Stack, fields ...*Field) (string, error) {
+	return std.ErrorWithStackContext(ctx, level, err, stack, fields...)
 }
 
 // -- Message reporting
 
 // Message asynchronously sends a message to Rollbar with the given severity
-// level.
-func Message(level string, msg string) {
-	body := buildBody(level, msg)
-	data := body["data"].(map[string]interface{})
-	data["body"] = messageBody(msg)
+// level. You can pass, optionally, custom Fields to be passed on to Rollbar.
+func Message(level string, msg string, fields ...*Field) (string, error) {
+	return std.Message(level, msg, fields...)
+}
+
+// MessageWithData asynchronously sends a message to Rollbar with the given
+// severity level and structured key / value pairs, using the package-level
+// configuration. See Client.MessageWithData.
+func MessageWithData(level string, msg string, data map[string]interface{}, fields ...*Field) (string, error) {
+	return std.MessageWithData(level, msg, data, fields...)
+}
 
-	push(body)
+// MessageWithContext asynchronously sends a message to Rollbar with the given
+// severity level. Fields carried by ctx are attached to the item, followed by
+// any custom Fields passed on to Rollbar.
+func MessageWithContext(ctx context.Context, level string, msg string, fields ...*Field) (string, error) {
+	return std.MessageWithContext(ctx, level, msg, fields...)
 }
 
 // -- Misc.
@@ -208,80 +488,145 @@ func Message(level string, msg string) {
 // PostErrors returns a channel that receives all errors encountered while
 // POSTing items to the Rollbar API.
 func PostErrors() <-chan error {
-	return postErrors
+	return std.PostErrors()
 }
 
 // Wait will block until the queue of errors / messages is empty. This allows
 // you to ensure that errors / messages are sent to Rollbar before exiting an
-// application.
+// application. See Client.Wait.
 func Wait() {
-	waitGroup.Wait()
+	std.Wait()
+}
+
+// Flush blocks until every queued error / message has been sent to Rollbar or
+// until ctx is done, whichever happens first.
+func Flush(ctx context.Context) error {
+	return std.Flush(ctx)
+}
+
+// Close stops the package-level functions from reporting new errors /
+// messages and blocks until everything already queued has been sent to
+// Rollbar.
+func Close() error {
+	return std.Close()
 }
 
-func getHostname() string {
-	if Hostname != "" {
-		return Hostname
+// LogWriter returns an io.Writer that reports every message written to it
+// through the package-level functions. See Client.LogWriter.
+func LogWriter() io.Writer {
+	return std.LogWriter()
+}
+
+// DefaultClient returns the Client the package-level functions report
+// through, e.g. to read its Stats.
+func DefaultClient() *Client {
+	return std
+}
+
+// Shutdown is like Close, but gives up waiting when ctx is done. See
+// Client.Shutdown.
+func Shutdown(ctx context.Context) error {
+	return std.Shutdown(ctx)
+}
+
+// errorBody generates a Rollbar error body with a given stack trace. Wrapped
+// errors are reported as a trace chain, with one trace per cause, outermost
+// first, so that Rollbar can tell the causes apart. Causes that carry the stack
+// trace of where they were created (see errorStack) are reported with it, the
+// others with the given one. The item is fingerprinted by the stack trace of
+// its innermost cause. Stack traces are filtered and limited in depth as
+// configured.
+func errorBody(err error, stack Stack, config configuration) (map[string]interface{}, string) {
+	chain := errorChain(err)
+	stacks := make([]Stack, len(chain))
+	for i, cause := range chain {
+		stacks[i] = errorStack(cause, config.stackOptions())
 	}
 
-	hostname, _ := os.Hostname()
-	return hostname
-}
-
-// Build the main JSON structure that will be sent to Rollbar with the
-// appropriate metadata.
-func buildBody(level, title string) map[string]interface{} {
-	timestamp := time.Now().Unix()
-	hostname := getHostname()
-
-	data := map[string]interface{}{
-		"environment": Environment,
-		"title":       title,
-		"level":       level,
-		"timestamp":   timestamp,
-		"platform":    Platform,
-		"language":    "go",
-		"server": map[string]interface{}{
-			"host": hostname,
-		},
-		"notifier": map[string]interface{}{
-			"name":    NAME,
-			"version": VERSION,
-		},
+	var traces []interface{}
+	for i, cause := range chain {
+		if i+1 < len(chain) && errorTitle(cause) == errorTitle(chain[i+1]) {
+			// cause only annotates the next one, e.g. with a stack trace.
+			if len(stacks[i+1]) == 0 {
+				stacks[i+1] = stacks[i]
+			}
+			continue
+		}
+		if len(stacks[i]) == 0 {
+			stacks[i] = stack
+		}
+		stacks[i] = elideFrames(collapseRecursion(filterFrames(stacks[i], config.frameFilter)), config.maxStackDepth)
+		stacks[i] = config.stackOptions().withSource(stacks[i])
+		traces = append(traces, errorTrace(cause, config.errorClass(cause), stacks[i]))
 	}
-	if CodeVersion != "" {
-		data["code_version"] = CodeVersion
+	fingerprint := config.fingerprint(stacks[len(stacks)-1])
+
+	if len(traces) == 1 {
+		return map[string]interface{}{"trace": traces[0]}, fingerprint
 	}
+	return map[string]interface{}{"trace_chain": traces}, fingerprint
+}
 
-	return map[string]interface{}{
-		"access_token": Token,
-		"data":         data,
+// applyFields sets each custom Field on the given item data. Later Fields win
+// over earlier ones with the same name, except that the key / value pairs of
+// Custom Fields are merged into a single "custom" section.
+func applyFields(data map[string]interface{}, fields []*Field) {
+	for _, field := range fields {
+		if extras, ok := field.Data.(map[string]interface{}); ok && field.Name == customFieldName {
+			mergeCustom(data, extras)
+			continue
+		}
+		data[field.Name] = field.Data
 	}
 }
 
-// errorBody generates a Rollbar error body with a given stack trace.
-func errorBody(err error, stack Stack) (map[string]interface{}, string) {
-	message := nilErrTitle
-	if err != nil {
-		message = err.Error()
+// hasField reports whether a Field with the given name is among fields.
+func hasField(fields []*Field, name string) bool {
+	for _, field := range fields {
+		if field != nil && field.Name == name {
+			return true
+		}
 	}
+	return false
+}
 
-	fingerprint := stack.Fingerprint()
-	errBody := map[string]interface{}{
-		"trace": map[string]interface{}{
-			"frames": stack,
-			"exception": map[string]interface{}{
-				"class":   errorClass(err),
-				"message": message,
-			},
-		},
+// mergeCustom copies the given key / value pairs into the item data's
+// "custom" section, creating it if necessary.
+func mergeCustom(data map[string]interface{}, extras map[string]interface{}) {
+	custom, ok := data[customFieldName].(map[string]interface{})
+	if !ok {
+		custom = make(map[string]interface{}, len(extras))
+		data[customFieldName] = custom
 	}
-	return errBody, fingerprint
+	for key, value := range extras {
+		custom[key] = value
+	}
+}
+
+// apiURLs returns the normalized API base URL and the item endpoint beneath
+// it.
+func apiURLs(baseURL string) (string, string) {
+	baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+	return baseURL, baseURL + "item/"
+}
+
+// copyCustom returns a shallow copy of the given custom data so that later
+// changes by the caller don't leak into reported items.
+func copyCustom(custom map[string]interface{}) map[string]interface{} {
+	if custom == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(custom))
+	for key, value := range custom {
+		result[key] = value
+	}
+	return result
 }
 
 // errorRequest extracts details from a Request in a format that Rollbar
 // accepts.
-func errorRequest(r *http.Request) map[string]interface{} {
-	cleanQuery := filterParams(r.URL.Query())
+func errorRequest(s *scrubber, r *http.Request) map[string]interface{} {
+	cleanQuery := filterParams(s, r.URL.Query())
 
 	return map[string]interface{}{
 		"url":     r.URL.String(),
@@ -293,21 +638,25 @@ func errorRequest(r *http.Request) map[string]interface{} {
 		"GET":          flattenValues(cleanQuery),
 
 		// POST / PUT params
-		"POST":    flattenValues(filterParams(r.Form)),
-		"user_ip": r.RemoteAddr,
+		"POST":    flattenValues(filterParams(s, r.PostForm)),
+		"user_ip": remoteIP(r),
 	}
 }
 
-// filterParams filters sensitive information like passwords from being sent to
-// Rollbar.
-func filterParams(values map[string][]string) map[string][]string {
-	for key := range values {
-		if FilterFields.Match([]byte(key)) {
-			values[key] = []string{FILTERED}
-		}
+// remoteIP returns the IP address of the client that sent the request,
+// without the port.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
+	return host
+}
 
-	return values
+// filterParams filters sensitive information like passwords from being sent to
+// Rollbar. The given values are left untouched.
+func filterParams(s *scrubber, values map[string][]string) map[string][]string {
+	return s.values(values)
 }
 
 func flattenValues(values map[string][]string) map[string]interface{} {
@@ -333,12 +682,56 @@ func messageBody(s string) map[string]interface{} {
 	}
 }
 
+// addMessageData adds the key / value pairs of a message to the "message"
+// section of its body, next to the message itself, which they can't replace.
+func addMessageData(body map[string]interface{}, extras map[string]interface{}) {
+	if len(extras) == 0 {
+		return
+	}
+	data := body["data"].(map[string]interface{})
+	message := data["body"].(map[string]interface{})["message"].(map[string]interface{})
+	for key, value := range extras {
+		if key != "body" {
+			message[key] = value
+		}
+	}
+}
+
+// wrapperClasses are the types of errors that only wrap other errors, such as
+// those returned by fmt.Errorf with %w, which are reported with the class of
+// the first error they wrap.
+var wrapperClasses = map[string]bool{
+	"*fmt.wrapError":    true,
+	"*fmt.wrapErrors":   true,
+	"*errors.joinError": true,
+}
+
+// errorClass returns the exception class of the given error: its concrete
+// type, such as "net.OpError", except for wrappers (see wrapperClasses), and
+// errors created by errors.New, whose message can only tell apart.
 func errorClass(err error) string {
 	if err == nil {
 		return nilErrTitle
 	}
+	if wrapped, ok := err.(*stackError); ok {
+		return errorClass(wrapped.err)
+	}
+	if panicErr, ok := err.(*PanicError); ok {
+		return panicErr.class()
+	}
+	if crash, ok := err.(*Crash); ok && crash.Kind != "" {
+		return crash.Kind
+	}
 
 	class := reflect.TypeOf(err).String()
+	if wrapperClasses[class] {
+		if cause := errors.Unwrap(err); cause != nil {
+			return errorClass(cause)
+		}
+		if joined := joinedErrors(err); len(joined) > 0 && joined[0] != nil {
+			return errorClass(joined[0])
+		}
+	}
 	if class == "" {
 		return "panic"
 	} else if class == "*errors.errorString" {
@@ -348,54 +741,3 @@ func errorClass(err error) string {
 		return strings.TrimPrefix(class, "*")
 	}
 }
-
-// -- POST handling
-
-// Queue the given JSON body to be POSTed to Rollbar.
-func push(body map[string]interface{}) {
-	if len(bodyChannel) < Buffer {
-		waitGroup.Add(1)
-		bodyChannel <- body
-	} else {
-		stderr("buffer full, dropping error on the floor")
-	}
-}
-
-// POST the given JSON body to Rollbar synchronously.
-func post(body map[string]interface{}) error {
-	if len(Token) == 0 {
-		stderr("empty token")
-		return nil
-	}
-
-	jsonBody, err := json.Marshal(body)
-	if err != nil {
-		stderr("failed to encode payload: %s", err.Error())
-		return err
-	}
-
-	resp, err := http.Post(Endpoint, "application/json", bytes.NewReader(jsonBody))
-	if err != nil {
-		stderr("POST failed: %s", err.Error())
-		return err
-	}
-
-	if resp.StatusCode != 200 {
-		stderr("received response: %s", resp.Status)
-		return ErrHTTPError(resp.StatusCode)
-	}
-
-	if resp != nil {
-		resp.Body.Close()
-	}
-
-	return nil
-}
-
-// -- stderr
-func stderr(format string, args ...interface{}) {
-	if ErrorWriter != nil {
-		format = "Rollbar error: " + format + "\n"
-		fmt.Fprintf(ErrorWriter, format, args...)
-	}
-}
@@ -3,8 +3,10 @@ package rollbar
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -45,9 +47,50 @@ func TestErrorClass(t *testing.T) {
 	}
 }
 
+func TestErrorClassOfWrappers(t *testing.T) {
+	cause := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tests := []error{
+		fmt.Errorf("connecting: %w", cause),
+		fmt.Errorf("connecting: %w (%w)", cause, os.ErrDeadlineExceeded),
+		errors.Join(cause, os.ErrClosed),
+		Wrap(fmt.Errorf("connecting: %w", cause)),
+	}
+	for i, err := range tests {
+		if class := errorClass(err); class != "net.OpError" {
+			t.Errorf("tests[%d]: got %s", i, class)
+		}
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil),
+		WithClassifier(func(err error) string {
+			if errors.Is(err, os.ErrNotExist) {
+				return "NotFound"
+			}
+			return ""
+		}))
+
+	var classes []string
+	client.SetCheckIgnore(func(item *Item) bool {
+		classes = append(classes, item.Class())
+		return false
+	})
+	client.Error(ERR, fmt.Errorf("loading config: %w", os.ErrNotExist))
+	client.Error(ERR, &CustomError{"Terrible mistakes were made."})
+
+	if fmt.Sprint(classes) != "[NotFound rollbar.CustomError]" {
+		t.Errorf("got %v", classes)
+	}
+	if !strings.Contains(string(transport.payloads[0]), `"class":"NotFound"`) {
+		t.Errorf("should report the class, got %s", transport.payloads[0])
+	}
+}
+
 func TestEverything(t *testing.T) {
-	Token = os.Getenv("TOKEN")
-	Environment = "test"
+	SetToken(os.Getenv("TOKEN"))
+	SetEnvironment("test")
 
 	Error("critical", errors.New("Normal critical error"))
 	Error("error", &CustomError{"This is a custom error"})
@@ -74,7 +117,7 @@ func TestErrorRequest(t *testing.T) {
 	r, _ := http.NewRequest("GET", "http://foo.com/somethere?param1=true", nil)
 	r.RemoteAddr = "1.1.1.1:123"
 
-	object := errorRequest(r)
+	object := errorRequest(newScrubber(defaultConfiguration("")), r)
 
 	if object["url"] != "http://foo.com/somethere?param1=true" {
 		t.Errorf("wrong url, got %v", object["url"])
@@ -87,6 +130,28 @@ func TestErrorRequest(t *testing.T) {
 	if object["query_string"] != "param1=true" {
 		t.Errorf("wrong id, got %v", object["query_string"])
 	}
+
+	if object["user_ip"] != "1.1.1.1" {
+		t.Errorf("wrong user_ip, got %v", object["user_ip"])
+	}
+}
+
+func TestErrorRequestPostParams(t *testing.T) {
+	r, _ := http.NewRequest("POST", "http://foo.com/somethere?param1=true", strings.NewReader("amount=10&password=hunter2"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.ParseForm()
+
+	post := errorRequest(newScrubber(defaultConfiguration("")), r)["POST"].(map[string]interface{})
+
+	if post["amount"] != "10" {
+		t.Errorf("wrong amount, got %v", post["amount"])
+	}
+	if post["password"] != FILTERED {
+		t.Errorf("should filter password, got %v", post["password"])
+	}
+	if _, ok := post["param1"]; ok {
+		t.Error("should not include query params")
+	}
 }
 
 func TestFilterParams(t *testing.T) {
@@ -96,7 +161,7 @@ func TestFilterParams(t *testing.T) {
 		"access_token": []string{"one"},
 	}
 
-	clean := filterParams(values)
+	clean := filterParams(newScrubber(defaultConfiguration("")), values)
 	if clean["password"][0] != FILTERED {
 		t.Error("should filter password parameter")
 	}
@@ -127,12 +192,42 @@ func TestFlattenValues(t *testing.T) {
 }
 
 func TestBuildError(t *testing.T) {
-	buildError(ERR, nil, BuildStack(0))
+	std.buildError(ERR, nil, BuildStack(0))
 	// this should not panic
 }
 
+func TestErrorTraceChain(t *testing.T) {
+	cause := &os.PathError{Op: "open", Path: "/etc/app.conf", Err: os.ErrNotExist}
+	err := fmt.Errorf("loading config: %w", cause)
+
+	body, _ := errorBody(err, BuildStack(0), configuration{})
+	if _, ok := body["trace"]; ok {
+		t.Error("should not report a single trace for a wrapped error")
+	}
+	chain := body["trace_chain"].([]interface{})
+	if len(chain) != 3 {
+		t.Fatalf("got %d traces", len(chain))
+	}
+
+	messages := []string{err.Error(), cause.Error(), os.ErrNotExist.Error()}
+	for i, message := range messages {
+		exception := chain[i].(map[string]interface{})["exception"].(map[string]interface{})
+		if exception["message"] != message {
+			t.Errorf("trace %d: got message %v, want %s", i, exception["message"], message)
+		}
+	}
+	if class := chain[1].(map[string]interface{})["exception"].(map[string]interface{})["class"]; class != "fs.PathError" {
+		t.Errorf("got cause class %v", class)
+	}
+
+	body, _ = errorBody(errors.New("plain"), BuildStack(0), configuration{})
+	if _, ok := body["trace"]; !ok {
+		t.Error("should report a single trace for an unwrapped error")
+	}
+}
+
 func TestCustomField(t *testing.T) {
-	body := buildError(ERR, errors.New("test-custom"), BuildStack(0), &Field{
+	body := std.buildError(ERR, errors.New("test-custom"), BuildStack(0), &Field{
 		Name: "custom",
 		Data: map[string]string{
 			"NAME1": "VALUE1",
@@ -170,30 +265,66 @@ func TestCustomField(t *testing.T) {
 }
 
 func TestErrorRead(t *testing.T) {
-	Token = os.Getenv("TOKEN")
-	Environment = "test"
+	client := New("dummy",
+		WithEnvironment("test"),
+		WithEndpoint("https://does.not.exsist/foo/bar"),
+		WithErrorWriter(nil),
+		WithBuffer(2),
+		WithRetries(0),
+	)
+
+	client.Message(ERR, "first")
+	client.Message(ERR, "second")
+	client.Wait()
+
+	errCount := len(client.PostErrors())
+	for i := 0; i < errCount; i++ {
+		t.Log(<-client.PostErrors())
+	}
+	if errCount != 2 {
+		t.Fatal("didn't receive the right number of errors", errCount)
+	}
+}
 
-	bckBuffer, bckEP := Buffer, Endpoint
-	defer func() {
-		Buffer, Endpoint = bckBuffer, bckEP
-	}()
+func TestClientsAreIndependent(t *testing.T) {
+	a := New("token-a", WithEnvironment("production"))
+	b := New("token-b", WithEnvironment("staging"))
 
-	Buffer = 2
-	Endpoint = "https://does.not.exsist/foo/bar"
+	aData := a.buildBody(ERR, "a")["data"].(map[string]interface{})
+	bData := b.buildBody(ERR, "b")["data"].(map[string]interface{})
 
-	go func() {
-		errCount := 0
-		for err := range PostErrors() {
-			t.Log(err)
-			errCount++
-		}
-		if errCount != 2 {
-			t.Fatal("didn't receive the right number of errors", errCount)
-		}
-	}()
+	if aData["environment"] != "production" {
+		t.Errorf("got: %v", aData["environment"])
+	}
+	if bData["environment"] != "staging" {
+		t.Errorf("got: %v", bData["environment"])
+	}
+	if a.buildBody(ERR, "a")["access_token"] != "token-a" {
+		t.Error("should use its own token")
+	}
+}
 
-	post(nil)
-	post(nil)
+func TestCustomExtras(t *testing.T) {
+	body := std.buildError(ERR, errors.New("test-extras"), BuildStack(0),
+		Custom(map[string]interface{}{"order_id": 42}),
+		Custom(map[string]interface{}{"gateway": "stripe"}),
+	)
+	data := body["data"].(map[string]interface{})
 
-	Wait()
+	custom, ok := data["custom"].(map[string]interface{})
+	if !ok {
+		t.Fatal("should have a 'custom' section")
+	}
+	if custom["order_id"] != 42 {
+		t.Errorf("got order_id: %v", custom["order_id"])
+	}
+	if custom["gateway"] != "stripe" {
+		t.Errorf("got gateway: %v", custom["gateway"])
+	}
+
+	body = std.buildMessage(INFO, "test-extras", Custom(map[string]interface{}{"order_id": 42}))
+	data = body["data"].(map[string]interface{})
+	if data["custom"].(map[string]interface{})["order_id"] != 42 {
+		t.Error("messages should have a 'custom' section")
+	}
 }
@@ -0,0 +1,255 @@
+// Package rollbarapi is a client for the read APIs of Rollbar, for tooling
+// built on top of the items reported with package rollbar, such as
+// dashboards and triage bots:
+//
+//	api := rollbarapi.New(readToken)
+//	page, err := api.ListItems(ctx, rollbarapi.ItemFilter{Status: "active", Level: rollbar.CRIT})
+//	for _, item := range page.Items {
+//		fmt.Printf("#%d %s (%d occurrences)\n", item.Counter, item.Title, item.TotalOccurrences)
+//	}
+//
+// Read APIs need a project access token with the "read" scope, rather than
+// the "post_server_item" token items are reported with, and the APIs that
+// change items, such as ResolveItem, one with the "write" scope.
+package rollbarapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/stvp/rollbar"
+)
+
+// maxResponseSize bounds how much of an API response is read.
+const maxResponseSize = 16 << 20
+
+// Client makes requests to the read APIs of a single Rollbar project.
+type Client struct {
+	token      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL sets the base URL of the Rollbar API, rollbar.DefaultBaseURL by
+// default.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+	}
+}
+
+// WithHTTPClient sets the http.Client requests are made with,
+// http.DefaultClient by default.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New returns a Client for the project of the given read access token.
+func New(token string, opts ...Option) *Client {
+	c := &Client{token: token, baseURL: rollbar.DefaultBaseURL, httpClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Item is an item of a Rollbar project: the occurrences of an error or
+// message grouped together.
+type Item struct {
+	ID          int64  `json:"id"`
+	ProjectID   int64  `json:"project_id"`
+	Counter     int    `json:"counter"`
+	Environment string `json:"environment"`
+	Title       string `json:"title"`
+	Level       string `json:"level"`
+	Status      string `json:"status"`
+	Framework   string `json:"framework"`
+
+	TotalOccurrences int `json:"total_occurrences"`
+
+	// AssignedUserID is the ID of the user the item is assigned to, or 0.
+	AssignedUserID    int64  `json:"assigned_user_id"`
+	ResolvedInVersion string `json:"resolved_in_version"`
+
+	// The timestamps are in seconds since the Unix epoch.
+	FirstOccurrenceTimestamp int64 `json:"first_occurrence_timestamp"`
+	LastOccurrenceTimestamp  int64 `json:"last_occurrence_timestamp"`
+	LastOccurrenceID         int64 `json:"last_occurrence_id"`
+}
+
+// ItemFilter selects the items listed by ListItems. Zero fields match any
+// item.
+type ItemFilter struct {
+	// Status is "active", "resolved", "muted" or "archived".
+	Status string
+
+	// Level is a severity level, such as rollbar.ERR.
+	Level string
+
+	// Environment is the environment items were reported under.
+	Environment string
+
+	// Page is the page of results, starting at 1.
+	Page int
+}
+
+// ItemsPage is a page of the items listed by ListItems.
+type ItemsPage struct {
+	Items      []Item `json:"items"`
+	Page       int    `json:"page"`
+	TotalCount int    `json:"total_count"`
+}
+
+// Occurrence is a single occurrence of an item, as reported.
+type Occurrence struct {
+	ID     int64 `json:"id"`
+	ItemID int64 `json:"item_id"`
+
+	// Timestamp is when the occurrence was reported, in seconds since the
+	// Unix epoch.
+	Timestamp int64 `json:"timestamp"`
+
+	// Data is the data of the occurrence, as reported: "body", "level",
+	// "custom", "uuid", etc.
+	Data map[string]interface{} `json:"data"`
+}
+
+// UUID returns the UUID of the occurrence, as returned by the reporting
+// functions of package rollbar.
+func (o *Occurrence) UUID() string {
+	uuid, _ := o.Data["uuid"].(string)
+	return uuid
+}
+
+// OccurrencesPage is a page of the occurrences listed by ListOccurrences.
+type OccurrencesPage struct {
+	Occurrences []Occurrence `json:"instances"`
+	Page        int          `json:"page"`
+}
+
+// ListItems lists the items matched by filter, most recent first.
+func (c *Client) ListItems(ctx context.Context, filter ItemFilter) (*ItemsPage, error) {
+	query := url.Values{}
+	if filter.Status != "" {
+		query.Set("status", filter.Status)
+	}
+	if filter.Level != "" {
+		query.Set("level", filter.Level)
+	}
+	if filter.Environment != "" {
+		query.Set("environment", filter.Environment)
+	}
+	if filter.Page > 0 {
+		query.Set("page", strconv.Itoa(filter.Page))
+	}
+
+	var page ItemsPage
+	if err := c.get(ctx, "items/", query, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetItemByCounter returns the item with the given project counter, the
+// number shown in Rollbar URLs ("#42").
+func (c *Client) GetItemByCounter(ctx context.Context, counter int) (*Item, error) {
+	var item Item
+	if err := c.get(ctx, fmt.Sprintf("item_by_counter/%d", counter), nil, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ListOccurrences lists the occurrences of the item with the given ID, most
+// recent first. page starts at 1.
+func (c *Client) ListOccurrences(ctx context.Context, itemID int64, page int) (*OccurrencesPage, error) {
+	query := url.Values{}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+
+	var occurrences OccurrencesPage
+	if err := c.get(ctx, fmt.Sprintf("item/%d/instances/", itemID), query, &occurrences); err != nil {
+		return nil, err
+	}
+	return &occurrences, nil
+}
+
+// GetOccurrence returns the occurrence with the given UUID, such as one
+// returned by rollbar.Error.
+func (c *Client) GetOccurrence(ctx context.Context, uuid string) (*Occurrence, error) {
+	var occurrence Occurrence
+	if err := c.get(ctx, "occurrence/uuid", url.Values{"uuid": {uuid}}, &occurrence); err != nil {
+		return nil, err
+	}
+	return &occurrence, nil
+}
+
+// get makes a GET request to the API at the given path, relative to the base
+// URL, and decodes the result of the response into result.
+func (c *Client) get(ctx context.Context, path string, query url.Values, result interface{}) error {
+	return c.do(ctx, "GET", path, query, nil, result)
+}
+
+// do makes a request to the API at the given path, relative to the base URL,
+// with the given JSON body, if not nil, and decodes the result of the
+// response into result. Errors reported by the API are *rollbar.APIErrors, or
+// rollbar.ErrHTTPErrors if the response has no message.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, result interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reqBody io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(jsonBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Rollbar-Access-Token", c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Err     int             `json:"err"`
+		Message string          `json:"message"`
+		Result  json.RawMessage `json:"result"`
+	}
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&response)
+	if resp.StatusCode != http.StatusOK || decodeErr == nil && response.Err != 0 {
+		if decodeErr != nil || response.Message == "" {
+			return rollbar.ErrHTTPError(resp.StatusCode)
+		}
+		return &rollbar.APIError{StatusCode: resp.StatusCode, Message: response.Message}
+	}
+	if decodeErr != nil {
+		return decodeErr
+	}
+	return json.Unmarshal(response.Result, result)
+}
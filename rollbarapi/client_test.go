@@ -0,0 +1,88 @@
+package rollbarapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stvp/rollbar"
+)
+
+func newServer(t *testing.T, routes map[string]string) *Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.Header.Get("X-Rollbar-Access-Token"); token != "read-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"err": 1, "message": "invalid access token"}`))
+			return
+		}
+		body, ok := routes[r.URL.RequestURI()]
+		if !ok {
+			t.Errorf("unexpected request: %s", r.URL.RequestURI())
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return New("read-token", WithBaseURL(server.URL+"/api/1"))
+}
+
+func TestListItems(t *testing.T) {
+	api := newServer(t, map[string]string{
+		"/api/1/items/?level=critical&page=2&status=active": `{"err": 0, "result": {"page": 2, "total_count": 21, "items": [
+			{"id": 272505123, "counter": 42, "title": "card declined", "level": "critical", "status": "active", "total_occurrences": 7}
+		]}}`,
+	})
+
+	page, err := api.ListItems(context.Background(), ItemFilter{Status: "active", Level: rollbar.CRIT, Page: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Page != 2 || page.TotalCount != 21 || len(page.Items) != 1 {
+		t.Fatalf("got %+v", page)
+	}
+	if item := page.Items[0]; item.ID != 272505123 || item.Counter != 42 || item.Title != "card declined" || item.TotalOccurrences != 7 {
+		t.Errorf("got %+v", item)
+	}
+}
+
+func TestGetItemAndOccurrences(t *testing.T) {
+	api := newServer(t, map[string]string{
+		"/api/1/item_by_counter/42":        `{"err": 0, "result": {"id": 272505123, "counter": 42}}`,
+		"/api/1/item/272505123/instances/": `{"err": 0, "result": {"page": 1, "instances": [{"id": 1, "item_id": 272505123, "data": {"uuid": "abc"}}]}}`,
+		"/api/1/occurrence/uuid?uuid=abc":  `{"err": 0, "result": {"id": 1, "item_id": 272505123, "timestamp": 1700000000, "data": {"uuid": "abc", "level": "critical"}}}`,
+	})
+	ctx := context.Background()
+
+	item, err := api.GetItemByCounter(ctx, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	occurrences, err := api.ListOccurrences(ctx, item.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(occurrences.Occurrences) != 1 || occurrences.Occurrences[0].UUID() != "abc" {
+		t.Fatalf("got %+v", occurrences)
+	}
+	occurrence, err := api.GetOccurrence(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if occurrence.ItemID != item.ID || occurrence.Timestamp != 1700000000 || occurrence.Data["level"] != "critical" {
+		t.Errorf("got %+v", occurrence)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	api := newServer(t, nil)
+	api.token = "wrong"
+
+	_, err := api.ListItems(context.Background(), ItemFilter{})
+	var apiErr *rollbar.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "invalid access token" {
+		t.Errorf("got %v", err)
+	}
+}
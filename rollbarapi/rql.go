@@ -0,0 +1,157 @@
+package rollbarapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Statuses of RQL jobs. A job is done once it is in any status but RQLNew and
+// RQLRunning.
+const (
+	RQLNew       = "new"
+	RQLRunning   = "running"
+	RQLSuccess   = "success"
+	RQLFailed    = "failed"
+	RQLCancelled = "cancelled"
+	RQLTimedOut  = "timed_out"
+)
+
+// DefaultRQLPollInterval is how often RunRQL polls the status of its job by
+// default.
+const DefaultRQLPollInterval = time.Second
+
+// RQLJob is an RQL query being run by Rollbar.
+type RQLJob struct {
+	ID          int64  `json:"id"`
+	ProjectID   int64  `json:"project_id"`
+	QueryString string `json:"query_string"`
+	Status      string `json:"status"`
+
+	// The dates are in seconds since the Unix epoch.
+	DateCreated  int64 `json:"date_created"`
+	DateModified int64 `json:"date_modified"`
+}
+
+// Done reports whether the job is no longer new or running.
+func (j *RQLJob) Done() bool {
+	return j.Status != RQLNew && j.Status != RQLRunning
+}
+
+// RQLResult is the result of a successful RQL job: rows of values, one per
+// column.
+type RQLResult struct {
+	Columns  []string        `json:"columns"`
+	Rows     [][]interface{} `json:"rows"`
+	RowCount int             `json:"rowcount"`
+	Errors   []string        `json:"errors"`
+	Warnings []string        `json:"warnings"`
+
+	// ExecutionTime is how long the query took, in seconds.
+	ExecutionTime float64 `json:"executionTime"`
+}
+
+// Decode decodes the rows of the result into dst, a pointer to a slice of
+// structs whose fields are matched with the columns by their JSON tags:
+//
+//	var rows []struct {
+//		Counter int    `json:"item.counter"`
+//		Title   string `json:"item.title"`
+//		Count   int    `json:"count(*)"`
+//	}
+//	err := result.Decode(&rows)
+func (r *RQLResult) Decode(dst interface{}) error {
+	records := make([]map[string]interface{}, len(r.Rows))
+	for i, row := range r.Rows {
+		if len(row) != len(r.Columns) {
+			return fmt.Errorf("rollbarapi: row %d has %d values for %d columns", i, len(row), len(r.Columns))
+		}
+		records[i] = make(map[string]interface{}, len(row))
+		for j, value := range row {
+			records[i][r.Columns[j]] = value
+		}
+	}
+	jsonRecords, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonRecords, dst)
+}
+
+// CreateRQLJob starts running the given RQL query. Rollbar may return the
+// result of an identical recent query, unless forceRefresh is set.
+func (c *Client) CreateRQLJob(ctx context.Context, query string, forceRefresh bool) (*RQLJob, error) {
+	body := map[string]interface{}{"query_string": query, "force_refresh": forceRefresh}
+	var job RQLJob
+	if err := c.do(ctx, "POST", "rql/jobs/", nil, body, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetRQLJob returns the RQL job with the given ID, to poll its status.
+func (c *Client) GetRQLJob(ctx context.Context, id int64) (*RQLJob, error) {
+	var job RQLJob
+	if err := c.get(ctx, fmt.Sprintf("rql/job/%d", id), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetRQLResult returns the result of the successful RQL job with the given
+// ID.
+func (c *Client) GetRQLResult(ctx context.Context, id int64) (*RQLResult, error) {
+	var job struct {
+		RQLJob
+		Result *RQLResult `json:"result"`
+	}
+	if err := c.get(ctx, fmt.Sprintf("rql/job/%d/result", id), nil, &job); err != nil {
+		return nil, err
+	}
+	if job.Result == nil {
+		return nil, &RQLJobError{Job: job.RQLJob}
+	}
+	return job.Result, nil
+}
+
+// RQLJobError is returned for an RQL job that did not succeed.
+type RQLJobError struct {
+	Job RQLJob
+}
+
+// Error implements the error interface.
+func (e *RQLJobError) Error() string {
+	return fmt.Sprintf("rollbarapi: RQL job %d %s", e.Job.ID, e.Job.Status)
+}
+
+// RunRQL runs the given RQL query, polling the status of its job every
+// pollInterval, or DefaultRQLPollInterval if it is 0, until it is done or ctx
+// is done, and returns its result. Jobs that fail, time out or are cancelled
+// return an *RQLJobError.
+func (c *Client) RunRQL(ctx context.Context, query string, pollInterval time.Duration) (*RQLResult, error) {
+	if pollInterval <= 0 {
+		pollInterval = DefaultRQLPollInterval
+	}
+
+	job, err := c.CreateRQLJob(ctx, query, false)
+	if err != nil {
+		return nil, err
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for !job.Done() {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		if job, err = c.GetRQLJob(ctx, job.ID); err != nil {
+			return nil, err
+		}
+	}
+	if job.Status != RQLSuccess {
+		return nil, &RQLJobError{Job: *job}
+	}
+	return c.GetRQLResult(ctx, job.ID)
+}
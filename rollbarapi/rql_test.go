@@ -0,0 +1,81 @@
+package rollbarapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunRQL(t *testing.T) {
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/1/rql/jobs/":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["query_string"] != "select item.counter, count(*) from item_occurrence group by 1" {
+				t.Errorf("got body %v", body)
+			}
+			w.Write([]byte(`{"err": 0, "result": {"id": 7, "status": "new"}}`))
+		case "GET /api/1/rql/job/7":
+			if atomic.AddInt32(&polls, 1) < 2 {
+				w.Write([]byte(`{"err": 0, "result": {"id": 7, "status": "running"}}`))
+				return
+			}
+			w.Write([]byte(`{"err": 0, "result": {"id": 7, "status": "success"}}`))
+		case "GET /api/1/rql/job/7/result":
+			w.Write([]byte(`{"err": 0, "result": {"id": 7, "status": "success", "result": {
+				"columns": ["item.counter", "count(*)"], "rows": [[42, 7], [43, 1]], "rowcount": 2
+			}}}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.RequestURI())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	api := New("read-token", WithBaseURL(server.URL+"/api/1"))
+
+	result, err := api.RunRQL(context.Background(), "select item.counter, count(*) from item_occurrence group by 1", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polls != 2 || result.RowCount != 2 {
+		t.Fatalf("got %d polls, %+v", polls, result)
+	}
+
+	var rows []struct {
+		Counter int `json:"item.counter"`
+		Count   int `json:"count(*)"`
+	}
+	if err := result.Decode(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Counter != 42 || rows[0].Count != 7 || rows[1].Counter != 43 {
+		t.Errorf("got rows %+v", rows)
+	}
+}
+
+func TestRunRQLFailed(t *testing.T) {
+	api := newServer(t, map[string]string{
+		"/api/1/rql/jobs/": `{"err": 0, "result": {"id": 8, "status": "failed"}}`,
+	})
+
+	_, err := api.RunRQL(context.Background(), "select nonsense", 0)
+	var jobErr *RQLJobError
+	if !errors.As(err, &jobErr) || jobErr.Job.ID != 8 || jobErr.Job.Status != RQLFailed {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestDecodeRaggedRow(t *testing.T) {
+	result := &RQLResult{Columns: []string{"a", "b"}, Rows: [][]interface{}{{1}}}
+	var rows []map[string]interface{}
+	if err := result.Decode(&rows); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,95 @@
+package rollbarapi
+
+import (
+	"context"
+	"fmt"
+)
+
+// Statuses of items.
+const (
+	StatusActive   = "active"
+	StatusResolved = "resolved"
+	StatusMuted    = "muted"
+	StatusArchived = "archived"
+)
+
+// ItemUpdate is a change to an item made by UpdateItem. Zero fields are left
+// as they are.
+type ItemUpdate struct {
+	// Status is one of StatusActive, StatusResolved, StatusMuted and
+	// StatusArchived.
+	Status string
+
+	// ResolvedInVersion is the code version an item being resolved is fixed
+	// in, e.g. the revision of a release. Rollbar reactivates the item if it
+	// occurs again in that version or a later one.
+	ResolvedInVersion string
+
+	// Level is a severity level, such as rollbar.WARN.
+	Level string
+
+	// Title replaces the title of the item.
+	Title string
+
+	// AssignedUserID is the ID of the user to assign the item to. Unassign
+	// removes the current owner instead.
+	AssignedUserID int64
+	Unassign       bool
+}
+
+// UpdateItem changes the item with the given ID and returns it as updated.
+func (c *Client) UpdateItem(ctx context.Context, itemID int64, update ItemUpdate) (*Item, error) {
+	body := map[string]interface{}{}
+	if update.Status != "" {
+		body["status"] = update.Status
+	}
+	if update.ResolvedInVersion != "" {
+		body["resolved_in_version"] = update.ResolvedInVersion
+	}
+	if update.Level != "" {
+		body["level"] = update.Level
+	}
+	if update.Title != "" {
+		body["title"] = update.Title
+	}
+	if update.Unassign {
+		body["assigned_user_id"] = nil
+	} else if update.AssignedUserID != 0 {
+		body["assigned_user_id"] = update.AssignedUserID
+	}
+
+	var item Item
+	if err := c.do(ctx, "PATCH", fmt.Sprintf("item/%d", itemID), nil, body, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ResolveItem resolves the item with the given ID, as fixed in the given code
+// version, if not empty, such as the revision passed to rollbar.ReportDeploy.
+func (c *Client) ResolveItem(ctx context.Context, itemID int64, version string) (*Item, error) {
+	return c.UpdateItem(ctx, itemID, ItemUpdate{Status: StatusResolved, ResolvedInVersion: version})
+}
+
+// ReactivateItem makes the resolved or muted item with the given ID active
+// again.
+func (c *Client) ReactivateItem(ctx context.Context, itemID int64) (*Item, error) {
+	return c.UpdateItem(ctx, itemID, ItemUpdate{Status: StatusActive})
+}
+
+// MuteItem mutes the item with the given ID, so that its occurrences no longer
+// notify anyone.
+func (c *Client) MuteItem(ctx context.Context, itemID int64) (*Item, error) {
+	return c.UpdateItem(ctx, itemID, ItemUpdate{Status: StatusMuted})
+}
+
+// AssignItem assigns the item with the given ID to the user with the given
+// ID, or unassigns it if userID is 0.
+func (c *Client) AssignItem(ctx context.Context, itemID, userID int64) (*Item, error) {
+	return c.UpdateItem(ctx, itemID, ItemUpdate{AssignedUserID: userID, Unassign: userID == 0})
+}
+
+// SetItemLevel sets the severity level of the item with the given ID.
+func (c *Client) SetItemLevel(ctx context.Context, itemID int64, level string) (*Item, error) {
+	return c.UpdateItem(ctx, itemID, ItemUpdate{Level: level})
+}
@@ -0,0 +1,55 @@
+package rollbarapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/stvp/rollbar"
+)
+
+func TestUpdateItem(t *testing.T) {
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PATCH" || r.URL.Path != "/api/1/item/272505123" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.RequestURI())
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		bodies = append(bodies, body)
+		w.Write([]byte(`{"err": 0, "result": {"id": 272505123, "status": "resolved", "resolved_in_version": "abc123"}}`))
+	}))
+	defer server.Close()
+	api := New("write-token", WithBaseURL(server.URL+"/api/1"))
+	ctx := context.Background()
+
+	item, err := api.ResolveItem(ctx, 272505123, "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != StatusResolved || item.ResolvedInVersion != "abc123" {
+		t.Errorf("got %+v", item)
+	}
+	api.MuteItem(ctx, 272505123)
+	api.ReactivateItem(ctx, 272505123)
+	api.AssignItem(ctx, 272505123, 9)
+	api.AssignItem(ctx, 272505123, 0)
+	api.SetItemLevel(ctx, 272505123, rollbar.WARN)
+
+	expected := []map[string]interface{}{
+		{"status": "resolved", "resolved_in_version": "abc123"},
+		{"status": "muted"},
+		{"status": "active"},
+		{"assigned_user_id": float64(9)},
+		{"assigned_user_id": nil},
+		{"level": "warning"},
+	}
+	if !reflect.DeepEqual(bodies, expected) {
+		t.Errorf("got bodies %v", bodies)
+	}
+}
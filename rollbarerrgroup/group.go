@@ -0,0 +1,130 @@
+// Package rollbarerrgroup wraps golang.org/x/sync/errgroup so that the
+// failure of a group of goroutines is reported to Rollbar:
+//
+//	g, ctx := rollbarerrgroup.WithContext(ctx, client, rollbar.Custom(map[string]interface{}{
+//		"job": "sync",
+//	}))
+//	for _, url := range urls {
+//		url := url
+//		g.Go(func() error { return fetch(ctx, url) })
+//	}
+//	err := g.Wait()
+//
+// The first error returned by a member is reported, once, when Wait returns,
+// with its own stack trace if it carries one (see rollbar.Wrap) or otherwise
+// the stack of the Wait call site. A panic in a member is reported as a
+// critical error with the stack trace of where it was raised, and is turned
+// into an error returned by Wait rather than crashing the program. Every item
+// carries the Group's Fields, and those of the context it was created with.
+package rollbarerrgroup
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/stvp/rollbar"
+)
+
+// Group is an errgroup.Group whose members' errors and panics are reported
+// to Rollbar.
+type Group struct {
+	group  *errgroup.Group
+	client *rollbar.Client
+	ctx    context.Context
+	fields []*rollbar.Field
+
+	mutex    sync.Mutex
+	err      error
+	reported bool
+}
+
+// New returns a Group that reports to client, or to the default client if
+// client is nil, with the given Fields.
+func New(client *rollbar.Client, fields ...*rollbar.Field) *Group {
+	if client == nil {
+		client = rollbar.DefaultClient()
+	}
+	return &Group{group: new(errgroup.Group), client: client, fields: fields}
+}
+
+// WithContext is like New, but the Group reports items with ctx, so that they
+// also carry its Fields, and the returned context is canceled when a member
+// fails or Wait returns, as with errgroup.WithContext.
+func WithContext(ctx context.Context, client *rollbar.Client, fields ...*rollbar.Field) (*Group, context.Context) {
+	g := New(client, fields...)
+	g.ctx = ctx
+	g.group, ctx = errgroup.WithContext(ctx)
+	return g, ctx
+}
+
+// Go calls fn in a new goroutine. See errgroup.Group.Go.
+func (g *Group) Go(fn func() error) {
+	g.group.Go(g.member(fn))
+}
+
+// TryGo calls fn in a new goroutine only if the number of active members is
+// below the limit. See errgroup.Group.TryGo.
+func (g *Group) TryGo(fn func() error) bool {
+	return g.group.TryGo(g.member(fn))
+}
+
+// SetLimit limits the number of active members. See errgroup.Group.SetLimit.
+func (g *Group) SetLimit(n int) {
+	g.group.SetLimit(n)
+}
+
+// Wait blocks until every member has returned, reports the first error
+// returned by one of them unless it was a panic, which has been reported
+// already, and returns it.
+func (g *Group) Wait() error {
+	g.group.Wait()
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.err != nil && !g.reported {
+		g.client.ErrorWithStackSkipContext(g.ctx, rollbar.ERR, g.err, 1, g.fields...)
+		g.reported = true
+	}
+	return g.err
+}
+
+// member wraps fn so that its error is recorded and its panic recovered.
+func (g *Group) member(fn func() error) func() error {
+	return func() (err error) {
+		defer func() {
+			if value := recover(); value != nil {
+				err = g.panicked(value)
+			}
+		}()
+		if err = fn(); err != nil {
+			g.fail(err, false)
+		}
+		return err
+	}
+}
+
+// panicked reports a panic raised by a member and returns it as an error.
+// It must be called from the deferred function that recovered it, so that
+// the reported stack starts where the panic was raised.
+func (g *Group) panicked(value interface{}) error {
+	err, ok := value.(error)
+	if !ok {
+		err = &rollbar.PanicError{Value: value}
+	}
+	g.client.ErrorWithStackSkipContext(g.ctx, rollbar.CRIT, err, 0, g.fields...)
+	g.fail(err, true)
+	return err
+}
+
+// fail records err as the Group's error if it is the first one.
+func (g *Group) fail(err error, reported bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.err == nil {
+		g.err, g.reported = err, reported
+	}
+}
@@ -0,0 +1,76 @@
+package rollbarerrgroup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stvp/rollbar"
+	"github.com/stvp/rollbar/rollbartest"
+)
+
+func frames(item rollbartest.Item) []interface{} {
+	trace := item.Data["body"].(map[string]interface{})["trace"].(map[string]interface{})
+	return trace["frames"].([]interface{})
+}
+
+func TestGroupReportsFirstError(t *testing.T) {
+	client, transport := rollbartest.NewClient()
+	g := New(client, rollbar.Custom(map[string]interface{}{"job": "sync"}))
+
+	g.Go(func() error { return errors.New("fetch failed") })
+	if err := g.Wait(); err == nil || err.Error() != "fetch failed" {
+		t.Fatalf("got error %v", err)
+	}
+	g.Wait()
+
+	transport.AssertCount(t, 1)
+	item := transport.AssertMessage(t, "fetch failed")
+	if item.Level != rollbar.ERR || item.Custom["job"] != "sync" {
+		t.Errorf("got level %s and custom data %v", item.Level, item.Custom)
+	}
+	if file := frames(item)[0].(map[string]interface{})["filename"].(string); !strings.HasSuffix(file, "group_test.go") {
+		t.Errorf("should capture the stack at the Wait call site, got %s", file)
+	}
+}
+
+func TestGroupReportsPanics(t *testing.T) {
+	client, transport := rollbartest.NewClient()
+	ctx := rollbar.ContextWithFields(context.Background(), rollbar.Custom(map[string]interface{}{"request": "abc"}))
+	g, ctx := WithContext(ctx, client)
+
+	g.Go(func() error { panic("boom") })
+	g.Go(func() error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	err := g.Wait()
+
+	var panicErr *rollbar.PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Fatalf("got error %v", err)
+	}
+
+	transport.AssertCount(t, 1)
+	item := transport.AssertMessage(t, "boom")
+	if item.Level != rollbar.CRIT || item.Custom["request"] != "abc" {
+		t.Errorf("got level %s and custom data %v", item.Level, item.Custom)
+	}
+	frame := frames(item)[0].(map[string]interface{})
+	if file := frame["filename"].(string); !strings.HasSuffix(file, "group_test.go") {
+		t.Errorf("should capture the stack where the panic was raised, got %s", file)
+	}
+}
+
+func TestGroupNoError(t *testing.T) {
+	client, transport := rollbartest.NewClient()
+	g := New(client)
+	g.SetLimit(1)
+
+	g.Go(func() error { return nil })
+	if err := g.Wait(); err != nil {
+		t.Errorf("got error %v", err)
+	}
+	transport.AssertCount(t, 0)
+}
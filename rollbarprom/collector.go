@@ -0,0 +1,99 @@
+// Package rollbarprom exports the delivery counters of a rollbar Client as
+// Prometheus metrics, so that queue depth and drop rates show up on the same
+// dashboards as the rest of the application:
+//
+//	client := rollbar.New(token)
+//	prometheus.MustRegister(rollbarprom.NewCollector(client.Stats))
+//
+// Use rollbar.DefaultClient().Stats to export the counters of the package-level
+// functions.
+package rollbarprom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stvp/rollbar"
+)
+
+const namespace = "rollbar"
+
+// Collector is a prometheus.Collector over rollbar.Stats.
+type Collector struct {
+	stats func() rollbar.Stats
+
+	queued        *prometheus.Desc
+	sent          *prometheus.Desc
+	retried       *prometheus.Desc
+	coalesced     *prometheus.Desc
+	sampled       *prometheus.Desc
+	suppressed    *prometheus.Desc
+	spooled       *prometheus.Desc
+	dropped       *prometheus.Desc
+	queueDepth    *prometheus.Desc
+	queueCapacity *prometheus.Desc
+}
+
+// NewCollector returns a Collector that reads the counters returned by stats,
+// typically a Client's Stats method, every time it is scraped. constLabels
+// are added to every metric, which tells several Clients apart.
+func NewCollector(stats func() rollbar.Stats, constLabels ...prometheus.Labels) *Collector {
+	var labels prometheus.Labels
+	if len(constLabels) > 0 {
+		labels = constLabels[0]
+	}
+	desc := func(name, help string, variableLabels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, variableLabels, labels)
+	}
+
+	return &Collector{
+		stats:         stats,
+		queued:        desc("items_queued_total", "Items accepted for delivery to Rollbar."),
+		sent:          desc("items_sent_total", "Items accepted by the Rollbar API."),
+		retried:       desc("retries_total", "POSTs to the Rollbar API retried after a transient failure."),
+		coalesced:     desc("items_coalesced_total", "Items merged into an identical item instead of being POSTed."),
+		sampled:       desc("items_sampled_total", "Items not sent because of sampling."),
+		suppressed:    desc("items_suppressed_total", "Items not sent because an identical item was sent shortly before."),
+		spooled:       desc("items_spooled_total", "Items saved to the on-disk spool."),
+		dropped:       desc("items_dropped_total", "Items that never reached Rollbar, by reason.", "reason"),
+		queueDepth:    desc("queue_depth", "Items waiting in the queue."),
+		queueCapacity: desc("queue_capacity", "Size of the queue."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queued
+	ch <- c.sent
+	ch <- c.retried
+	ch <- c.coalesced
+	ch <- c.sampled
+	ch <- c.suppressed
+	ch <- c.spooled
+	ch <- c.dropped
+	ch <- c.queueDepth
+	ch <- c.queueCapacity
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+
+	counter := func(desc *prometheus.Desc, value uint64, labelValues ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), labelValues...)
+	}
+	counter(c.queued, stats.Queued)
+	counter(c.sent, stats.Sent)
+	counter(c.retried, stats.Retried)
+	counter(c.coalesced, stats.Coalesced)
+	counter(c.sampled, stats.Sampled)
+	counter(c.suppressed, stats.Suppressed)
+	counter(c.spooled, stats.Spooled)
+	counter(c.dropped, stats.DroppedBufferFull, "buffer_full")
+	counter(c.dropped, stats.DroppedClosed, "closed")
+	counter(c.dropped, stats.DroppedCircuitOpen, "circuit_open")
+	counter(c.dropped, stats.DroppedRateLimited, "rate_limited")
+	counter(c.dropped, stats.DroppedRejected, "rejected")
+	counter(c.dropped, stats.DroppedFailed, "failed")
+
+	ch <- prometheus.MustNewConstMetric(c.queueDepth, prometheus.GaugeValue, float64(stats.QueueDepth))
+	ch <- prometheus.MustNewConstMetric(c.queueCapacity, prometheus.GaugeValue, float64(stats.QueueCapacity))
+}
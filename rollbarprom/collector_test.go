@@ -0,0 +1,39 @@
+package rollbarprom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stvp/rollbar"
+)
+
+func TestCollector(t *testing.T) {
+	stats := func() rollbar.Stats {
+		return rollbar.Stats{Sent: 3, Dropped: 2, DroppedBufferFull: 2, QueueDepth: 5, QueueCapacity: 10}
+	}
+	collector := NewCollector(stats, prometheus.Labels{"project": "web"})
+
+	expected := `
+# HELP rollbar_items_dropped_total Items that never reached Rollbar, by reason.
+# TYPE rollbar_items_dropped_total counter
+rollbar_items_dropped_total{project="web",reason="buffer_full"} 2
+rollbar_items_dropped_total{project="web",reason="circuit_open"} 0
+rollbar_items_dropped_total{project="web",reason="closed"} 0
+rollbar_items_dropped_total{project="web",reason="failed"} 0
+rollbar_items_dropped_total{project="web",reason="rate_limited"} 0
+rollbar_items_dropped_total{project="web",reason="rejected"} 0
+# HELP rollbar_items_sent_total Items accepted by the Rollbar API.
+# TYPE rollbar_items_sent_total counter
+rollbar_items_sent_total{project="web"} 3
+# HELP rollbar_queue_depth Items waiting in the queue.
+# TYPE rollbar_queue_depth gauge
+rollbar_queue_depth{project="web"} 5
+`
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"rollbar_items_dropped_total", "rollbar_items_sent_total", "rollbar_queue_depth")
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,214 @@
+// Package rollbarslog provides a log/slog Handler that reports warnings and
+// errors to Rollbar, so that a single logging call both logs and reports:
+//
+//	client := rollbar.New(token)
+//	logger := slog.New(rollbarslog.NewHandler(client, slog.NewJSONHandler(os.Stderr, nil), nil))
+//	logger.Error("charge failed", "err", err, "order", orderID)
+//
+// Records at or above the reporting level are reported with their attributes
+// as custom data and a stack trace captured at the log call site. If a record
+// carries an error attribute, the error is reported as such, otherwise the
+// record is reported as a message. Every record is also passed on to the
+// inner Handler, if any.
+package rollbarslog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/stvp/rollbar"
+)
+
+// messageField is the custom data field that holds the log message of
+// records reported as errors.
+const messageField = "log_message"
+
+// Options configures a Handler.
+type Options struct {
+	// Level is the minimum level of the records reported to Rollbar. The
+	// default is slog.LevelWarn.
+	Level slog.Leveler
+}
+
+// Handler is a slog.Handler that reports records to Rollbar.
+type Handler struct {
+	client *rollbar.Client
+	inner  slog.Handler
+	level  slog.Leveler
+
+	// attrs are the attributes added with WithAttrs, already nested in the
+	// groups that were open at the time. groups are the groups open now.
+	attrs  map[string]interface{}
+	groups []string
+}
+
+// NewHandler returns a Handler that reports records to the given Client and
+// passes them on to inner, which can be nil. opts can be nil too.
+func NewHandler(client *rollbar.Client, inner slog.Handler, opts *Options) *Handler {
+	h := &Handler{client: client, inner: inner, level: slog.LevelWarn, attrs: map[string]interface{}{}}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() || (h.inner != nil && h.inner.Enabled(ctx, level))
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.level.Level() {
+		h.report(ctx, r)
+	}
+	if h.inner != nil && h.inner.Enabled(ctx, r.Level) {
+		return h.inner.Handle(ctx, r)
+	}
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := h.clone()
+	if h.inner != nil {
+		clone.inner = h.inner.WithAttrs(attrs)
+	}
+	addAttrs(clone.group(), attrs)
+	return clone
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := h.clone()
+	if h.inner != nil {
+		clone.inner = h.inner.WithGroup(name)
+	}
+	clone.groups = append(clone.groups[:len(clone.groups):len(clone.groups)], name)
+	return clone
+}
+
+// clone returns a copy of the Handler with its own attributes.
+func (h *Handler) clone() *Handler {
+	clone := *h
+	clone.attrs = copyMap(h.attrs)
+	return &clone
+}
+
+// group returns the map that holds the attributes of the innermost open
+// group, creating it if necessary.
+func (h *Handler) group() map[string]interface{} {
+	m := h.attrs
+	for _, name := range h.groups {
+		sub, ok := m[name].(map[string]interface{})
+		if !ok {
+			sub = map[string]interface{}{}
+			m[name] = sub
+		}
+		m = sub
+	}
+	return m
+}
+
+// report sends the given record to Rollbar.
+func (h *Handler) report(ctx context.Context, r slog.Record) {
+	clone := h.clone()
+	var attrs []slog.Attr
+	var err error
+	r.Attrs(func(attr slog.Attr) bool {
+		if e, ok := attr.Value.Resolve().Any().(error); ok && err == nil {
+			err = e
+			return true
+		}
+		attrs = append(attrs, attr)
+		return true
+	})
+	addAttrs(clone.group(), attrs)
+
+	custom := clone.attrs
+	var fields []*rollbar.Field
+	level := rollbarLevel(r.Level)
+	if err == nil {
+		if len(custom) > 0 {
+			fields = append(fields, rollbar.Custom(custom))
+		}
+		h.client.MessageWithContext(ctx, level, r.Message, fields...)
+		return
+	}
+
+	custom[messageField] = r.Message
+	fields = append(fields, rollbar.Custom(custom))
+	h.client.ErrorWithStackContext(ctx, level, err, h.client.CallerStack(r.PC, "log/slog"), fields...)
+}
+
+// rollbarLevel maps a slog level to a Rollbar level.
+func rollbarLevel(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError+4:
+		return rollbar.CRIT
+	case level >= slog.LevelError:
+		return rollbar.ERR
+	case level >= slog.LevelWarn:
+		return rollbar.WARN
+	case level >= slog.LevelInfo:
+		return rollbar.INFO
+	default:
+		return rollbar.DEBUG
+	}
+}
+
+// addAttrs adds the given attributes to m, nesting groups.
+func addAttrs(m map[string]interface{}, attrs []slog.Attr) {
+	for _, attr := range attrs {
+		value := attr.Value.Resolve()
+		if value.Kind() == slog.KindGroup {
+			group := value.Group()
+			if len(group) == 0 {
+				continue
+			}
+			if attr.Key == "" {
+				addAttrs(m, group)
+				continue
+			}
+			sub, ok := m[attr.Key].(map[string]interface{})
+			if !ok {
+				sub = map[string]interface{}{}
+				m[attr.Key] = sub
+			}
+			addAttrs(sub, group)
+			continue
+		}
+		if attr.Key == "" {
+			continue
+		}
+		m[attr.Key] = attrValue(value)
+	}
+}
+
+// attrValue returns the value to report for an attribute value.
+func attrValue(value slog.Value) interface{} {
+	switch value.Kind() {
+	case slog.KindDuration, slog.KindTime:
+		return value.String()
+	default:
+		if err, ok := value.Any().(error); ok {
+			return err.Error()
+		}
+		return value.Any()
+	}
+}
+
+// copyMap returns a deep copy of nested attribute maps.
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	clone := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if sub, ok := v.(map[string]interface{}); ok {
+			v = copyMap(sub)
+		}
+		clone[k] = v
+	}
+	return clone
+}
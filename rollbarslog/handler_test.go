@@ -0,0 +1,77 @@
+package rollbarslog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stvp/rollbar"
+	"github.com/stvp/rollbar/rollbartest"
+)
+
+func TestHandlerReportsErrors(t *testing.T) {
+	client, transport := rollbartest.NewClient()
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(client, slog.NewTextHandler(&buf, nil), nil))
+
+	logger.With("service", "billing").WithGroup("order").Error("charge failed", "err", errors.New("card declined"), "id", 42)
+
+	item := transport.AssertMessage(t, "card declined")
+	if item.Level != rollbar.ERR {
+		t.Errorf("got level %s", item.Level)
+	}
+	if item.Custom["service"] != "billing" || item.Custom[messageField] != "charge failed" {
+		t.Errorf("got custom data %v", item.Custom)
+	}
+	if order, _ := item.Custom["order"].(map[string]interface{}); order["id"] != float64(42) {
+		t.Errorf("got order group %v", item.Custom["order"])
+	}
+
+	frames := item.Data["body"].(map[string]interface{})["trace"].(map[string]interface{})["frames"].([]interface{})
+	if file := frames[0].(map[string]interface{})["filename"].(string); !strings.HasSuffix(file, "handler_test.go") {
+		t.Errorf("should capture the stack at the log call site, got %s", file)
+	}
+
+	if !strings.Contains(buf.String(), "charge failed") {
+		t.Errorf("should pass records on to the inner handler, got %q", buf.String())
+	}
+}
+
+func TestHandlerLevels(t *testing.T) {
+	client, transport := rollbartest.NewClient()
+	logger := slog.New(NewHandler(client, nil, nil))
+
+	logger.Info("routine")
+	logger.Warn("disk almost full", "free", "2%")
+
+	transport.AssertCount(t, 1)
+	item := transport.AssertMessage(t, "disk almost full")
+	if item.Level != rollbar.WARN || item.Custom["free"] != "2%" {
+		t.Errorf("got %+v", item)
+	}
+	if logger.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("should not be enabled below the reporting level without an inner handler")
+	}
+}
+
+func TestHandlerReportsContext(t *testing.T) {
+	client, transport := rollbartest.NewClient(rollbar.WithPersonExtractor(func(ctx context.Context, r *http.Request) *rollbar.Person {
+		return &rollbar.Person{ID: "42"}
+	}))
+	logger := slog.New(NewHandler(client, nil, nil))
+
+	ctx := rollbar.ContextWithFields(context.Background(), rollbar.Custom(map[string]interface{}{"request": "abc"}))
+	logger.ErrorContext(ctx, "charge failed", "err", errors.New("card declined"))
+
+	item := transport.AssertMessage(t, "card declined")
+	if item.Custom["request"] != "abc" {
+		t.Errorf("got custom data %v", item.Custom)
+	}
+	if person, _ := item.Data["person"].(map[string]interface{}); person["id"] != "42" {
+		t.Errorf("should report the person of the context, got %v", item.Data["person"])
+	}
+}
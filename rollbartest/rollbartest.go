@@ -0,0 +1,191 @@
+// Package rollbartest helps test how applications report errors to Rollbar
+// without hitting the network. A Transport records the items a Client
+// delivers, and its assertion helpers check what was reported:
+//
+//	client, transport := rollbartest.NewClient()
+//	handler := NewHandler(client)
+//	handler.ServeHTTP(httptest.NewRecorder(), badRequest)
+//	transport.AssertReported(t, "strconv.NumError")
+package rollbartest
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stvp/rollbar"
+)
+
+// Item is an item recorded by a Transport.
+type Item struct {
+	// Level is the Rollbar severity level (rollbar.CRIT, rollbar.ERR, etc.).
+	Level string
+
+	// Title is the error message, or the message body for messages.
+	Title string
+
+	// Class is the exception class of an error, or "" for messages.
+	Class string
+
+	// UUID identifies the occurrence, as returned by the reporting function.
+	UUID string
+
+	// AccessToken is the token of the project the item was routed to.
+	AccessToken string
+
+	// Custom is the custom data reported with the item, if any.
+	Custom map[string]interface{}
+
+	// Data is the whole decoded "data" section of the payload.
+	Data map[string]interface{}
+}
+
+// Transport is a rollbar.Transport that records items in memory. It is safe
+// for concurrent use.
+type Transport struct {
+	mutex sync.Mutex
+	items []Item
+}
+
+// NewTransport returns an empty Transport.
+func NewTransport() *Transport {
+	return &Transport{}
+}
+
+// NewClient returns a synchronous Client that records the items it reports to
+// a new Transport, so that they can be checked as soon as the reporting call
+// returns. The given options are applied on top.
+func NewClient(opts ...rollbar.Option) (*rollbar.Client, *Transport) {
+	transport := NewTransport()
+	opts = append([]rollbar.Option{rollbar.WithTransport(transport), rollbar.WithSync()}, opts...)
+	return rollbar.New("test-token", opts...), transport
+}
+
+// Send implements rollbar.Transport.
+func (t *Transport) Send(ctx context.Context, payload []byte) error {
+	var body struct {
+		AccessToken string                 `json:"access_token"`
+		Data        map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return err
+	}
+
+	item := Item{AccessToken: body.AccessToken, Data: body.Data}
+	item.Level, _ = body.Data["level"].(string)
+	item.Title, _ = body.Data["title"].(string)
+	item.UUID, _ = body.Data["uuid"].(string)
+	item.Custom, _ = body.Data["custom"].(map[string]interface{})
+	if itemBody, ok := body.Data["body"].(map[string]interface{}); ok {
+		trace, ok := itemBody["trace"].(map[string]interface{})
+		if chain, _ := itemBody["trace_chain"].([]interface{}); !ok && len(chain) > 0 {
+			// The first trace of a chain is the reported error itself.
+			trace, ok = chain[0].(map[string]interface{})
+		}
+		if ok {
+			if exception, ok := trace["exception"].(map[string]interface{}); ok {
+				item.Class, _ = exception["class"].(string)
+			}
+		}
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.items = append(t.items, item)
+	return nil
+}
+
+// Flush implements rollbar.Transport.
+func (t *Transport) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close implements rollbar.Transport.
+func (t *Transport) Close() error {
+	return nil
+}
+
+// Items returns the items recorded so far, oldest first.
+func (t *Transport) Items() []Item {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return append([]Item(nil), t.items...)
+}
+
+// Reset forgets the items recorded so far.
+func (t *Transport) Reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.items = nil
+}
+
+// find returns the first recorded item that matches.
+func (t *Transport) find(match func(Item) bool) (Item, bool) {
+	for _, item := range t.Items() {
+		if match(item) {
+			return item, true
+		}
+	}
+	return Item{}, false
+}
+
+// AssertReported fails the test unless an error of the given class has been
+// recorded, and returns the first such item.
+func (t *Transport) AssertReported(tb testing.TB, class string) Item {
+	tb.Helper()
+	item, ok := t.find(func(item Item) bool { return item.Class == class })
+	if !ok {
+		tb.Errorf("rollbartest: no %s error reported, got %s", class, t.summary())
+	}
+	return item
+}
+
+// AssertNotReported fails the test if an error of the given class has been
+// recorded.
+func (t *Transport) AssertNotReported(tb testing.TB, class string) {
+	tb.Helper()
+	if item, ok := t.find(func(item Item) bool { return item.Class == class }); ok {
+		tb.Errorf("rollbartest: unexpected %s error reported: %q", class, item.Title)
+	}
+}
+
+// AssertMessage fails the test unless an item whose title contains the given
+// text has been recorded, and returns the first such item.
+func (t *Transport) AssertMessage(tb testing.TB, text string) Item {
+	tb.Helper()
+	item, ok := t.find(func(item Item) bool { return strings.Contains(item.Title, text) })
+	if !ok {
+		tb.Errorf("rollbartest: no item containing %q reported, got %s", text, t.summary())
+	}
+	return item
+}
+
+// AssertCount fails the test unless exactly n items have been recorded.
+func (t *Transport) AssertCount(tb testing.TB, n int) {
+	tb.Helper()
+	if items := t.Items(); len(items) != n {
+		tb.Errorf("rollbartest: got %d items reported, want %d: %s", len(items), n, t.summary())
+	}
+}
+
+// summary describes the recorded items for failure messages.
+func (t *Transport) summary() string {
+	items := t.Items()
+	if len(items) == 0 {
+		return "nothing"
+	}
+
+	var parts []string
+	for _, item := range items {
+		if item.Class != "" {
+			parts = append(parts, item.Class+": "+item.Title)
+		} else {
+			parts = append(parts, item.Title)
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
@@ -0,0 +1,53 @@
+package rollbartest
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stvp/rollbar"
+)
+
+// recorder is a testing.TB that records failures instead of failing.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestTransportRecordsItems(t *testing.T) {
+	client, transport := NewClient(rollbar.WithCustom(map[string]interface{}{"region": "eu"}))
+
+	_, err := os.Open("/does/not/exist")
+	uuid, _ := client.Error(rollbar.ERR, err)
+	client.Message(rollbar.INFO, "cache warmed up")
+
+	transport.AssertCount(t, 2)
+	item := transport.AssertReported(t, "fs.PathError")
+	if item.UUID != uuid || item.Level != rollbar.ERR || item.Custom["region"] != "eu" {
+		t.Errorf("got %+v", item)
+	}
+	transport.AssertMessage(t, "warmed")
+	transport.AssertNotReported(t, "net.OpError")
+
+	transport.Reset()
+	transport.AssertCount(t, 0)
+}
+
+func TestAssertionsFail(t *testing.T) {
+	client, transport := NewClient()
+	client.Message(rollbar.INFO, "hello")
+
+	r := &recorder{TB: t}
+	transport.AssertReported(r, "fs.PathError")
+	transport.AssertMessage(r, "goodbye")
+	transport.AssertCount(r, 2)
+	if len(r.failures) != 3 {
+		t.Errorf("got failures %v", r.failures)
+	}
+}
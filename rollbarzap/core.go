@@ -0,0 +1,112 @@
+// Package rollbarzap provides a zapcore.Core that reports log entries to
+// Rollbar. Tee it with the application's own core so that every error is both
+// logged and reported from a single call site:
+//
+//	client := rollbar.New(token)
+//	logger := zap.New(rollbarzap.Tee(core, client))
+//	logger.Error("charge failed", zap.Error(err), zap.Int("order", orderID))
+//
+// An error field is reported as the error, with a stack trace of the log call
+// site, and the other fields are reported as custom data. Entries without an
+// error field are reported as messages.
+package rollbarzap
+
+import (
+	"go.uber.org/zap/zapcore"
+
+	"github.com/stvp/rollbar"
+)
+
+// messageField is the custom data field that holds the log message of
+// entries reported as errors.
+const messageField = "log_message"
+
+// Core is a zapcore.Core that reports entries to Rollbar.
+type Core struct {
+	zapcore.LevelEnabler
+	client *rollbar.Client
+	fields []zapcore.Field
+}
+
+// NewCore returns a Core that reports entries enabled by level (e.g.
+// zapcore.ErrorLevel) to client.
+func NewCore(client *rollbar.Client, level zapcore.LevelEnabler) *Core {
+	return &Core{LevelEnabler: level, client: client}
+}
+
+// Tee returns a Core that writes entries to core and reports those at
+// zapcore.ErrorLevel or above to client.
+func Tee(core zapcore.Core, client *rollbar.Client) zapcore.Core {
+	return zapcore.NewTee(core, NewCore(client, zapcore.ErrorLevel))
+}
+
+// With implements zapcore.Core.
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *Core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write implements zapcore.Core. Entries above zapcore.ErrorLevel are
+// delivered before Write returns, since zap may panic or exit right after.
+func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoder := zapcore.NewMapObjectEncoder()
+	var err error
+	for _, field := range append(c.fields[:len(c.fields):len(c.fields)], fields...) {
+		if e, ok := field.Interface.(error); ok && field.Type == zapcore.ErrorType && err == nil {
+			err = e
+			continue
+		}
+		field.AddTo(encoder)
+	}
+	custom := encoder.Fields
+
+	var reportErr error
+	level := rollbarLevel(entry.Level)
+	if err == nil {
+		var fields []*rollbar.Field
+		if len(custom) > 0 {
+			fields = append(fields, rollbar.Custom(custom))
+		}
+		_, reportErr = c.client.Message(level, entry.Message, fields...)
+	} else {
+		custom[messageField] = entry.Message
+		_, reportErr = c.client.ErrorWithStack(level, err, c.client.CallerStack(entry.Caller.PC, "go.uber.org/zap"), rollbar.Custom(custom))
+	}
+
+	if entry.Level > zapcore.ErrorLevel {
+		c.client.Wait()
+	}
+	return reportErr
+}
+
+// Sync implements zapcore.Core. It waits for reported entries to be
+// delivered.
+func (c *Core) Sync() error {
+	c.client.Wait()
+	return nil
+}
+
+// rollbarLevel maps a zap level to a Rollbar level.
+func rollbarLevel(level zapcore.Level) string {
+	switch {
+	case level > zapcore.ErrorLevel:
+		return rollbar.CRIT
+	case level == zapcore.ErrorLevel:
+		return rollbar.ERR
+	case level == zapcore.WarnLevel:
+		return rollbar.WARN
+	case level == zapcore.InfoLevel:
+		return rollbar.INFO
+	default:
+		return rollbar.DEBUG
+	}
+}
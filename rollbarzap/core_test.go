@@ -0,0 +1,46 @@
+package rollbarzap
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/stvp/rollbar"
+	"github.com/stvp/rollbar/rollbartest"
+)
+
+func TestCoreReportsErrors(t *testing.T) {
+	client, transport := rollbartest.NewClient()
+	logger := zap.New(Tee(zapcore.NewNopCore(), client), zap.AddCaller())
+
+	logger.With(zap.String("service", "billing")).Error("charge failed", zap.Error(errors.New("card declined")), zap.Int("order", 42))
+
+	item := transport.AssertMessage(t, "card declined")
+	if item.Level != rollbar.ERR {
+		t.Errorf("got level %s", item.Level)
+	}
+	if item.Custom["service"] != "billing" || item.Custom["order"] != float64(42) || item.Custom[messageField] != "charge failed" {
+		t.Errorf("got custom data %v", item.Custom)
+	}
+
+	frames := item.Data["body"].(map[string]interface{})["trace"].(map[string]interface{})["frames"].([]interface{})
+	if file := frames[0].(map[string]interface{})["filename"].(string); !strings.HasSuffix(file, "core_test.go") {
+		t.Errorf("should capture the stack at the log call site, got %s", file)
+	}
+}
+
+func TestCoreLevels(t *testing.T) {
+	client, transport := rollbartest.NewClient()
+	logger := zap.New(Tee(zapcore.NewNopCore(), client))
+
+	logger.Warn("ignored")
+	logger.Error("no error attached", zap.String("user", "jane"))
+
+	transport.AssertCount(t, 1)
+	if item := transport.AssertMessage(t, "no error attached"); item.Custom["user"] != "jane" {
+		t.Errorf("got custom data %v", item.Custom)
+	}
+}
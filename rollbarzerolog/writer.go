@@ -0,0 +1,108 @@
+// Package rollbarzerolog provides a zerolog LevelWriter that reports log
+// events to Rollbar. Combine it with the application's own output so that
+// every error is both logged and reported from a single call site:
+//
+//	client := rollbar.New(token)
+//	logger := zerolog.New(zerolog.MultiLevelWriter(os.Stderr, rollbarzerolog.NewWriter(client)))
+//	logger.Error().Err(err).Int("order", orderID).Msg("charge failed")
+//
+// The JSON event is parsed: its error field is reported as the error, with a
+// stack trace of the log call site, and its other fields are reported as
+// custom data. Events without an error are reported as messages.
+package rollbarzerolog
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/rs/zerolog"
+	"github.com/stvp/rollbar"
+)
+
+// messageField is the custom data field that holds the log message of events
+// reported as errors.
+const messageField = "log_message"
+
+// Writer is a zerolog.LevelWriter that reports events to Rollbar.
+type Writer struct {
+	client *rollbar.Client
+	level  zerolog.Level
+}
+
+// NewWriter returns a Writer that reports events at zerolog.ErrorLevel or
+// above to client.
+func NewWriter(client *rollbar.Client) *Writer {
+	return NewLevelWriter(client, zerolog.ErrorLevel)
+}
+
+// NewLevelWriter returns a Writer that reports events at the given level or
+// above to client.
+func NewLevelWriter(client *rollbar.Client, level zerolog.Level) *Writer {
+	return &Writer{client: client, level: level}
+}
+
+// Write implements io.Writer. The level of the event is read from its level
+// field.
+func (w *Writer) Write(p []byte) (int, error) {
+	var event struct {
+		Level string `json:"level"`
+	}
+	json.Unmarshal(p, &event)
+	level, err := zerolog.ParseLevel(event.Level)
+	if err != nil {
+		level = zerolog.NoLevel
+	}
+	return w.WriteLevel(level, p)
+}
+
+// WriteLevel implements zerolog.LevelWriter. Events are reported
+// asynchronously, except fatal and panic events, which are delivered before
+// WriteLevel returns since zerolog exits or panics right after.
+func (w *Writer) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < w.level || level == zerolog.NoLevel || level == zerolog.Disabled {
+		return len(p), nil
+	}
+
+	var custom map[string]interface{}
+	if err := json.Unmarshal(p, &custom); err != nil {
+		return 0, err
+	}
+	message, _ := custom[zerolog.MessageFieldName].(string)
+	errMessage, hasErr := custom[zerolog.ErrorFieldName].(string)
+	for _, key := range []string{zerolog.LevelFieldName, zerolog.MessageFieldName, zerolog.ErrorFieldName, zerolog.TimestampFieldName} {
+		delete(custom, key)
+	}
+
+	rollbarLevel := rollbarLevel(level)
+	if !hasErr {
+		var fields []*rollbar.Field
+		if len(custom) > 0 {
+			fields = append(fields, rollbar.Custom(custom))
+		}
+		w.client.Message(rollbarLevel, message, fields...)
+	} else {
+		custom[messageField] = message
+		w.client.ErrorWithStack(rollbarLevel, errors.New(errMessage), w.client.CallerStack(0, "github.com/rs/zerolog"), rollbar.Custom(custom))
+	}
+
+	if level >= zerolog.FatalLevel {
+		w.client.Wait()
+	}
+	return len(p), nil
+}
+
+// rollbarLevel maps a zerolog level to a Rollbar level.
+func rollbarLevel(level zerolog.Level) string {
+	switch {
+	case level >= zerolog.FatalLevel:
+		return rollbar.CRIT
+	case level == zerolog.ErrorLevel:
+		return rollbar.ERR
+	case level == zerolog.WarnLevel:
+		return rollbar.WARN
+	case level == zerolog.InfoLevel:
+		return rollbar.INFO
+	default:
+		return rollbar.DEBUG
+	}
+}
@@ -0,0 +1,48 @@
+package rollbarzerolog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stvp/rollbar"
+	"github.com/stvp/rollbar/rollbartest"
+)
+
+func TestWriterReportsErrors(t *testing.T) {
+	client, transport := rollbartest.NewClient()
+	logger := zerolog.New(NewWriter(client)).With().Timestamp().Logger()
+
+	logger.Error().Err(errors.New("card declined")).Int("order", 42).Msg("charge failed")
+
+	item := transport.AssertMessage(t, "card declined")
+	if item.Level != rollbar.ERR {
+		t.Errorf("got level %s", item.Level)
+	}
+	if item.Custom["order"] != float64(42) || item.Custom[messageField] != "charge failed" {
+		t.Errorf("got custom data %v", item.Custom)
+	}
+	if _, ok := item.Custom[zerolog.TimestampFieldName]; ok {
+		t.Error("should not report the timestamp as custom data")
+	}
+
+	frames := item.Data["body"].(map[string]interface{})["trace"].(map[string]interface{})["frames"].([]interface{})
+	if file := frames[0].(map[string]interface{})["filename"].(string); !strings.HasSuffix(file, "writer_test.go") {
+		t.Errorf("should capture the stack at the log call site, got %s", file)
+	}
+}
+
+func TestWriterLevels(t *testing.T) {
+	client, transport := rollbartest.NewClient()
+	writer := NewLevelWriter(client, zerolog.WarnLevel)
+
+	writer.Write([]byte(`{"level":"info","message":"ignored"}`))
+	writer.Write([]byte(`{"level":"warn","message":"disk almost full","free":"2%"}`))
+
+	transport.AssertCount(t, 1)
+	item := transport.AssertMessage(t, "disk almost full")
+	if item.Level != rollbar.WARN || item.Custom["free"] != "2%" {
+		t.Errorf("got %+v", item)
+	}
+}
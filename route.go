@@ -0,0 +1,102 @@
+package rollbar
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Matcher reports whether an Item matches a Route.
+type Matcher func(item *Item) bool
+
+// Route sends the items it matches to another Rollbar project. Routes are
+// checked in the order they were added and the first match wins. Items that
+// match no Route go to the project of the Client's own access token.
+type Route struct {
+	Token string
+	Match Matcher
+}
+
+// MatchLevels matches items reported with any of the given severity levels.
+func MatchLevels(levels ...string) Matcher {
+	return func(item *Item) bool {
+		for _, level := range levels {
+			if item.Level == level {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchPackages matches errors whose innermost stack frame is in a file under
+// any of the given import path prefixes (e.g. "github.com/acme/payments/").
+// Paths are compared after shortening, as in Frame.Filename.
+func MatchPackages(prefixes ...string) Matcher {
+	return func(item *Item) bool {
+		if len(item.Stack) == 0 {
+			return false
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(item.Stack[0].Filename, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchErrorClasses matches errors reported with any of the given exception
+// classes (e.g. "net.OpError").
+func MatchErrorClasses(classes ...string) Matcher {
+	return func(item *Item) bool {
+		class := item.Class()
+		for _, c := range classes {
+			if class == c {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchErrors matches errors that are, or wrap, any of the given errors, as
+// reported by errors.Is, such as io.EOF or context.Canceled.
+func MatchErrors(targets ...error) Matcher {
+	return func(item *Item) bool {
+		if item.Err == nil {
+			return false
+		}
+		for _, target := range targets {
+			if errors.Is(item.Err, target) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchMessages matches errors and messages whose title, i.e. the error
+// message or the message body, matches any of the given patterns.
+func MatchMessages(patterns ...*regexp.Regexp) Matcher {
+	return func(item *Item) bool {
+		for _, pattern := range patterns {
+			if pattern.MatchString(item.Title) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// tokenFor returns the access token of the first Route matching item, or the
+// Client's own token.
+func (c *Client) tokenFor(item *Item) string {
+	config := c.snapshot()
+	for _, route := range config.routes {
+		if route.Match(item) {
+			return route.Token
+		}
+	}
+	return config.token
+}
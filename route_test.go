@@ -0,0 +1,74 @@
+package rollbar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"testing"
+)
+
+func TestTokenFor(t *testing.T) {
+	client := New("platform",
+		WithRoute("payments", MatchPackages("github.com/acme/payments/")),
+		WithRoute("critical", MatchLevels(CRIT)),
+		WithRoute("custom", MatchErrorClasses("rollbar.CustomError")),
+	)
+
+	paymentsStack := Stack{Frame{Filename: "github.com/acme/payments/charge.go", Method: "payments.Charge", Line: 10}}
+	otherStack := Stack{Frame{Filename: "github.com/acme/web/handler.go", Method: "web.Handle", Line: 20}}
+
+	tests := []struct {
+		Item     *Item
+		Expected string
+	}{
+		{newErrorItem(ERR, errors.New("declined"), paymentsStack), "payments"},
+		{newErrorItem(CRIT, errors.New("declined"), paymentsStack), "payments"},
+		{newErrorItem(CRIT, errors.New("oops"), otherStack), "critical"},
+		{newErrorItem(ERR, &CustomError{"oops"}, otherStack), "custom"},
+		{newErrorItem(ERR, errors.New("oops"), otherStack), "platform"},
+		{newMessageItem(INFO, "hello"), "platform"},
+		{newMessageItem(CRIT, "hello"), "critical"},
+	}
+
+	for i, test := range tests {
+		got := client.tokenFor(test.Item)
+		if got != test.Expected {
+			t.Errorf("tests[%d]: got %s", i, got)
+		}
+	}
+}
+
+func TestItemClass(t *testing.T) {
+	if class := newMessageItem(INFO, "hello").Class(); class != "" {
+		t.Errorf("got: %s", class)
+	}
+	if class := newErrorItem(ERR, &CustomError{"oops"}, nil).Class(); class != "rollbar.CustomError" {
+		t.Errorf("got: %s", class)
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil),
+		WithIgnoredErrors(io.EOF, context.Canceled),
+		WithIgnoredMessages(regexp.MustCompile(`^broken pipe`)),
+	)
+	client.AddIgnore(MatchErrorClasses("rollbar.CustomError"))
+
+	client.Error(ERR, io.EOF)
+	client.Error(ERR, fmt.Errorf("reading body: %w", context.Canceled))
+	client.Error(ERR, errors.New("broken pipe while writing"))
+	client.Message(WARN, "broken pipe")
+	client.Error(ERR, &CustomError{"oops"})
+	if len(transport.payloads) != 0 {
+		t.Errorf("should ignore every item, got %d", len(transport.payloads))
+	}
+
+	client.Error(ERR, io.ErrUnexpectedEOF)
+	client.Message(WARN, "disk full: broken pipe")
+	if len(transport.payloads) != 2 {
+		t.Errorf("got %d items", len(transport.payloads))
+	}
+}
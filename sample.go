@@ -0,0 +1,62 @@
+package rollbar
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// sampleRateField is the key of the custom data field that tells which
+// fraction of occurrences an item was sampled at.
+const sampleRateField = "sample_rate"
+
+// maxSampledFingerprints bounds how many fingerprints a sampler keeps counts
+// for. When there are more, counting starts over.
+const maxSampledFingerprints = 10000
+
+// SamplingRule sends only a fraction of the occurrences of high-volume items
+// that are already well known, so that they don't drown out new ones.
+type SamplingRule struct {
+	// Match selects the items the rule applies to. A nil Match matches every
+	// item.
+	Match Matcher
+
+	// After is the number of occurrences of each fingerprint (same level,
+	// class, message and stack trace) that are always sent before sampling
+	// starts.
+	After int
+
+	// Rate is the fraction of further occurrences that are sent, between 0
+	// and 1.
+	Rate float64
+}
+
+// sampler counts occurrences per fingerprint to apply SamplingRules.
+type sampler struct {
+	mutex  sync.Mutex
+	counts map[string]int
+}
+
+// sample reports whether the given item is sent under the first matching
+// rule, and the rate it was sampled at (1 if it was not sampled).
+func (s *sampler) sample(item *Item, rules []SamplingRule) (bool, float64) {
+	for _, rule := range rules {
+		if rule.Match != nil && !rule.Match(item) {
+			continue
+		}
+
+		s.mutex.Lock()
+		if s.counts == nil || len(s.counts) >= maxSampledFingerprints {
+			s.counts = map[string]int{}
+		}
+		fingerprint := item.fingerprint()
+		s.counts[fingerprint]++
+		count := s.counts[fingerprint]
+		s.mutex.Unlock()
+
+		if count <= rule.After {
+			return true, 1
+		}
+		return rand.Float64() < rule.Rate, rule.Rate
+	}
+	return true, 1
+}
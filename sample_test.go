@@ -0,0 +1,58 @@
+package rollbar
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSampling(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil),
+		WithSampling(SamplingRule{Match: MatchLevels(ERR), After: 2, Rate: 0}))
+
+	known := errors.New("known")
+	for i := 0; i < 5; i++ {
+		client.Error(ERR, known)
+	}
+	client.Message(INFO, "unmatched")
+
+	if len(transport.payloads) != 3 {
+		t.Errorf("got %d items", len(transport.payloads))
+	}
+	if sampled := client.Stats().Sampled; sampled != 3 {
+		t.Errorf("got %d sampled items", sampled)
+	}
+}
+
+func TestSamplingRate(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil),
+		WithSampling(SamplingRule{Rate: 1}))
+	client.Message(INFO, "sampled")
+
+	var body struct {
+		Data struct {
+			Custom map[string]interface{} `json:"custom"`
+		} `json:"data"`
+	}
+	json.Unmarshal(transport.payloads[0], &body)
+	if rate := body.Data.Custom[sampleRateField]; rate != nil {
+		t.Errorf("should not mark items sent at a rate of 1, got %v", rate)
+	}
+
+	var s sampler
+	rule := []SamplingRule{{Rate: 0.5}}
+	sent := 0
+	for i := 0; i < 1000; i++ {
+		if ok, rate := s.sample(newMessageItem(INFO, "often"), rule); ok {
+			sent++
+			if rate != 0.5 {
+				t.Fatalf("got rate %v", rate)
+			}
+		}
+	}
+	if sent < 400 || sent > 600 {
+		t.Errorf("sent %d of 1000 items at a rate of 0.5", sent)
+	}
+}
@@ -0,0 +1,128 @@
+package rollbar
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ScrubFunc implements a custom redaction policy. It is called for every map
+// key found in an item's data, at any depth, along with the key's value, and
+// returns the value to send in its place. Return value unchanged to keep it,
+// or FILTERED to scrub it. ScrubFunc is only called for keys that the scrub
+// fields and patterns didn't match.
+type ScrubFunc func(key string, value interface{}) interface{}
+
+// scrubber replaces sensitive values in item data. The zero value scrubs
+// nothing.
+type scrubber struct {
+	patterns []*regexp.Regexp
+	fn       ScrubFunc
+}
+
+// scrubPattern compiles the given field names into a case-insensitive
+// regular expression that matches any key containing one of them.
+func scrubPattern(fields []string) *regexp.Regexp {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = regexp.QuoteMeta(field)
+	}
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
+
+// newScrubber returns a scrubber for the scrub settings of config.
+func newScrubber(config configuration) *scrubber {
+	s := &scrubber{fn: config.scrubFunc}
+	if config.filterFields != nil {
+		s.patterns = append(s.patterns, config.filterFields)
+	}
+	s.patterns = append(s.patterns, config.scrubPatterns...)
+	return s
+}
+
+func (s *scrubber) matches(key string) bool {
+	for _, pattern := range s.patterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// field returns the value to send for the given key.
+func (s *scrubber) field(key string, value interface{}) interface{} {
+	if s.matches(key) {
+		return FILTERED
+	}
+	if s.fn != nil {
+		return s.fn(key, value)
+	}
+	return value
+}
+
+// scrub returns a copy of value in which the value of every sensitive map
+// key, at any depth, has been scrubbed. Maps and slices are copied rather
+// than modified so that data owned by the caller (custom data, request
+// headers, etc.) is left untouched. Values of other types are returned as
+// they are.
+func (s *scrubber) scrub(value interface{}) interface{} {
+	if len(s.patterns) == 0 && s.fn == nil {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			item = s.field(key, item)
+			if item != FILTERED {
+				item = s.scrub(item)
+			}
+			result[key] = item
+		}
+		return result
+	case map[string]string:
+		result := make(map[string]string, len(v))
+		for key, item := range v {
+			if scrubbed, ok := s.field(key, item).(string); ok {
+				result[key] = scrubbed
+			} else {
+				result[key] = FILTERED
+			}
+		}
+		return result
+	case map[string][]string:
+		return s.values(v)
+	case http.Header:
+		return http.Header(s.values(v))
+	case url.Values:
+		return url.Values(s.values(v))
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = s.scrub(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// values scrubs a copy of the given multi-valued map, such as request
+// headers or params.
+func (s *scrubber) values(values map[string][]string) map[string][]string {
+	result := make(map[string][]string, len(values))
+	for key, items := range values {
+		if scrubbed, ok := s.field(key, items).([]string); ok {
+			result[key] = scrubbed
+		} else {
+			result[key] = []string{FILTERED}
+		}
+	}
+	return result
+}
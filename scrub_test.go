@@ -0,0 +1,118 @@
+package rollbar
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestScrub(t *testing.T) {
+	s := newScrubber(defaultConfiguration(""))
+	custom := map[string]interface{}{
+		"order_id": 42,
+		"Password": "hunter2",
+		"nested": map[string]interface{}{
+			"api_token": "abc",
+			"list":      []interface{}{map[string]interface{}{"client_secret": "xyz"}},
+		},
+	}
+	headers := http.Header{
+		"Authorization": []string{"Bearer abc"},
+		"Accept":        []string{"*/*"},
+	}
+
+	scrubbed := s.scrub(map[string]interface{}{
+		"custom":  custom,
+		"headers": headers,
+	}).(map[string]interface{})
+
+	clean := scrubbed["custom"].(map[string]interface{})
+	if clean["order_id"] != 42 {
+		t.Errorf("got order_id: %v", clean["order_id"])
+	}
+	if clean["Password"] != FILTERED {
+		t.Errorf("got Password: %v", clean["Password"])
+	}
+	nested := clean["nested"].(map[string]interface{})
+	if nested["api_token"] != FILTERED {
+		t.Errorf("got api_token: %v", nested["api_token"])
+	}
+	if nested["list"].([]interface{})[0].(map[string]interface{})["client_secret"] != FILTERED {
+		t.Error("should scrub maps inside slices")
+	}
+
+	cleanHeaders := scrubbed["headers"].(http.Header)
+	if cleanHeaders.Get("Authorization") != FILTERED {
+		t.Errorf("got Authorization: %v", cleanHeaders.Get("Authorization"))
+	}
+	if cleanHeaders.Get("Accept") != "*/*" {
+		t.Errorf("got Accept: %v", cleanHeaders.Get("Accept"))
+	}
+
+	if custom["Password"] != "hunter2" || headers.Get("Authorization") != "Bearer abc" {
+		t.Error("should not modify the original data")
+	}
+}
+
+func TestScrubFieldsOption(t *testing.T) {
+	client := New("token", WithScrubFields("ssn"))
+	pattern := client.snapshot().filterFields
+
+	if !pattern.MatchString("customer_SSN") {
+		t.Error("should match configured field")
+	}
+	if pattern.MatchString("password") {
+		t.Error("should replace the default fields")
+	}
+	if New("token", WithScrubFields()).snapshot().filterFields != nil {
+		t.Error("should scrub nothing when given no fields")
+	}
+}
+
+func TestScrubPatterns(t *testing.T) {
+	client := New("token", WithScrubPatterns(regexp.MustCompile(`(?i)api[-_]?key`)))
+	s := newScrubber(client.snapshot())
+
+	clean := s.scrub(map[string]interface{}{
+		"X-Api-Key": "abc",
+		"apikey":    "abc",
+		"password":  "hunter2",
+		"name":      "alice",
+	}).(map[string]interface{})
+
+	for _, key := range []string{"X-Api-Key", "apikey", "password"} {
+		if clean[key] != FILTERED {
+			t.Errorf("should scrub %s, got %v", key, clean[key])
+		}
+	}
+	if clean["name"] != "alice" {
+		t.Errorf("got name: %v", clean["name"])
+	}
+}
+
+func TestScrubFunc(t *testing.T) {
+	client := New("token", WithScrubFunc(func(key string, value interface{}) interface{} {
+		if s, ok := value.(string); ok && strings.HasPrefix(s, "4111") {
+			return "card ending " + s[len(s)-4:]
+		}
+		return value
+	}))
+	s := newScrubber(client.snapshot())
+
+	clean := s.scrub(map[string]interface{}{
+		"card":     "4111111111111111",
+		"password": "4111",
+		"other":    "hello",
+	}).(map[string]interface{})
+
+	if clean["card"] != "card ending 1111" {
+		t.Errorf("got card: %v", clean["card"])
+	}
+	if clean["password"] != FILTERED {
+		t.Errorf("got password: %v", clean["password"])
+	}
+	if clean["other"] != "hello" {
+		t.Errorf("got other: %v", clean["other"])
+	}
+}
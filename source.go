@@ -0,0 +1,98 @@
+package rollbar
+
+import (
+	"bytes"
+	"container/list"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// sourceCacheSize is how many bytes of source files are kept in memory to
+// read the code of stack frames.
+const sourceCacheSize = 8 << 20
+
+// sources caches the source files read for stack frames, so that error
+// storms don't read the same files for every frame of every item.
+var sources = newSourceCache(sourceCacheSize)
+
+// sourceCache is an LRU cache of the lines of source files, bounded by their
+// total size. Files are read again when their size or modification time
+// changes.
+type sourceCache struct {
+	mutex    sync.Mutex
+	maxBytes int64
+	size     int64
+	lru      *list.List // of *sourceFile, most recently used first
+	files    map[string]*list.Element
+}
+
+type sourceFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	lines   [][]byte
+}
+
+func newSourceCache(maxBytes int64) *sourceCache {
+	return &sourceCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		files:    make(map[string]*list.Element),
+	}
+}
+
+// lines returns the lines of the given file.
+func (cache *sourceCache) lines(path string) ([][]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mutex.Lock()
+	if element, ok := cache.files[path]; ok {
+		file := element.Value.(*sourceFile)
+		if file.size == info.Size() && file.modTime.Equal(info.ModTime()) {
+			cache.lru.MoveToFront(element)
+			cache.mutex.Unlock()
+			return file.lines, nil
+		}
+		cache.remove(element)
+	}
+	cache.mutex.Unlock()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := bytes.Split(data, []byte{'\n'})
+	cache.add(&sourceFile{path, info.Size(), info.ModTime(), lines})
+	return lines, nil
+}
+
+// add caches the given file, evicting the least recently used files to make
+// room for it. Files larger than the cache are not cached.
+func (cache *sourceCache) add(file *sourceFile) {
+	if file.size > cache.maxBytes {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if element, ok := cache.files[file.path]; ok {
+		cache.remove(element)
+	}
+	for cache.size+file.size > cache.maxBytes {
+		cache.remove(cache.lru.Back())
+	}
+	cache.files[file.path] = cache.lru.PushFront(file)
+	cache.size += file.size
+}
+
+func (cache *sourceCache) remove(element *list.Element) {
+	file := cache.lru.Remove(element).(*sourceFile)
+	delete(cache.files, file.path)
+	cache.size -= file.size
+}
@@ -0,0 +1,70 @@
+package rollbar
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeSource(t *testing.T, path, source string, modTime time.Time) {
+	if err := ioutil.WriteFile(path, []byte(source), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSourceCache(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	now := time.Now()
+	writeSource(t, a, "package a\n", now)
+	writeSource(t, b, "package b\n", now)
+
+	cache := newSourceCache(15)
+	if lines, err := cache.lines(a); err != nil || string(lines[0]) != "package a" {
+		t.Fatalf("got %q, %v", lines, err)
+	}
+	if _, ok := cache.files[a]; !ok {
+		t.Fatal("should cache files")
+	}
+
+	cache.lines(b)
+	if _, ok := cache.files[a]; ok || cache.size != 10 {
+		t.Errorf("should evict the least recently used file, got %d bytes", cache.size)
+	}
+
+	writeSource(t, b, "package bb\n", now.Add(time.Second))
+	if lines, _ := cache.lines(b); string(lines[0]) != "package bb" {
+		t.Errorf("should read modified files again, got %q", lines)
+	}
+
+	if _, err := cache.lines(filepath.Join(dir, "missing.go")); err == nil {
+		t.Error("should fail to read missing files")
+	}
+}
+
+func TestSourceRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "internal"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeSource(t, filepath.Join(root, "internal", "db.go"), "package internal\n\nfunc query() {}\n", time.Now())
+
+	opts := stackOptions{sourceRoots: []sourceRoot{
+		{root: "/nonexistent", prefix: "example.com/other"},
+		{root: root, prefix: "/builds/acme/api/"},
+	}}
+	frame := opts.newFrame("/builds/acme/api/internal/db.go", "internal.query", 3)
+	if frame.Filename != "internal/db.go" || frame.Code != "func query() {}" {
+		t.Errorf("got %+v", frame)
+	}
+
+	frame = opts.newFrame("/builds/acme/apiserver/main.go", "main.main", 3)
+	if frame.Filename != "/builds/acme/apiserver/main.go" {
+		t.Errorf("should not relocate other files, got %+v", frame)
+	}
+}
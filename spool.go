@@ -0,0 +1,156 @@
+package rollbar
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// spool is a bounded on-disk queue of encoded items that could not be
+// delivered because the network or Rollbar was unavailable. Each item is a
+// file in dir; file names sort in the order the items were spooled. When the
+// files take more than maxBytes, the oldest ones are removed.
+//
+// Spooled files contain access tokens, so they are only readable by their
+// owner.
+type spool struct {
+	dir      string
+	maxBytes int64
+
+	mutex     sync.Mutex
+	seq       uint64
+	replaying int32
+
+	// unsent is 1 while the spool may hold items, so that deliveries only
+	// replay it then. It starts at 1, for the items of previous processes.
+	unsent int32
+}
+
+const spoolExt = ".json"
+
+// errNoSpool is returned by ReplaySpool for a Client without a spool.
+var errNoSpool = errors.New("rollbar: client has no spool")
+
+func newSpool(dir string, maxBytes int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &spool{dir: dir, maxBytes: maxBytes, unsent: 1}, nil
+}
+
+// write adds an encoded item to the spool.
+func (s *spool) write(jsonBody []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.seq++
+	name := fmt.Sprintf("%020d-%010d%s", time.Now().UnixNano(), s.seq, spoolExt)
+	if err := ioutil.WriteFile(filepath.Join(s.dir, name), jsonBody, 0600); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&s.unsent, 1)
+
+	return s.trim()
+}
+
+// files returns the paths of all spooled items, oldest first.
+func (s *spool) files() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), spoolExt) {
+			files = append(files, filepath.Join(s.dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// trim removes the oldest spooled items until the spool fits in maxBytes.
+func (s *spool) trim() error {
+	if s.maxBytes <= 0 {
+		return nil
+	}
+
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	sizes := make([]int64, len(files))
+	var total int64
+	for i, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			sizes[i] = info.Size()
+			total += sizes[i]
+		}
+	}
+
+	for i := 0; total > s.maxBytes && i < len(files); i++ {
+		os.Remove(files[i])
+		total -= sizes[i]
+	}
+	return nil
+}
+
+// replay POSTs spooled items, oldest first, with the given function. Items
+// that are delivered, or that Rollbar rejects for good, are removed. Replay
+// stops at the first transient failure, leaving the remaining items for
+// later. Only one replay runs at a time; concurrent calls return right away.
+func (s *spool) replay(post func(jsonBody []byte) error) {
+	if !atomic.CompareAndSwapInt32(&s.replaying, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&s.replaying, 0)
+
+	// Items written from now on are left for the next replay.
+	atomic.StoreInt32(&s.unsent, 0)
+	files, err := s.files()
+	if err != nil {
+		atomic.StoreInt32(&s.unsent, 1)
+		return
+	}
+
+	for _, file := range files {
+		jsonBody, err := ioutil.ReadFile(file)
+		if err != nil {
+			atomic.StoreInt32(&s.unsent, 1)
+			continue
+		}
+		if err := post(jsonBody); err != nil && retryable(err) {
+			atomic.StoreInt32(&s.unsent, 1)
+			return
+		}
+		os.Remove(file)
+	}
+}
+
+// holdsItems reports whether the spool may hold items to replay.
+func (s *spool) holdsItems() bool {
+	return atomic.LoadInt32(&s.unsent) == 1
+}
+
+// ReplaySpool POSTs the items saved to the Client's spool (see WithSpool)
+// right away, rather than after the next successful delivery, and returns the
+// number of items left in the spool, because a delivery failed. Spooled items
+// carry their own access token, so a Client can replay the spool of another
+// process, such as one that exited before being able to deliver them.
+func (c *Client) ReplaySpool() (int, error) {
+	if c.spool == nil {
+		return 0, errNoSpool
+	}
+	c.spool.replay(c.replayPost)
+	files, err := c.spool.files()
+	return len(files), err
+}
@@ -0,0 +1,132 @@
+package rollbar
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func tempSpool(t *testing.T, maxBytes int64) (*spool, func()) {
+	dir, err := ioutil.TempDir("", "rollbar-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := newSpool(dir, maxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, func() { os.RemoveAll(dir) }
+}
+
+func TestSpoolTrim(t *testing.T) {
+	s, cleanup := tempSpool(t, 10)
+	defer cleanup()
+
+	s.write([]byte("aaaa"))
+	s.write([]byte("bbbb"))
+	s.write([]byte("cccc"))
+
+	files, _ := s.files()
+	if len(files) != 2 {
+		t.Fatalf("got %d files", len(files))
+	}
+	first, _ := ioutil.ReadFile(files[0])
+	if string(first) != "bbbb" {
+		t.Errorf("should drop the oldest item, got %s", first)
+	}
+}
+
+func TestSpoolReplay(t *testing.T) {
+	s, cleanup := tempSpool(t, 0)
+	defer cleanup()
+
+	s.write([]byte("1"))
+	s.write([]byte("2"))
+	s.write([]byte("3"))
+
+	var replayed []string
+	s.replay(func(jsonBody []byte) error {
+		replayed = append(replayed, string(jsonBody))
+		if len(replayed) == 2 {
+			return ErrHTTPError(503)
+		}
+		return nil
+	})
+
+	if len(replayed) != 2 || replayed[0] != "1" {
+		t.Errorf("got %v", replayed)
+	}
+	files, _ := s.files()
+	if len(files) != 2 {
+		t.Errorf("should keep items that failed to replay, got %d files", len(files))
+	}
+}
+
+func TestClientSpoolsUndeliveredItems(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rollbar-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var up int32
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&up) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		atomic.AddInt32(&received, 1)
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil),
+		WithRetries(0), WithCircuitBreaker(0, 0), WithSpool(dir, 0))
+
+	client.Message(INFO, "offline 1")
+	client.Message(INFO, "offline 2")
+	if files, _ := client.spool.files(); len(files) != 2 {
+		t.Fatalf("got %d spooled items", len(files))
+	}
+
+	atomic.StoreInt32(&up, 1)
+	client.Message(INFO, "online")
+	if got := atomic.LoadInt32(&received); got != 3 {
+		t.Errorf("got %d items", got)
+	}
+	if files, _ := client.spool.files(); len(files) != 0 {
+		t.Errorf("got %d spooled items", len(files))
+	}
+	if client.spool.holdsItems() {
+		t.Error("should not replay an empty spool after every delivery")
+	}
+}
+
+func TestReplaySpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rollbar-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	offline := New("token", WithEndpoint("http://127.0.0.1:1"), WithSync(), WithErrorWriter(nil),
+		WithRetries(0), WithCircuitBreaker(0, 0), WithSpool(dir, 0))
+	offline.Message(INFO, "offline")
+
+	transport := &fakeTransport{}
+	replayer := New("other-token", WithTransport(transport), WithErrorWriter(nil), WithSpool(dir, 0))
+	left, err := replayer.ReplaySpool()
+	if err != nil || left != 0 || len(transport.payloads) != 1 {
+		t.Fatalf("got %d left, %v, %d payloads", left, err, len(transport.payloads))
+	}
+	if !strings.Contains(string(transport.payloads[0]), `"access_token":"token"`) {
+		t.Errorf("should replay items with their own token, got %s", transport.payloads[0])
+	}
+
+	if _, err := New("token").ReplaySpool(); err != errNoSpool {
+		t.Errorf("got %v", err)
+	}
+}
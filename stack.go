@@ -2,71 +2,431 @@ package rollbar
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"hash/crc32"
-	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
+	"sync"
+	"unicode"
 )
 
+// maxFingerprintLength is the longest fingerprint that Rollbar accepts.
+const maxFingerprintLength = 40
+
 var (
-	knownFilePathPatterns = []string{
-		runtime.GOROOT() + "/",
+	// DefaultFilePathPatterns are the patterns that file paths are shortened
+	// to by default; see ShortenFilePath.
+	DefaultFilePathPatterns = []string{
+		slashPath(runtime.GOROOT()) + "/",
 		"github.com/",
 		"code.google.com/",
 		"bitbucket.org/",
 		"launchpad.net/",
 	}
+
+	// filePaths holds how file paths are shortened, as set by
+	// SetFilePathPatterns and SetFilePathShortener.
+	filePaths struct {
+		sync.RWMutex
+		patterns  []string
+		shortener func(path string) string
+	}
 )
 
 func init() {
-	gopath := os.Getenv("GOPATH")
-	if gopath != "" {
-		knownFilePathPatterns = append(knownFilePathPatterns, gopath)
+	filePaths.patterns = DefaultFilePathPatterns
+	for _, gopath := range filepath.SplitList(os.Getenv("GOPATH")) {
+		filePaths.patterns = append(filePaths.patterns, slashPath(gopath))
 	}
 }
 
+// AddFilePathPatterns adds patterns that file paths of stack traces are
+// shortened to, such as the host of a private VCS ("git.corp.example.com/"),
+// to those tried by ShortenFilePath. Patterns added last are tried last.
+func AddFilePathPatterns(patterns ...string) {
+	filePaths.Lock()
+	defer filePaths.Unlock()
+
+	current := filePaths.patterns
+	filePaths.patterns = make([]string, 0, len(current)+len(patterns))
+	filePaths.patterns = append(append(filePaths.patterns, current...), patterns...)
+}
+
+// SetFilePathPatterns replaces the patterns that file paths of stack traces
+// are shortened to by ShortenFilePath, DefaultFilePathPatterns and $GOPATH by
+// default.
+func SetFilePathPatterns(patterns ...string) {
+	filePaths.Lock()
+	defer filePaths.Unlock()
+
+	filePaths.patterns = append([]string(nil), patterns...)
+}
+
+// SetFilePathShortener sets a function that shortens the file paths of stack
+// traces instead of ShortenFilePath, which it can call for the paths it
+// leaves alone. A nil function restores ShortenFilePath. File paths are
+// shortened as stack traces are built, so it applies to every Client.
+func SetFilePathShortener(shortener func(path string) string) {
+	filePaths.Lock()
+	defer filePaths.Unlock()
+
+	filePaths.shortener = shortener
+}
+
 // Frame is a single line of executed code in a Stack.
 type Frame struct {
 	Filename string `json:"filename"`
 	Method   string `json:"method"`
 	Line     int    `json:"lineno"`
 	Code     string `json:"code,omitempty"`
+
+	// Context holds the source lines around Code, if the Client is
+	// configured to report them (see WithContextLines).
+	Context *FrameContext `json:"context,omitempty"`
+
+	// source is the path of the file whose source is read when the frame is
+	// reported, as configured by the Client, for frames built without it,
+	// such as those of parsed stacks.
+	source string
+}
+
+// FrameContext holds the source lines before and after the line of a Frame.
+type FrameContext struct {
+	Pre  []string `json:"pre,omitempty"`
+	Post []string `json:"post,omitempty"`
 }
 
 // NewFrame creates a new Frame with the filename shortened in the same way as it
 // would be when using BuildStack
 func NewFrame(file, method string, line int) Frame {
-	code, _ := sourceLine(file, line)
-	return Frame{shortenFilePath(file), method, line, code}
+	return stackOptions{}.newFrame(file, method, line)
+}
+
+// stackOptions controls how a Client captures stack traces.
+type stackOptions struct {
+	// maxDepth is the largest number of frames captured, or 0 for no limit;
+	// see elideFrames.
+	maxDepth int
+
+	// wrappers are the import paths of packages whose frames are left out of
+	// the top of stack traces; see skipWrapperFrames.
+	wrappers []string
+
+	// contextLines is how many source lines before and after the line of a
+	// frame are captured.
+	contextLines int
+
+	// skipCode leaves out the source of frames, without reading it.
+	skipCode bool
+
+	// sourceRoots relocate the source files of frames; see WithSourceRoot.
+	sourceRoots []sourceRoot
+}
+
+// sourceRoot maps source files built under prefix to root, where they are at
+// run time.
+type sourceRoot struct {
+	root   string
+	prefix string
+}
+
+// newFrame creates a new Frame, reading its source unless told not to. Files
+// under the prefix of a source root are read from the root, and reported
+// relative to it.
+func (opts stackOptions) newFrame(file, method string, line int) Frame {
+	frame := Frame{Filename: shortenFilePath(file), Method: method, Line: line}
+	source := file
+	for _, sourceRoot := range opts.sourceRoots {
+		if rel, ok := relativePath(file, sourceRoot.prefix); ok {
+			frame.Filename = rel
+			source = filepath.Join(sourceRoot.root, filepath.FromSlash(rel))
+			break
+		}
+	}
+	if opts.skipCode {
+		return frame
+	}
+	var pre, post []string
+	frame.Code, pre, post, _ = sourceContext(source, line, opts.contextLines)
+	if len(pre) > 0 || len(post) > 0 {
+		frame.Context = &FrameContext{Pre: pre, Post: post}
+	}
+	return frame
+}
+
+// withSource returns stack, or a copy of it in which the frames whose source
+// is read when they are reported have it, as configured.
+func (opts stackOptions) withSource(stack Stack) Stack {
+	var sourced Stack
+	for i, frame := range stack {
+		if frame.source == "" {
+			continue
+		}
+		if sourced == nil {
+			sourced = append(Stack(nil), stack...)
+		}
+		sourced[i] = opts.newFrame(frame.source, frame.Method, frame.Line)
+	}
+	if sourced == nil {
+		return stack
+	}
+	return sourced
 }
 
 // Stack represents a stacktrace as a slice of Frames.
 type Stack []Frame
 
 // BuildStack builds a full stacktrace for the current execution location.
+// Frames are resolved with runtime.CallersFrames, so that inlined functions
+// are reported as the functions they were inlined from.
+//
+// Frames of this package at the top of the stack are left out, so that the
+// innermost frame is where the error happened even when reported through
+// helpers layered on top of the package.
 func BuildStack(skip int) Stack {
-	stack := make(Stack, 0)
+	return buildStack(skip+1, stackOptions{})
+}
 
-	for i := skip; ; i++ {
-		pc, file, line, ok := runtime.Caller(i)
-		if !ok {
-			break
+// buildStack is like BuildStack, but captures the stack as configured by the
+// given options. Frames beyond the maximum depth are elided before their
+// source is read.
+func buildStack(skip int, opts stackOptions) Stack {
+	stack := stackFromPCs(callers(skip), opts)
+	if stack == nil {
+		stack = make(Stack, 0)
+	}
+	return stack
+}
+
+// callers returns the return program counters of the current goroutine's
+// stack, skipping skip frames: skip 0 is the caller of callers, as with
+// runtime.Caller.
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 64)
+	for {
+		// +2 for runtime.Callers and callers itself.
+		n := runtime.Callers(skip+2, pcs)
+		if n < len(pcs) {
+			return pcs[:n]
 		}
+		pcs = make([]uintptr, 2*len(pcs))
+	}
+}
+
+// stackFromPCs builds a Stack from return program counters, as returned by
+// runtime.Callers, or nil if there are none, as configured by the given
+// options. Frames of this package at the top of the stack are always left out.
+func stackFromPCs(pcs []uintptr, opts stackOptions) Stack {
+	if len(pcs) == 0 {
+		return nil
+	}
 
-		code, _ := sourceLine(file, line)
-		file = shortenFilePath(file)
-		stack = append(stack, Frame{file, functionName(pc), line, code})
+	var frames []runtime.Frame
+	callers := runtime.CallersFrames(pcs)
+	for {
+		frame, more := callers.Next()
+		if frame.Function != "" || frame.File != "" {
+			learnMainModuleDir(frame)
+			frames = append(frames, frame)
+		}
+		if !more {
+			break
+		}
 	}
+	frames = skipWrapperFrames(frames, opts.wrappers)
 
+	head, tail := elision(len(frames), opts.maxDepth)
+	stack := make(Stack, 0, head+tail+1)
+	for _, frame := range frames[:head] {
+		stack = append(stack, opts.newFrame(frame.File, shortFunctionName(frame.Function), frame.Line))
+	}
+	if elided := len(frames) - head - tail; elided > 0 {
+		stack = append(stack, elisionFrame(elided))
+	}
+	for _, frame := range frames[len(frames)-tail:] {
+		stack = append(stack, opts.newFrame(frame.File, shortFunctionName(frame.Function), frame.Line))
+	}
 	return stack
 }
 
+// relativePath returns the path of file relative to the given directory, if
+// file is under it.
+func relativePath(file, dir string) (string, bool) {
+	file, dir = slashPath(file), strings.TrimSuffix(slashPath(dir), "/")
+	if !strings.HasPrefix(file, dir+"/") {
+		return "", false
+	}
+	return file[len(dir)+1:], true
+}
+
+// packagePath is the import path of this package.
+var packagePath = reflect.TypeOf(Client{}).PkgPath()
+
+// skipWrapperFrames leaves out the frames at the top of the given stack that
+// belong to this package or its sub-packages, the runtime or one of the given
+// wrapper packages (or their sub-packages), unless every frame does. Frames of
+// test files are kept, so that the packages' own tests see their frames.
+func skipWrapperFrames(frames []runtime.Frame, wrappers []string) []runtime.Frame {
+	for i, frame := range frames {
+		if !isWrapperFrame(frame, wrappers) {
+			return frames[i:]
+		}
+	}
+	return frames
+}
+
+func isWrapperFrame(frame runtime.Frame, wrappers []string) bool {
+	if strings.HasSuffix(frame.File, "_test.go") {
+		return false
+	}
+	pkg := functionPackage(frame.Function)
+	if pkg == packagePath || strings.HasPrefix(pkg, packagePath+"/") || pkg == "runtime" {
+		// runtime frames at the top are those of a panic being raised.
+		return true
+	}
+	for _, wrapper := range wrappers {
+		if pkg == wrapper || strings.HasPrefix(pkg, wrapper+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// functionPackage returns the import path of the package of a fully qualified
+// function name, such as "github.com/stvp/rollbar.(*Client).Error".
+func functionPackage(name string) string {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot != -1 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+// elideFrames returns the given stack if it is at most maxDepth frames deep,
+// or if maxDepth is 0. Otherwise, it returns the innermost and outermost frames
+// of the stack around a frame saying how many frames were elided in between,
+// maxDepth frames in all.
+func elideFrames(stack Stack, maxDepth int) Stack {
+	head, tail := elision(len(stack), maxDepth)
+	elided := len(stack) - head - tail
+	if elided == 0 {
+		return stack
+	}
+
+	elidedStack := make(Stack, 0, head+tail+1)
+	elidedStack = append(elidedStack, stack[:head]...)
+	elidedStack = append(elidedStack, elisionFrame(elided))
+	return append(elidedStack, stack[len(stack)-tail:]...)
+}
+
+// elision returns how many of the innermost (head) and outermost (tail)
+// frames of a stack of the given depth are kept when limiting it to maxDepth
+// frames, one of which says how many were elided.
+func elision(depth, maxDepth int) (head, tail int) {
+	if maxDepth <= 0 || depth <= maxDepth {
+		return depth, 0
+	}
+	tail = (maxDepth - 1) / 2
+	return maxDepth - 1 - tail, tail
+}
+
+// elisionFrame is the frame that stands for frames left out of a Stack.
+func elisionFrame(elided int) Frame {
+	return Frame{Filename: "...", Method: fmt.Sprintf("(%d frames elided)", elided)}
+}
+
+const (
+	// maxRecursionCycle is the largest number of frames of a cycle that
+	// collapseRecursion looks for, as in mutual recursion.
+	maxRecursionCycle = 16
+
+	// minRecursionRepeats is the fewest repetitions of a cycle that
+	// collapseRecursion collapses.
+	minRecursionRepeats = 3
+)
+
+// collapseRecursion collapses the cycles of frames repeated back to back in
+// the given stack, as in deep recursion, into a single copy of the cycle
+// followed by a frame saying how many more times it was repeated. Cycles
+// repeated fewer than minRecursionRepeats times are left alone.
+func collapseRecursion(stack Stack) Stack {
+	var collapsed Stack
+	for i := 0; i < len(stack); {
+		cycle, repeats := recursionAt(stack, i)
+		if repeats < minRecursionRepeats {
+			if collapsed != nil {
+				collapsed = append(collapsed, stack[i])
+			}
+			i++
+			continue
+		}
+
+		if collapsed == nil {
+			collapsed = append(make(Stack, 0, len(stack)), stack[:i]...)
+		}
+		collapsed = append(collapsed, stack[i:i+cycle]...)
+		collapsed = append(collapsed, recursionFrame(cycle, repeats-1))
+		i += cycle * repeats
+	}
+	if collapsed == nil {
+		return stack
+	}
+	return collapsed
+}
+
+// recursionAt returns the length of the cycle of frames starting at the
+// given index that covers the most frames, and how many times it is
+// repeated back to back.
+func recursionAt(stack Stack, start int) (cycle, repeats int) {
+	cycle, repeats = 1, 1
+	for length := 1; length <= maxRecursionCycle && start+2*length <= len(stack); length++ {
+		n := 1
+		for next := start + length; next+length <= len(stack) && sameFrames(stack[start:start+length], stack[next:next+length]); next += length {
+			n++
+		}
+		if n*length > cycle*repeats && n > 1 {
+			cycle, repeats = length, n
+		}
+	}
+	return cycle, repeats
+}
+
+// sameFrames reports whether two runs of frames are of the same lines of the
+// same functions.
+func sameFrames(a, b Stack) bool {
+	for i := range a {
+		if a[i].Filename != b[i].Filename || a[i].Method != b[i].Method || a[i].Line != b[i].Line {
+			return false
+		}
+	}
+	return true
+}
+
+// recursionFrame is the frame that stands for the repetitions of a cycle of
+// frames left out of a Stack.
+func recursionFrame(cycle, repeats int) Frame {
+	return Frame{Filename: "...", Method: fmt.Sprintf("(%d frames repeated %d more times)", cycle, repeats)}
+}
+
 // Fingerprint builds a string that uniquely identifies a Rollbar item using
 // the full stacktrace. The fingerprint is used to ensure (to a reasonable
 // degree) that items are coalesced by Rollbar in a smart way.
 func (s Stack) Fingerprint() string {
+	hash := crc32.NewIEEE()
+	for _, frame := range s {
+		fmt.Fprintf(hash, "%s%s%d", frame.Filename, frame.Method, frame.Line)
+	}
+	return fmt.Sprintf("%x", hash.Sum32())
+}
+
+// fingerprintWithoutLines is like Fingerprint, but leaves line numbers out,
+// so that edits that only move code around don't change the fingerprint.
+func (s Stack) fingerprintWithoutLines() string {
 	hash := crc32.NewIEEE()
 	for _, frame := range s {
 		fmt.Fprintf(hash, "%s%s", frame.Filename, frame.Method)
@@ -74,19 +434,62 @@ func (s Stack) Fingerprint() string {
 	return fmt.Sprintf("%x", hash.Sum32())
 }
 
-// Remove un-needed information from the source file path. This makes them
-// shorter in Rollbar UI as well as making them the same, regardless of the
-// machine the code was compiled on.
+// sha256Fingerprint is like Fingerprint, but uses SHA-256, which unlike CRC32
+// doesn't make unrelated stack traces of large projects collide, and
+// separates the fields of frames so that they can't run into each other. Line
+// numbers are left out unless lines is true. The fingerprint is truncated to
+// the longest that Rollbar accepts.
+func (s Stack) sha256Fingerprint(lines bool) string {
+	hash := sha256.New()
+	for _, frame := range s {
+		if lines {
+			fmt.Fprintf(hash, "%s\x00%s\x00%d\n", frame.Filename, frame.Method, frame.Line)
+		} else {
+			fmt.Fprintf(hash, "%s\x00%s\n", frame.Filename, frame.Method)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:maxFingerprintLength]
+}
+
+// shortenFilePath shortens the given file path with the function set by
+// SetFilePathShortener, or with ShortenFilePath.
+func shortenFilePath(s string) string {
+	filePaths.RLock()
+	shortener := filePaths.shortener
+	filePaths.RUnlock()
+
+	if shortener != nil {
+		return shortener(s)
+	}
+	return ShortenFilePath(s)
+}
+
+// ShortenFilePath removes un-needed information from the source file path.
+// This makes them shorter in Rollbar UI as well as making them the same,
+// regardless of the machine the code was compiled on. Paths are shortened to
+// the first pattern (see SetFilePathPatterns) they contain.
 //
 // Examples:
 //   /usr/local/go/src/pkg/runtime/proc.c -> pkg/runtime/proc.c
 //   /home/foo/go/src/github.com/rollbar/rollbar.go -> github.com/rollbar/rollbar.go
-func shortenFilePath(s string) string {
+//
+// Files of the module cache are shortened to their module path, and files of
+// the main module to their path in it (see shortenModulePath).
+func ShortenFilePath(s string) string {
+	s = slashPath(s)
 	idx := strings.Index(s, "/src/pkg/")
 	if idx != -1 {
 		return s[idx+5:]
 	}
-	for _, pattern := range knownFilePathPatterns {
+	if short, ok := shortenModulePath(s); ok {
+		return short
+	}
+
+	filePaths.RLock()
+	patterns := filePaths.patterns
+	filePaths.RUnlock()
+
+	for _, pattern := range patterns {
 		idx = strings.Index(s, pattern)
 		if idx != -1 {
 			return s[idx:]
@@ -95,27 +498,55 @@ func shortenFilePath(s string) string {
 	return s
 }
 
-func functionName(pc uintptr) string {
-	fn := runtime.FuncForPC(pc)
-	if fn == nil {
+// slashPath returns the given path with slashes rather than backslashes if it
+// is a Windows path, such as C:\Users\foo\go or a UNC path, whatever the
+// OS: file paths recorded in binaries use slashes on every OS, but
+// environment variables and configuration may not.
+func slashPath(path string) string {
+	isWindows := strings.HasPrefix(path, `\\`) ||
+		len(path) >= 3 && path[1] == ':' && (path[2] == '\\' || path[2] == '/') && unicode.IsLetter(rune(path[0]))
+	if !isWindows {
+		return path
+	}
+	return strings.Replace(path, `\`, "/", -1)
+}
+
+// shortFunctionName strips the package path from a fully qualified function
+// name. Import paths are always separated by slashes, whatever the OS.
+func shortFunctionName(name string) string {
+	if name == "" {
 		return "???"
 	}
-	name := fn.Name()
-	end := strings.LastIndex(name, string(os.PathSeparator))
+	end := strings.LastIndex(name, "/")
 	return name[end+1 : len(name)]
 }
 
-func sourceLine(file string, lineNumber int) (string, error) {
-	data, err := ioutil.ReadFile(file)
+// sourceContext returns the given line of a source file, along with up to
+// context lines before (pre) and after (post) it. Source files are cached.
+func sourceContext(file string, lineNumber, context int) (code string, pre, post []string, err error) {
+	lines, err := sources.lines(file)
 
 	if err != nil {
-		return "", err
+		return "", nil, nil, err
 	}
 
-	lines := bytes.Split(data, []byte{'\n'})
 	if lineNumber <= 0 || lineNumber >= len(lines) {
-		return "???", nil
+		return "???", nil, nil, nil
 	}
 	// -1 because line-numbers are 1 based, but our array is 0 based
-	return string(bytes.Trim(lines[lineNumber-1], " \t")), nil
+	index := lineNumber - 1
+	code = string(bytes.Trim(lines[index], " \t"))
+	if context <= 0 {
+		return code, nil, nil, nil
+	}
+
+	for i := index - context; i < index; i++ {
+		if i >= 0 {
+			pre = append(pre, string(bytes.TrimRight(lines[i], "\r")))
+		}
+	}
+	for i := index + 1; i <= index+context && i < len(lines); i++ {
+		post = append(post, string(bytes.TrimRight(lines[i], "\r")))
+	}
+	return code, pre, post, nil
 }
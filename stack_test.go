@@ -1,6 +1,10 @@
 package rollbar
 
 import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -12,11 +16,26 @@ func TestBuildStack(t *testing.T) {
 	if frame.Method != "rollbar.TestBuildStack" {
 		t.Errorf("got: %s", frame.Method)
 	}
-	if frame.Line != 8 {
+	if frame.Line != 12 {
 		t.Errorf("got: %d", frame.Line)
 	}
 }
 
+// inlined is small enough for the compiler to inline it.
+func inlined() Stack {
+	return BuildStack(1)
+}
+
+func TestBuildStackInlined(t *testing.T) {
+	stack := inlined()
+	if len(stack) < 2 {
+		t.Fatalf("got %+v", stack)
+	}
+	if stack[0].Method != "rollbar.inlined" || stack[1].Method != "rollbar.TestBuildStackInlined" {
+		t.Errorf("should report inlined functions, got %s, %s", stack[0].Method, stack[1].Method)
+	}
+}
+
 func TestStackFingerprint(t *testing.T) {
 	tests := []struct {
 		Fingerprint string
@@ -25,20 +44,20 @@ func TestStackFingerprint(t *testing.T) {
 		{
 			"9344290d",
 			Stack{
-				Frame{"foo.go", "Oops", 1},
+				Frame{Filename: "foo.go", Method: "Oops", Line: 1},
 			},
 		},
 		{
 			"a4d78b7",
 			Stack{
-				Frame{"foo.go", "Oops", 2},
+				Frame{Filename: "foo.go", Method: "Oops", Line: 2},
 			},
 		},
 		{
 			"50e0fcb3",
 			Stack{
-				Frame{"foo.go", "Oops", 1},
-				Frame{"foo.go", "Oops", 2},
+				Frame{Filename: "foo.go", Method: "Oops", Line: 1},
+				Frame{Filename: "foo.go", Method: "Oops", Line: 2},
 			},
 		},
 	}
@@ -68,3 +87,224 @@ func TestShortenFilePath(t *testing.T) {
 		}
 	}
 }
+
+func TestFilePathPatterns(t *testing.T) {
+	filePaths.RLock()
+	patterns := filePaths.patterns
+	filePaths.RUnlock()
+	defer SetFilePathPatterns(patterns...)
+
+	path := "/home/foo/src/git.corp.example.com/payments/api.go"
+	AddFilePathPatterns("git.corp.example.com/")
+	if got := shortenFilePath(path); got != "git.corp.example.com/payments/api.go" {
+		t.Errorf("got %s", got)
+	}
+	if got := shortenFilePath("/home/foo/go/src/github.com/stvp/rollbar.go"); got != "github.com/stvp/rollbar.go" {
+		t.Errorf("should keep the default patterns, got %s", got)
+	}
+
+	SetFilePathPatterns("payments/")
+	if got := shortenFilePath(path); got != "payments/api.go" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestFilePathShortener(t *testing.T) {
+	defer SetFilePathShortener(nil)
+
+	SetFilePathShortener(func(path string) string {
+		if strings.HasPrefix(path, "/srv/") {
+			return strings.TrimPrefix(path, "/srv/")
+		}
+		return ShortenFilePath(path)
+	})
+	if got := shortenFilePath("/srv/api/main.go"); got != "api/main.go" {
+		t.Errorf("got %s", got)
+	}
+	if got := shortenFilePath("/home/foo/go/src/github.com/stvp/rollbar.go"); got != "github.com/stvp/rollbar.go" {
+		t.Errorf("got %s", got)
+	}
+
+	SetFilePathShortener(nil)
+	if got := shortenFilePath("/srv/api/main.go"); got != "/srv/api/main.go" {
+		t.Errorf("should restore the default shortener, got %s", got)
+	}
+}
+
+func TestWindowsPaths(t *testing.T) {
+	tests := []struct {
+		Given    string
+		Expected string
+	}{
+		{`C:\Users\foo\go\src\github.com\stvp\rollbar.go`, "github.com/stvp/rollbar.go"},
+		{"C:/Users/foo/go/pkg/mod/github.com/lib/pq@v1.10.9/conn.go", "github.com/lib/pq/conn.go"},
+		{`\\build\share\src\github.com\acme\api\main.go`, "github.com/acme/api/main.go"},
+		{`/home/foo/back\slash.go`, `/home/foo/back\slash.go`},
+	}
+	for i, test := range tests {
+		if got := ShortenFilePath(test.Given); got != test.Expected {
+			t.Errorf("tests[%d]: got %s", i, got)
+		}
+	}
+
+	if rel, ok := relativePath("C:/builds/api/internal/db.go", `C:\builds\api\`); !ok || rel != "internal/db.go" {
+		t.Errorf("got %q, %v", rel, ok)
+	}
+	if got := shortFunctionName("github.com/stvp/rollbar.(*Client).Error"); got != "rollbar.(*Client).Error" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestElideFrames(t *testing.T) {
+	stack := make(Stack, 10)
+	for i := range stack {
+		stack[i] = Frame{Filename: "deep.go", Method: "recurse", Line: i}
+	}
+
+	if got := elideFrames(stack, 0); len(got) != 10 {
+		t.Errorf("should not limit the depth, got %d frames", len(got))
+	}
+	if got := elideFrames(stack, 10); len(got) != 10 {
+		t.Errorf("should leave shallow stacks alone, got %d frames", len(got))
+	}
+
+	got := elideFrames(stack, 6)
+	if len(got) != 6 {
+		t.Fatalf("got %d frames", len(got))
+	}
+	if got[0].Line != 0 || got[2].Line != 2 || got[4].Line != 8 || got[5].Line != 9 {
+		t.Errorf("should keep the innermost and outermost frames, got %+v", got)
+	}
+	if got[3].Method != "(5 frames elided)" {
+		t.Errorf("got elision frame %+v", got[3])
+	}
+}
+
+func recurse(depth int, fn func() Stack) Stack {
+	if depth == 0 {
+		return fn()
+	}
+	return recurse(depth-1, fn)
+}
+
+func TestMaxStackDepth(t *testing.T) {
+	stack := recurse(100, func() Stack { return buildStack(1, stackOptions{maxDepth: 8}) })
+	if len(stack) != 8 {
+		t.Fatalf("got %d frames", len(stack))
+	}
+	if stack[0].Method != "rollbar.TestMaxStackDepth.func1" || stack[4].Filename != "..." {
+		t.Errorf("got %+v", stack)
+	}
+	if stack[1].Code == "" {
+		t.Error("should read the source of kept frames")
+	}
+}
+
+func TestCollapseRecursion(t *testing.T) {
+	frame := func(method string, line int) Frame {
+		return Frame{Filename: "walk.go", Method: method, Line: line}
+	}
+	stack := Stack{frame("visit", 3)}
+	for i := 0; i < 5; i++ {
+		stack = append(stack, frame("walk", 10), frame("visit", 4))
+	}
+	stack = append(stack, frame("main", 1), frame("main", 1))
+
+	got := collapseRecursion(stack)
+	expected := Stack{
+		frame("visit", 3),
+		frame("walk", 10), frame("visit", 4),
+		{Filename: "...", Method: "(2 frames repeated 4 more times)"},
+		frame("main", 1), frame("main", 1),
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("got %+v", got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("frame %d: got %+v, expected %+v", i, got[i], expected[i])
+		}
+	}
+
+	shallow := Stack{frame("a", 1), frame("b", 2), frame("a", 1), frame("b", 2)}
+	if got := collapseRecursion(shallow); len(got) != 4 {
+		t.Errorf("should not collapse cycles repeated only twice, got %+v", got)
+	}
+}
+
+func TestRecursionIsCollapsed(t *testing.T) {
+	stack := recurse(20, func() Stack { return BuildStack(1) })
+	body, _ := errorBody(errors.New("too deep"), stack, defaultConfiguration("token"))
+	frames := body["trace"].(map[string]interface{})["frames"].(Stack)
+	if len(frames) >= len(stack) || frames[3].Method != "(1 frames repeated 19 more times)" {
+		t.Errorf("got %+v", frames)
+	}
+}
+
+func TestFunctionPackage(t *testing.T) {
+	tests := map[string]string{
+		"github.com/stvp/rollbar.(*Client).Error":  "github.com/stvp/rollbar",
+		"github.com/stvp/rollbar.BuildStack.func1": "github.com/stvp/rollbar",
+		"main.main":    "main",
+		"net/http.Get": "net/http",
+	}
+	for name, pkg := range tests {
+		if got := functionPackage(name); got != pkg {
+			t.Errorf("%s: got %s, want %s", name, got, pkg)
+		}
+	}
+}
+
+func TestSkipWrapperFrames(t *testing.T) {
+	frames := []runtime.Frame{
+		{Function: packagePath + ".(*Client).Error", File: "client.go"},
+		{Function: "example.com/app/report.Error", File: "report.go"},
+		{Function: "example.com/app/report/internal.capture", File: "capture.go"},
+		{Function: "example.com/app.handle", File: "app.go"},
+		{Function: packagePath + ".Middleware.func1", File: "middleware.go"},
+	}
+
+	got := skipWrapperFrames(frames, nil)
+	if len(got) != 4 || got[0].Function != "example.com/app/report.Error" {
+		t.Errorf("should skip frames of this package, got %+v", got)
+	}
+	got = skipWrapperFrames(frames, []string{"example.com/app/report"})
+	if len(got) != 2 || got[0].Function != "example.com/app.handle" {
+		t.Errorf("should skip frames of wrapper packages, got %+v", got)
+	}
+	if got := skipWrapperFrames(frames[:1], nil); len(got) != 1 {
+		t.Error("should keep stacks made only of wrapper frames")
+	}
+}
+
+func TestContextLines(t *testing.T) {
+	stack := buildStack(1, stackOptions{contextLines: 2})
+	frame := stack[0]
+	if frame.Code != "stack := buildStack(1, stackOptions{contextLines: 2})" {
+		t.Errorf("got code %q", frame.Code)
+	}
+	if frame.Context == nil || len(frame.Context.Pre) != 2 || len(frame.Context.Post) != 2 {
+		t.Fatalf("got context %+v", frame.Context)
+	}
+	if frame.Context.Pre[1] != "func TestContextLines(t *testing.T) {" || frame.Context.Post[0] != "\tframe := stack[0]" {
+		t.Errorf("got context %q, %q", frame.Context.Pre, frame.Context.Post)
+	}
+
+	if frame := BuildStack(1)[0]; frame.Context != nil {
+		t.Errorf("should not capture context by default, got %+v", frame.Context)
+	}
+}
+
+func TestCodeCapture(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil),
+		WithCodeCapture(false), WithContextLines(3))
+	client.Error(ERR, errors.New("no source"))
+
+	if bytes.Contains(transport.payloads[0], []byte(`"code"`)) || bytes.Contains(transport.payloads[0], []byte(`"context"`)) {
+		t.Errorf("should not report source code, got %s", transport.payloads[0])
+	}
+	if frame := buildStack(1, stackOptions{skipCode: true})[0]; frame.Code != "" || frame.Line == 0 {
+		t.Errorf("got %+v", frame)
+	}
+}
@@ -0,0 +1,136 @@
+package rollbar
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of a Client's delivery counters, for operators who need
+// to know whether error reporting itself is healthy. Counters start at zero
+// when the Client is created and only go up.
+type Stats struct {
+	// Queued is the number of items accepted for delivery.
+	Queued uint64
+
+	// Sent is the number of items Rollbar accepted.
+	Sent uint64
+
+	// Retried is the number of POSTs that were retried after a transient
+	// failure.
+	Retried uint64
+
+	// Coalesced is the number of items that were merged into an identical item
+	// by batching (see WithBatching) instead of being POSTed on their own.
+	Coalesced uint64
+
+	// Sampled is the number of items that were not sent because of sampling
+	// (see WithSampling). They are not counted as dropped.
+	Sampled uint64
+
+	// Suppressed is the number of items that were not sent because an
+	// identical item was sent shortly before (see WithDedupWindow). They are
+	// not counted as dropped.
+	Suppressed uint64
+
+	// Spooled is the number of items saved to the spool (see WithSpool).
+	Spooled uint64
+
+	// Dropped is the number of items that never reached Rollbar. The
+	// Dropped* counters below break it down by reason.
+	Dropped uint64
+
+	// DroppedBufferFull counts items dropped because the queue was full.
+	DroppedBufferFull uint64
+
+	// DroppedClosed counts items reported after the Client was closed.
+	DroppedClosed uint64
+
+	// DroppedCircuitOpen counts items dropped by the open circuit breaker.
+	DroppedCircuitOpen uint64
+
+	// DroppedRateLimited counts items over the Client's own item limits (see
+	// WithItemLimit).
+	DroppedRateLimited uint64
+
+	// DroppedRejected counts items the Rollbar API rejected (a bad access
+	// token, a malformed item, etc.).
+	DroppedRejected uint64
+
+	// DroppedFailed counts items that could not be delivered for any other
+	// reason, such as network errors that persisted through every retry.
+	DroppedFailed uint64
+
+	// QueueDepth is the number of items currently waiting in the queue.
+	QueueDepth int
+
+	// QueueCapacity is the size of the queue (see WithBuffer).
+	QueueCapacity int
+}
+
+// counters holds the live values behind Stats. Fields are updated with
+// sync/atomic.
+type counters struct {
+	queued, sent, retried, coalesced, sampled, suppressed, spooled uint64
+
+	droppedBufferFull, droppedClosed, droppedCircuitOpen uint64
+	droppedRateLimited, droppedRejected, droppedFailed   uint64
+}
+
+// Stats returns the Client's current delivery counters.
+func (c *Client) Stats() Stats {
+	stats := Stats{
+		Queued:             atomic.LoadUint64(&c.counters.queued),
+		Sent:               atomic.LoadUint64(&c.counters.sent),
+		Retried:            atomic.LoadUint64(&c.counters.retried),
+		Coalesced:          atomic.LoadUint64(&c.counters.coalesced),
+		Sampled:            atomic.LoadUint64(&c.counters.sampled),
+		Suppressed:         atomic.LoadUint64(&c.counters.suppressed),
+		Spooled:            atomic.LoadUint64(&c.counters.spooled),
+		DroppedBufferFull:  atomic.LoadUint64(&c.counters.droppedBufferFull),
+		DroppedClosed:      atomic.LoadUint64(&c.counters.droppedClosed),
+		DroppedCircuitOpen: atomic.LoadUint64(&c.counters.droppedCircuitOpen),
+		DroppedRateLimited: atomic.LoadUint64(&c.counters.droppedRateLimited),
+		DroppedRejected:    atomic.LoadUint64(&c.counters.droppedRejected),
+		DroppedFailed:      atomic.LoadUint64(&c.counters.droppedFailed),
+		QueueDepth:         len(c.queue),
+		QueueCapacity:      cap(c.queue),
+	}
+	stats.Dropped = stats.DroppedBufferFull + stats.DroppedClosed + stats.DroppedCircuitOpen +
+		stats.DroppedRateLimited + stats.DroppedRejected + stats.DroppedFailed
+	return stats
+}
+
+// countDropped counts an item dropped because of the given error.
+func (c *Client) countDropped(err error) {
+	switch {
+	case err == ErrBufferFull:
+		atomic.AddUint64(&c.counters.droppedBufferFull, 1)
+	case err == ErrClosed:
+		atomic.AddUint64(&c.counters.droppedClosed, 1)
+	case err == ErrCircuitOpen:
+		atomic.AddUint64(&c.counters.droppedCircuitOpen, 1)
+	case err == ErrRateLimited:
+		atomic.AddUint64(&c.counters.droppedRateLimited, 1)
+	case !retryable(err):
+		atomic.AddUint64(&c.counters.droppedRejected, 1)
+	default:
+		atomic.AddUint64(&c.counters.droppedFailed, 1)
+	}
+}
+
+// reportStats calls handler with the Client's Stats every interval until the
+// Client is closed, and one last time after that.
+func (c *Client) reportStats(interval time.Duration, handler func(Stats)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			handler(c.Stats())
+		case <-c.done:
+			handler(c.Stats())
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package rollbar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 2 {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithWorkers(1), WithErrorWriter(nil))
+	client.Message(INFO, "sent")
+	client.Wait()
+	client.Message(INFO, "rejected")
+	client.Close()
+	client.Message(INFO, "late")
+
+	stats := client.Stats()
+	if stats.Queued != 2 || stats.Sent != 1 {
+		t.Errorf("got %d queued, %d sent", stats.Queued, stats.Sent)
+	}
+	if stats.Dropped != 2 || stats.DroppedRejected != 1 || stats.DroppedClosed != 1 {
+		t.Errorf("got %+v", stats)
+	}
+	if stats.QueueDepth != 0 || stats.QueueCapacity != DefaultBuffer {
+		t.Errorf("got queue %d / %d", stats.QueueDepth, stats.QueueCapacity)
+	}
+}
+
+func TestStatsRetried(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil),
+		WithRetryBackoff(time.Millisecond, time.Millisecond))
+	client.Message(INFO, "flaky")
+
+	if stats := client.Stats(); stats.Retried != 1 || stats.Sent != 1 || stats.Dropped != 0 {
+		t.Errorf("got %+v", stats)
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	reported := make(chan Stats, 100)
+	client := New("token", WithEndpoint("http://localhost"), WithBuffer(7), WithStatsHandler(time.Millisecond, func(stats Stats) {
+		reported <- stats
+	}))
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case stats := <-reported:
+			if stats.QueueCapacity != 7 {
+				t.Errorf("got %+v", stats)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("should report stats periodically")
+		}
+	}
+}
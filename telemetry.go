@@ -0,0 +1,273 @@
+package rollbar
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxTelemetryEvents is the number of recent telemetry events a
+	// Client keeps by default; see WithMaxTelemetryEvents.
+	DefaultMaxTelemetryEvents = 50
+
+	// telemetryFieldName is the name of the Field that passes the Telemetry
+	// of a context along with the fields of an item.
+	telemetryFieldName = "telemetry"
+)
+
+// Types of TelemetryEvents, as Rollbar shows them.
+const (
+	TelemetryLog     = "log"
+	TelemetryNetwork = "network"
+	TelemetryError   = "error"
+	TelemetryManual  = "manual"
+)
+
+// TelemetryEvent is something that happened before an item was reported, such
+// as a log line or an outbound HTTP call. Rollbar shows the telemetry events
+// reported with an item as the timeline leading up to it.
+type TelemetryEvent struct {
+	// Level is the severity level of the event (ERR, INFO, etc.).
+	Level string `json:"level"`
+
+	// Type is one of TelemetryLog, TelemetryNetwork, TelemetryError and
+	// TelemetryManual.
+	Type string `json:"type"`
+
+	// Source is where the event comes from, "server" by default.
+	Source string `json:"source"`
+
+	// Timestamp is when the event happened, in milliseconds since the Unix
+	// epoch, now by default.
+	Timestamp int64 `json:"timestamp_ms"`
+
+	// Body describes the event, e.g. {"message": "..."} for log lines.
+	Body map[string]interface{} `json:"body"`
+}
+
+// Telemetry is a ring buffer of the most recent TelemetryEvents. Every Client
+// has one (see Client.Telemetry), whose events are reported with each of its
+// items, and contexts can carry their own (see ContextWithTelemetry), whose
+// events are reported along with them for the items reported with the
+// context. Recording events is safe from multiple goroutines, and is a no-op
+// on a nil Telemetry.
+type Telemetry struct {
+	mutex  sync.Mutex
+	events []TelemetryEvent
+	next   int
+	max    int
+}
+
+// NewTelemetry returns a Telemetry that keeps the max most recent events.
+func NewTelemetry(max int) *Telemetry {
+	return &Telemetry{max: max}
+}
+
+// Record records an event, dropping the oldest one if the Telemetry is full.
+func (t *Telemetry) Record(event TelemetryEvent) {
+	if t == nil || t.max <= 0 {
+		return
+	}
+	if event.Source == "" {
+		event.Source = "server"
+	}
+	if event.Timestamp == 0 {
+		event.Timestamp = timestampMillis(time.Now())
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.events) < t.max {
+		t.events = append(t.events, event)
+		return
+	}
+	t.events[t.next] = event
+	t.next = (t.next + 1) % t.max
+}
+
+// RecordLog records a log line with the given severity level.
+func (t *Telemetry) RecordLog(level, message string) {
+	t.Record(TelemetryEvent{Level: level, Type: TelemetryLog, Body: map[string]interface{}{"message": message}})
+}
+
+// RecordNetwork records an outbound HTTP call to rawURL, which got the given
+// status code, or 0 if it failed without a response.
+func (t *Telemetry) RecordNetwork(method, rawURL string, statusCode int, start, end time.Time) {
+	level := INFO
+	if statusCode == 0 || statusCode >= 500 {
+		level = ERR
+	}
+	body := map[string]interface{}{
+		"method":             method,
+		"url":                rawURL,
+		"status_code":        statusCode,
+		"start_timestamp_ms": timestampMillis(start),
+		"end_timestamp_ms":   timestampMillis(end),
+		"duration_ms":        durationMillis(end.Sub(start)),
+	}
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		body["host"] = u.Host
+	}
+	t.Record(TelemetryEvent{Level: level, Type: TelemetryNetwork, Timestamp: timestampMillis(start), Body: body})
+}
+
+// RecordEvent records a custom event with the given severity level.
+func (t *Telemetry) RecordEvent(level string, body map[string]interface{}) {
+	t.Record(TelemetryEvent{Level: level, Type: TelemetryManual, Body: body})
+}
+
+// Events returns the recorded events, oldest first.
+func (t *Telemetry) Events() []TelemetryEvent {
+	if t == nil {
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	events := make([]TelemetryEvent, 0, len(t.events))
+	events = append(events, t.events[t.next:]...)
+	return append(events, t.events[:t.next]...)
+}
+
+// RoundTripper returns an http.RoundTripper that records the HTTP calls made
+// through next, or http.DefaultTransport if it is nil, as network events:
+// their method, URL, host, status code and duration. Calls made with a
+// request whose context carries a Telemetry are recorded there instead. URLs
+// are recorded without their query string, which may hold credentials.
+func (t *Telemetry) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &telemetryTransport{telemetry: t, next: next}
+}
+
+// RoundTripper is like Telemetry.RoundTripper for the Client's Telemetry, but
+// also reports the 5xx responses of the given hosts, such as critical
+// dependencies ("payments.internal:8443"), as warnings, each a
+// *DependencyError with the stack trace of the call:
+//
+//	httpClient := &http.Client{Transport: client.RoundTripper(nil, "api.stripe.com")}
+func (c *Client) RoundTripper(next http.RoundTripper, criticalHosts ...string) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &telemetryTransport{telemetry: c.telemetry, next: next, client: c, criticalHosts: criticalHosts}
+}
+
+// DependencyError is the error reported for a 5xx response of a critical
+// dependency; see Client.RoundTripper.
+type DependencyError struct {
+	Method     string
+	URL        string
+	StatusCode int
+}
+
+// Error implements the error interface.
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+type telemetryTransport struct {
+	telemetry     *Telemetry
+	next          http.RoundTripper
+	client        *Client
+	criticalHosts []string
+}
+
+func (rt *telemetryTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := rt.next.RoundTrip(r)
+	end := time.Now()
+
+	telemetry := rt.telemetry
+	if contextTelemetry := TelemetryFromContext(r.Context()); contextTelemetry != nil {
+		telemetry = contextTelemetry
+	}
+	statusCode := 0
+	if err == nil {
+		statusCode = resp.StatusCode
+	}
+	u := *r.URL
+	u.RawQuery, u.Fragment, u.User = "", "", nil
+	telemetry.RecordNetwork(r.Method, u.String(), statusCode, start, end)
+
+	if statusCode >= 500 && rt.critical(r.URL) {
+		rt.client.ErrorWithContext(r.Context(), WARN, &DependencyError{Method: r.Method, URL: u.String(), StatusCode: statusCode},
+			Custom(map[string]interface{}{"duration_ms": durationMillis(end.Sub(start))}))
+	}
+	return resp, err
+}
+
+// critical reports whether u is on one of the critical hosts whose server
+// errors are reported, with or without its port.
+func (rt *telemetryTransport) critical(u *url.URL) bool {
+	if rt.client == nil {
+		return false
+	}
+	for _, critical := range rt.criticalHosts {
+		if u.Host == critical || u.Hostname() == critical {
+			return true
+		}
+	}
+	return false
+}
+
+// Telemetry returns the Client's Telemetry, whose events are reported with
+// every item.
+func (c *Client) Telemetry() *Telemetry {
+	return c.telemetry
+}
+
+// ContextWithTelemetry returns a copy of ctx that carries a Telemetry of its
+// own, which keeps the max most recent events, e.g. those of a single
+// request. Its events are reported, along with those of the Client, with the
+// items reported with the context, such as by ErrorWithContext.
+func ContextWithTelemetry(ctx context.Context, max int) context.Context {
+	return context.WithValue(ctx, telemetryContextKey, NewTelemetry(max))
+}
+
+// TelemetryFromContext returns the Telemetry carried by ctx, or nil.
+func TelemetryFromContext(ctx context.Context) *Telemetry {
+	telemetry, _ := ctx.Value(telemetryContextKey).(*Telemetry)
+	return telemetry
+}
+
+// telemetryEvents returns the events of the Client's Telemetry and those of
+// the context Telemetry passed along with the fields of an item, if any,
+// oldest first, along with the other fields.
+func (c *Client) telemetryEvents(fields []*Field) ([]TelemetryEvent, []*Field) {
+	events := c.telemetry.Events()
+	if !hasField(fields, telemetryFieldName) {
+		return events, fields
+	}
+
+	others := make([]*Field, 0, len(fields))
+	for _, field := range fields {
+		if field == nil || field.Name != telemetryFieldName {
+			others = append(others, field)
+			continue
+		}
+		if telemetry, ok := field.Data.(*Telemetry); ok {
+			events = append(events, telemetry.Events()...)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp < events[j].Timestamp
+	})
+	return events, others
+}
+
+func timestampMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
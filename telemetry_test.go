@@ -0,0 +1,138 @@
+package rollbar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTelemetryRingBuffer(t *testing.T) {
+	telemetry := NewTelemetry(3)
+	for _, message := range []string{"a", "b", "c", "d", "e"} {
+		telemetry.RecordLog(INFO, message)
+	}
+
+	events := telemetry.Events()
+	if len(events) != 3 {
+		t.Fatalf("got %d events", len(events))
+	}
+	for i, message := range []string{"c", "d", "e"} {
+		if events[i].Body["message"] != message || events[i].Type != TelemetryLog || events[i].Source != "server" || events[i].Timestamp == 0 {
+			t.Errorf("events[%d]: got %+v", i, events[i])
+		}
+	}
+
+	var disabled *Telemetry
+	disabled.RecordLog(INFO, "ignored")
+	if disabled.Events() != nil {
+		t.Error("a nil Telemetry should record nothing")
+	}
+}
+
+func TestTelemetryIsReported(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+	client.Telemetry().Record(TelemetryEvent{Level: INFO, Type: TelemetryManual, Timestamp: 1000, Body: map[string]interface{}{"step": "start"}})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+
+	ctx := ContextWithTelemetry(context.Background(), 10)
+	TelemetryFromContext(ctx).RecordLog(WARN, "retrying")
+	httpClient := &http.Client{Transport: client.Telemetry().RoundTripper(nil)}
+	req, _ := http.NewRequest("GET", server.URL+"/brew?token=secret", nil)
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	client.ErrorWithContext(ctx, ERR, errors.New("no coffee"))
+	client.Message(INFO, "without context")
+
+	var bodies [2]struct {
+		Data struct {
+			Body struct {
+				Telemetry []TelemetryEvent `json:"telemetry"`
+			} `json:"body"`
+		} `json:"data"`
+	}
+	for i := range bodies {
+		if err := json.Unmarshal(transport.payloads[i], &bodies[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := bodies[0].Data.Body.Telemetry
+	if len(events) != 3 {
+		t.Fatalf("got events %+v", events)
+	}
+	if events[0].Body["step"] != "start" || events[1].Body["message"] != "retrying" {
+		t.Errorf("should report the events of the client and the context, oldest first, got %+v", events)
+	}
+	if network := events[2]; network.Type != TelemetryNetwork || network.Body["url"] != server.URL+"/brew" || network.Body["status_code"] != 418.0 {
+		t.Errorf("got network event %+v", network)
+	}
+	if events := bodies[1].Data.Body.Telemetry; len(events) != 1 {
+		t.Errorf("should only report the events of the client, got %+v", events)
+	}
+}
+
+func TestRoundTripperReportsCriticalServerErrors(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	host := server.Listener.Addr().(*net.TCPAddr).IP.String()
+
+	get := func(httpClient *http.Client) {
+		resp, err := httpClient.Get(server.URL + "/charge?card=4242")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	get(&http.Client{Transport: client.RoundTripper(nil, host)})
+	get(&http.Client{Transport: client.RoundTripper(nil, "other.internal")})
+	status = http.StatusOK
+	get(&http.Client{Transport: client.RoundTripper(nil, host)})
+
+	if len(transport.payloads) != 1 {
+		t.Fatalf("got %d items", len(transport.payloads))
+	}
+	var body struct {
+		Data struct {
+			Level string `json:"level"`
+			Body  struct {
+				Trace struct {
+					Exception struct {
+						Class   string `json:"class"`
+						Message string `json:"message"`
+					} `json:"exception"`
+				} `json:"trace"`
+			} `json:"body"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(transport.payloads[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	exception := body.Data.Body.Trace.Exception
+	if body.Data.Level != WARN || exception.Class != "rollbar.DependencyError" || exception.Message != "GET "+server.URL+"/charge: 503 Service Unavailable" {
+		t.Errorf("got %+v", body.Data)
+	}
+
+	events := client.Telemetry().Events()
+	if len(events) != 3 || events[0].Body["host"] != server.Listener.Addr().String() || events[0].Body["duration_ms"] == nil {
+		t.Errorf("got events %+v", events)
+	}
+}
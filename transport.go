@@ -0,0 +1,398 @@
+package rollbar
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Transport delivers encoded items. The default Transport POSTs them to the
+// Rollbar API; others can hand them to a message queue or a local agent, or
+// record them in tests (see the rollbartest package). A Client queues,
+// coalesces, truncates, retries and spools items the same way whatever its
+// Transport.
+type Transport interface {
+	// Send delivers a single encoded item, i.e. the JSON body that the
+	// Rollbar API expects, giving up when ctx is done. Errors for which
+	// retrying is pointless should be an ErrHTTPError (or wrap one) with a
+	// 4xx status other than 429; other errors are retried.
+	Send(ctx context.Context, payload []byte) error
+
+	// Flush blocks until every item passed to Send has been delivered, or
+	// until ctx is done.
+	Flush(ctx context.Context) error
+
+	// Close releases the Transport's resources. The Client calls it once
+	// it has been closed and has stopped calling Send.
+	Close() error
+}
+
+// envelope is a queued item along with the JSON body that will be POSTed for
+// it.
+type envelope struct {
+	item *Item
+	body map[string]interface{}
+}
+
+// pending counts items that have been queued but not yet delivered. Unlike a
+// sync.WaitGroup, it may be waited on while items are being queued.
+type pending struct {
+	mutex sync.Mutex
+	empty chan struct{}
+	count int
+}
+
+func (p *pending) add(delta int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.count += delta
+	if p.count == 0 && p.empty != nil {
+		close(p.empty)
+		p.empty = nil
+	}
+}
+
+// emptied returns a channel that is closed once the count drops to zero, so
+// that waiting can be abandoned without leaving a goroutine behind.
+func (p *pending) emptied() <-chan struct{} {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	empty := p.empty
+	if empty == nil {
+		empty = make(chan struct{})
+		if p.count == 0 {
+			close(empty)
+		} else {
+			p.empty = empty
+		}
+	}
+	return empty
+}
+
+// wait blocks until the count drops to zero.
+func (p *pending) wait() {
+	<-p.emptied()
+}
+
+// start creates the Client's bounded queue and the pool of sender workers
+// that drain it. A fixed number of workers means an error storm can never
+// open more than that many concurrent connections to Rollbar.
+func (c *Client) start() {
+	config := c.snapshot()
+	workers := config.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	c.http = &httpTransport{client: c}
+	c.telemetry = NewTelemetry(config.maxTelemetry)
+	c.breaker = newBreaker(config.breakerThreshold, config.breakerCooldown)
+	if config.spoolDir != "" {
+		var err error
+		if c.spool, err = newSpool(config.spoolDir, config.spoolMaxBytes); err != nil {
+			c.errorf("failed to create spool: %s", err.Error())
+		}
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.queue = make(chan *envelope, config.buffer)
+	c.postErrors = make(chan error, config.buffer)
+	c.done = make(chan struct{})
+	c.stopping = make(chan struct{})
+
+	running := make(chan struct{}, workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			c.work()
+			running <- struct{}{}
+		}()
+	}
+
+	if config.statsHandler != nil && config.statsInterval > 0 {
+		go c.reportStats(config.statsInterval, config.statsHandler)
+	}
+
+	go func() {
+		for i := 0; i < workers; i++ {
+			<-running
+		}
+		// The queue is only closed once the Client is, so no synchronous
+		// POST can start anymore.
+		c.sending.Wait()
+		close(c.postErrors)
+		c.cancel()
+		close(c.done)
+	}()
+}
+
+// work POSTs queued items until the queue is closed. With batching enabled,
+// identical items queued close together are POSTed once.
+func (c *Client) work() {
+	for env := range c.queue {
+		config := c.snapshot()
+		if config.batchSize <= 1 {
+			c.deliver(env)
+			c.pending.add(-1)
+			continue
+		}
+
+		envs := c.collect(env, config.batchSize, config.batchInterval)
+		batches := coalesce(envs)
+		atomic.AddUint64(&c.counters.coalesced, uint64(len(envs)-len(batches)))
+		for _, b := range batches {
+			c.deliver(b.env, b.merged...)
+		}
+		c.pending.add(-len(envs))
+	}
+}
+
+// postError makes err available on PostErrors, dropping the oldest error if
+// nobody has been reading them.
+func (c *Client) postError(err error) {
+	for {
+		select {
+		case c.postErrors <- err:
+			return
+		default:
+			select {
+			case <-c.postErrors:
+			default:
+			}
+		}
+	}
+}
+
+// enqueue queues the given envelope for the sender workers. What happens when
+// the queue is full depends on the Client's QueuePolicy; by default the new
+// item is dropped rather than blocking the caller: reporting an error must
+// never slow down the application that reports it.
+func (c *Client) enqueue(env *envelope) error {
+	c.closeMutex.RLock()
+	defer c.closeMutex.RUnlock()
+
+	if c.closed {
+		c.errorf("client closed, dropping error on the floor")
+		c.dropped(env.item, ErrClosed)
+		return ErrClosed
+	}
+
+	config := c.snapshot()
+	c.pending.add(1)
+	select {
+	case c.queue <- env:
+		atomic.AddUint64(&c.counters.queued, 1)
+		return nil
+	default:
+	}
+
+	switch config.queuePolicy {
+	case DropOldest:
+		for {
+			select {
+			case c.queue <- env:
+				atomic.AddUint64(&c.counters.queued, 1)
+				return nil
+			case oldest := <-c.queue:
+				c.pending.add(-1)
+				c.errorf("buffer full, dropping oldest error on the floor")
+				c.dropped(oldest.item, ErrBufferFull)
+			}
+		}
+	case Block:
+		var timeout <-chan time.Time
+		if config.queueTimeout > 0 {
+			timer := time.NewTimer(config.queueTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case c.queue <- env:
+			atomic.AddUint64(&c.counters.queued, 1)
+			return nil
+		case <-timeout:
+		case <-c.stopping:
+			c.pending.add(-1)
+			c.errorf("client closed, dropping error on the floor")
+			c.dropped(env.item, ErrClosed)
+			return ErrClosed
+		}
+	}
+
+	c.pending.add(-1)
+	c.errorf("buffer full, dropping error on the floor")
+	c.dropped(env.item, ErrBufferFull)
+	return ErrBufferFull
+}
+
+// dropped passes an item that will never reach Rollbar to the delivery error
+// handler, if any.
+func (c *Client) dropped(item *Item, err error) {
+	c.countDropped(err)
+	if handler := c.snapshot().onDropped; handler != nil {
+		handler(item, err)
+	}
+}
+
+// send POSTs the given envelope from the calling goroutine and returns the
+// delivery error. It is used instead of enqueue by synchronous Clients, and
+// Shutdown waits for it to return before closing the Transport.
+func (c *Client) send(env *envelope) error {
+	c.closeMutex.RLock()
+	closed := c.closed
+	if !closed {
+		c.sending.Add(1)
+	}
+	c.closeMutex.RUnlock()
+
+	if closed {
+		c.errorf("client closed, dropping error on the floor")
+		c.dropped(env.item, ErrClosed)
+		return ErrClosed
+	}
+	defer c.sending.Done()
+
+	atomic.AddUint64(&c.counters.queued, 1)
+	return c.deliver(env)
+}
+
+// deliver POSTs the given envelope, retrying transient failures with
+// exponential backoff, and makes the final error available on PostErrors.
+// The delivery hooks are called for the envelope's item and for the items
+// of merged envelopes, which were coalesced into it.
+func (c *Client) deliver(env *envelope, merged ...*Item) error {
+	items := append([]*Item{env.item}, merged...)
+	config := c.snapshot()
+	if token, _ := env.body["access_token"].(string); len(token) == 0 {
+		c.errorf("empty token")
+		return nil
+	}
+
+	jsonBody, err := json.Marshal(env.body)
+	if err != nil {
+		c.errorf("failed to encode payload: %s", err.Error())
+		c.postError(err)
+		for _, item := range items {
+			c.dropped(item, err)
+		}
+		return err
+	}
+	if config.maxPayloadSize > 0 && len(jsonBody) > config.maxPayloadSize {
+		jsonBody = truncate(jsonBody, config.maxPayloadSize)
+		c.debugf("payload too large, truncated it to %d bytes", len(jsonBody))
+	}
+	if config.beforeSend != nil {
+		jsonBody = config.beforeSend(env.item, jsonBody)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err = c.waitForRateLimit(c.ctx); err != nil {
+			break
+		}
+		if !c.breaker.allow() {
+			err = ErrCircuitOpen
+			break
+		}
+		err = c.post(c.ctx, jsonBody)
+		c.breaker.record(err)
+		if err == nil || attempt >= config.retries || !retryable(err) {
+			break
+		}
+		if sleepErr := sleep(c.ctx, backoff(config.retryBackoff, config.maxRetryBackoff, attempt)); sleepErr != nil {
+			break
+		}
+		atomic.AddUint64(&c.counters.retried, 1)
+	}
+	if config.afterSend != nil {
+		for _, item := range items {
+			config.afterSend(item, jsonBody, err)
+		}
+	}
+
+	if err != nil {
+		c.postError(err)
+		if !c.spoolItem(jsonBody, err) {
+			for _, item := range items {
+				c.dropped(item, err)
+			}
+		}
+	} else {
+		atomic.AddUint64(&c.counters.sent, 1)
+		if c.spool != nil && c.spool.holdsItems() {
+			c.spool.replay(c.replayPost)
+		}
+	}
+	return err
+}
+
+// spoolItem saves an encoded item that failed to be delivered with the given
+// error to the spool, if the Client has one and the failure is transient. It
+// reports whether the item was saved.
+func (c *Client) spoolItem(jsonBody []byte, err error) bool {
+	if c.spool == nil || !retryable(err) {
+		return false
+	}
+	if spoolErr := c.spool.write(jsonBody); spoolErr != nil {
+		c.errorf("failed to spool item: %s", spoolErr.Error())
+		return false
+	}
+	atomic.AddUint64(&c.counters.spooled, 1)
+	return true
+}
+
+// replayPost POSTs an item from the spool, honoring rate limits and the
+// circuit breaker.
+func (c *Client) replayPost(jsonBody []byte) error {
+	if err := c.waitForRateLimit(c.ctx); err != nil {
+		return err
+	}
+	if !c.breaker.allow() {
+		return ErrCircuitOpen
+	}
+	err := c.post(c.ctx, jsonBody)
+	c.breaker.record(err)
+	return err
+}
+
+// POST the given encoded JSON body to Rollbar synchronously, once, through
+// the Client's Transport, giving up after the configured timeout or when ctx
+// is done.
+func (c *Client) post(ctx context.Context, jsonBody []byte) error {
+	config := c.snapshot()
+	if config.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.timeout)
+		defer cancel()
+	}
+
+	return c.transport(config).Send(ctx, jsonBody)
+}
+
+// transport returns the configured Transport, or the HTTP transport that
+// POSTs to the Rollbar API.
+func (c *Client) transport(config configuration) Transport {
+	if config.transport != nil {
+		return config.transport
+	}
+	return c.http
+}
+
+// sleep pauses for the given duration, returning early with ctx.Err() if ctx
+// is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
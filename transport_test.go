@@ -0,0 +1,451 @@
+package rollbar
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolBoundsConcurrency(t *testing.T) {
+	var inFlight, maxInFlight, received int32
+	arrived := make(chan bool, 10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		arrived <- true
+		<-release
+		atomic.AddInt32(&inFlight, -1)
+		atomic.AddInt32(&received, 1)
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithWorkers(2), WithBuffer(2), WithErrorWriter(nil))
+
+	// Occupy both workers, then fill the queue and overflow it.
+	client.Message(INFO, "1")
+	client.Message(INFO, "2")
+	<-arrived
+	<-arrived
+	for i := 0; i < 5; i++ {
+		client.Message(INFO, "queued or dropped")
+	}
+
+	close(release)
+	client.Wait()
+
+	if max := atomic.LoadInt32(&maxInFlight); max != 2 {
+		t.Errorf("got %d concurrent requests", max)
+	}
+	if got := atomic.LoadInt32(&received); got != 4 {
+		t.Errorf("got %d items", got)
+	}
+}
+
+func TestSyncDelivery(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil), WithRetries(0))
+	if _, err := client.Message(INFO, "delivered"); err != nil {
+		t.Errorf("got: %v", err)
+	}
+
+	status = http.StatusInternalServerError
+	if _, err := client.Error(ERR, errors.New("not delivered")); err != ErrHTTPError(500) {
+		t.Errorf("got: %v", err)
+	}
+
+	client.Close()
+	if _, err := client.Message(INFO, "closed"); err != ErrClosed {
+		t.Errorf("got: %v", err)
+	}
+}
+
+func TestAsyncQueueErrors(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithWorkers(1), WithBuffer(1), WithErrorWriter(nil))
+	var errs []error
+	for i := 0; i < 5; i++ {
+		_, err := client.Message(INFO, "hello")
+		errs = append(errs, err)
+	}
+	close(release)
+	client.Close()
+
+	if errs[0] != nil {
+		t.Errorf("first item should be queued, got: %v", errs[0])
+	}
+	if errs[4] != ErrBufferFull {
+		t.Errorf("last item should be dropped, got: %v", errs[4])
+	}
+	if _, err := client.Message(INFO, "closed"); err != ErrClosed {
+		t.Errorf("got: %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil),
+		WithRetries(0), WithTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	if _, err := client.Message(INFO, "slow"); err == nil {
+		t.Error("should give up on slow POSTs")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %s", elapsed)
+	}
+}
+
+func TestShutdownAbortsDelivery(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := New("token", WithEndpoint(server.URL), WithErrorWriter(nil), WithTimeout(0))
+	client.Message(INFO, "stuck")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got %v", err)
+	}
+	if _, err := client.Message(INFO, "late"); err != ErrClosed {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestGzip(t *testing.T) {
+	var encoding string
+	var message string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(reader).Decode(&body)
+		data := body["data"].(map[string]interface{})
+		message = data["body"].(map[string]interface{})["message"].(map[string]interface{})["body"].(string)
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil),
+		WithRetries(0), WithGzip(0))
+	if _, err := client.Message(INFO, "compressed"); err != nil {
+		t.Fatal(err)
+	}
+
+	if encoding != "gzip" {
+		t.Errorf("got Content-Encoding: %q", encoding)
+	}
+	if message != "compressed" {
+		t.Errorf("got message: %q", message)
+	}
+}
+
+func TestCompressThreshold(t *testing.T) {
+	payload, encoding, _ := compress([]byte("{}"), 1024)
+	if encoding != "" || string(payload) != "{}" {
+		t.Errorf("should not compress small payloads, got %q", encoding)
+	}
+	if _, encoding, _ = compress([]byte("{}"), -1); encoding != "" {
+		t.Errorf("should not compress when disabled, got %q", encoding)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"err": 1, "message": "invalid access token"}`))
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil))
+	_, err := client.Message(INFO, "hello")
+
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusForbidden || apiErr.Message != "invalid access token" {
+		t.Errorf("got %#v", apiErr)
+	}
+	if !errors.Is(err, ErrHTTPError(http.StatusForbidden)) {
+		t.Error("should unwrap to the HTTP status")
+	}
+	if retryable(err) {
+		t.Error("should not retry API errors")
+	}
+}
+
+func TestAPIErrorWithOKStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"err": 1, "message": "project is disabled"}`))
+	}))
+	defer server.Close()
+
+	client := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil))
+	if _, err := client.Message(INFO, "hello"); err == nil || err.Error() != "rollbar: service returned status: 200: project is disabled" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestDeliveryErrorHandler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	var dropped []*Item
+	var reasons []error
+	client := New("token", WithEndpoint(server.URL), WithSync(), WithErrorWriter(nil),
+		WithDeliveryErrorHandler(func(item *Item, err error) {
+			dropped = append(dropped, item)
+			reasons = append(reasons, err)
+		}))
+
+	uuid, _ := client.Message(INFO, "rejected")
+	client.Close()
+	client.Message(INFO, "late")
+
+	if len(dropped) != 2 {
+		t.Fatalf("got %d dropped items", len(dropped))
+	}
+	if dropped[0].UUID != uuid || reasons[0] != ErrHTTPError(http.StatusBadRequest) {
+		t.Errorf("got %q: %v", dropped[0].Title, reasons[0])
+	}
+	if dropped[1].Title != "late" || reasons[1] != ErrClosed {
+		t.Errorf("got %q: %v", dropped[1].Title, reasons[1])
+	}
+}
+
+// stuckClient returns a Client with a single worker stuck POSTing a first
+// item and a queue of one, already holding a second item.
+func stuckClient(t *testing.T, opts ...Option) (*Client, func()) {
+	arrived := make(chan bool, 10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- true
+		<-release
+	}))
+
+	opts = append([]Option{WithEndpoint(server.URL), WithWorkers(1), WithBuffer(1), WithErrorWriter(nil)}, opts...)
+	client := New("token", opts...)
+	client.Message(INFO, "in flight")
+	<-arrived
+	client.Message(INFO, "queued")
+
+	return client, func() {
+		close(release)
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestQueuePolicyDropOldest(t *testing.T) {
+	var dropped []string
+	client, cleanup := stuckClient(t, WithQueuePolicy(DropOldest, 0),
+		WithDeliveryErrorHandler(func(item *Item, err error) { dropped = append(dropped, item.Title) }))
+	defer cleanup()
+
+	if _, err := client.Message(INFO, "newest"); err != nil {
+		t.Errorf("got %v", err)
+	}
+	if len(dropped) != 1 || dropped[0] != "queued" {
+		t.Errorf("got dropped items %v", dropped)
+	}
+}
+
+func TestQueuePolicyBlock(t *testing.T) {
+	client, cleanup := stuckClient(t, WithQueuePolicy(Block, 20*time.Millisecond))
+	defer cleanup()
+
+	start := time.Now()
+	if _, err := client.Message(INFO, "blocked"); err != ErrBufferFull {
+		t.Errorf("got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("should wait for room in the queue, waited %s", elapsed)
+	}
+}
+
+func TestQueuePolicyBlockShutdown(t *testing.T) {
+	client, cleanup := stuckClient(t, WithQueuePolicy(Block, 0))
+	defer cleanup()
+
+	reported := make(chan error)
+	go func() {
+		_, err := client.Message(INFO, "blocked")
+		reported <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got %v", err)
+	}
+	if err := <-reported; err != ErrClosed {
+		t.Errorf("should drop items blocked on a full queue on shutdown, got %v", err)
+	}
+}
+
+// slowTransport fails every Send after a delay, and records whether it was
+// closed while a Send was in progress.
+type slowTransport struct {
+	fakeTransport
+	sending, closedWhileSending int32
+}
+
+func (t *slowTransport) Send(ctx context.Context, payload []byte) error {
+	atomic.AddInt32(&t.sending, 1)
+	defer atomic.AddInt32(&t.sending, -1)
+	time.Sleep(100 * time.Millisecond)
+	return errors.New("unavailable")
+}
+
+func (t *slowTransport) Close() error {
+	if atomic.LoadInt32(&t.sending) > 0 {
+		atomic.StoreInt32(&t.closedWhileSending, 1)
+	}
+	return nil
+}
+
+func TestCloseDuringSyncSend(t *testing.T) {
+	transport := &slowTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithRetries(0), WithErrorWriter(nil))
+
+	sent := make(chan error)
+	go func() {
+		_, err := client.Message(INFO, "slow")
+		sent <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-sent; err == nil {
+		t.Error("should return the delivery error")
+	}
+	if atomic.LoadInt32(&transport.closedWhileSending) != 0 {
+		t.Error("should not close the Transport while a synchronous POST is in progress")
+	}
+}
+
+type fakeTransport struct {
+	payloads        [][]byte
+	flushed, closed int
+}
+
+func (t *fakeTransport) Send(ctx context.Context, payload []byte) error {
+	t.payloads = append(t.payloads, payload)
+	return nil
+}
+
+func (t *fakeTransport) Flush(ctx context.Context) error {
+	t.flushed++
+	return nil
+}
+
+func (t *fakeTransport) Close() error {
+	t.closed++
+	return nil
+}
+
+func TestPendingEmptied(t *testing.T) {
+	var p pending
+	select {
+	case <-p.emptied():
+	default:
+		t.Fatal("should be empty")
+	}
+
+	p.add(2)
+	empty := p.emptied()
+	p.add(-1)
+	select {
+	case <-empty:
+		t.Fatal("should not be empty with an item left")
+	default:
+	}
+
+	p.add(-1)
+	select {
+	case <-empty:
+	default:
+		t.Fatal("should be empty once every item is delivered")
+	}
+
+	p.add(1)
+	select {
+	case <-p.emptied():
+		t.Fatal("should not be empty after queueing again")
+	default:
+	}
+}
+
+func TestCustomTransport(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil))
+
+	if _, err := client.Message(INFO, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	client.Close()
+
+	if len(transport.payloads) != 1 {
+		t.Fatalf("got %d payloads", len(transport.payloads))
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(transport.payloads[0], &body); err != nil || body["access_token"] != "token" {
+		t.Errorf("got payload %s", transport.payloads[0])
+	}
+	if transport.flushed != 2 || transport.closed != 1 {
+		t.Errorf("got %d flushes, %d closes", transport.flushed, transport.closed)
+	}
+}
+
+type failingTransport struct {
+	err error
+}
+
+func (t failingTransport) Send(ctx context.Context, payload []byte) error { return t.err }
+func (t failingTransport) Flush(ctx context.Context) error                { return nil }
+func (t failingTransport) Close() error                                   { return nil }
@@ -0,0 +1,148 @@
+package rollbar
+
+import (
+	"encoding/json"
+)
+
+// truncation limits, applied progressively until a payload fits.
+const (
+	truncatedFrames        = 10
+	truncatedMessageLength = 255
+	truncatedSuffix        = "..."
+)
+
+var truncatedStringLengths = []int{1024, 512, 256}
+
+// truncate shrinks an encoded payload that is larger than maxSize, following
+// Rollbar's truncation strategy: it drops the middle frames of huge stack
+// traces, then the POST params of the request, then truncates long strings to
+// shorter and shorter lengths, and as a last resort keeps only the error
+// class, a truncated message and the innermost frames. It returns the
+// smallest encoding it got, which may still be too large.
+func truncate(jsonBody []byte, maxSize int) []byte {
+	if maxSize <= 0 || len(jsonBody) <= maxSize {
+		return jsonBody
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(jsonBody, &payload); err != nil {
+		return jsonBody
+	}
+	data, ok := payload["data"].(map[string]interface{})
+	if !ok {
+		return jsonBody
+	}
+
+	steps := []func(){
+		func() { truncateFrames(data) },
+		func() { truncateRequest(data) },
+	}
+	for _, length := range truncatedStringLengths {
+		length := length
+		steps = append(steps, func() { payload["data"] = truncateStrings(payload["data"], length) })
+	}
+	steps = append(steps, func() { minimizeBody(payload["data"]) })
+
+	for _, step := range steps {
+		step()
+		truncated, err := json.Marshal(payload)
+		if err != nil {
+			return jsonBody
+		}
+		jsonBody = truncated
+		if len(jsonBody) <= maxSize {
+			break
+		}
+	}
+	return jsonBody
+}
+
+// traces returns the trace sections of the given item data: its trace, or
+// every trace of its trace chain.
+func traces(data interface{}) []map[string]interface{} {
+	dataMap, _ := data.(map[string]interface{})
+	body, _ := dataMap["body"].(map[string]interface{})
+
+	if trace, ok := body["trace"].(map[string]interface{}); ok {
+		return []map[string]interface{}{trace}
+	}
+
+	var traces []map[string]interface{}
+	chain, _ := body["trace_chain"].([]interface{})
+	for _, trace := range chain {
+		if trace, ok := trace.(map[string]interface{}); ok {
+			traces = append(traces, trace)
+		}
+	}
+	return traces
+}
+
+// truncateFrames keeps only the outermost and innermost frames of long stack
+// traces.
+func truncateFrames(data interface{}) {
+	for _, trace := range traces(data) {
+		frames, _ := trace["frames"].([]interface{})
+		if len(frames) > 2*truncatedFrames {
+			kept := append([]interface{}{}, frames[:truncatedFrames]...)
+			trace["frames"] = append(kept, frames[len(frames)-truncatedFrames:]...)
+		}
+	}
+}
+
+// truncateRequest drops the POST params of the reported request.
+func truncateRequest(data map[string]interface{}) {
+	if request, ok := data["request"].(map[string]interface{}); ok {
+		delete(request, "POST")
+		delete(request, "body")
+	}
+}
+
+// truncateStrings truncates every string longer than length runes in the
+// given decoded JSON value, in place, and returns the result.
+func truncateStrings(value interface{}, length int) interface{} {
+	switch value := value.(type) {
+	case string:
+		return truncateString(value, length)
+	case map[string]interface{}:
+		for k, v := range value {
+			value[k] = truncateStrings(v, length)
+		}
+	case []interface{}:
+		for i, v := range value {
+			value[i] = truncateStrings(v, length)
+		}
+	}
+	return value
+}
+
+func truncateString(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length-len(truncatedSuffix)]) + truncatedSuffix
+}
+
+// minimizeBody keeps only the exception class, a truncated message and the
+// innermost frame of each trace.
+func minimizeBody(data interface{}) {
+	for _, trace := range traces(data) {
+		if exception, ok := trace["exception"].(map[string]interface{}); ok {
+			if message, ok := exception["message"].(string); ok {
+				exception["message"] = truncateString(message, truncatedMessageLength)
+			}
+		}
+		if frames, ok := trace["frames"].([]interface{}); ok && len(frames) > 1 {
+			trace["frames"] = frames[:1]
+		}
+	}
+
+	dataMap, _ := data.(map[string]interface{})
+	if body, ok := dataMap["body"].(map[string]interface{}); ok {
+		if message, ok := body["message"].(map[string]interface{}); ok {
+			if text, ok := message["body"].(string); ok {
+				message["body"] = truncateString(text, truncatedMessageLength)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package rollbar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func encodedError(t *testing.T, message string, frames int) []byte {
+	stack := make(Stack, frames)
+	for i := range stack {
+		stack[i] = Frame{Filename: "deep.go", Method: "recurse", Line: i}
+	}
+	errBody, _ := errorBody(errors.New(message), stack, configuration{})
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"access_token": "token",
+		"data": map[string]interface{}{
+			"body":    errBody,
+			"request": map[string]interface{}{"url": "/", "POST": map[string]interface{}{"big": strings.Repeat("x", 2000)}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jsonBody
+}
+
+func decodedTrace(t *testing.T, jsonBody []byte) map[string]interface{} {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(jsonBody, &payload); err != nil {
+		t.Fatal(err)
+	}
+	return traces(payload["data"])[0]
+}
+
+func TestTruncateLeavesSmallPayloadsAlone(t *testing.T) {
+	jsonBody := encodedError(t, "small", 3)
+	if got := truncate(jsonBody, len(jsonBody)); string(got) != string(jsonBody) {
+		t.Error("should not touch payloads that fit")
+	}
+}
+
+func TestTruncateFrames(t *testing.T) {
+	jsonBody := encodedError(t, "deep", 1000)
+	truncated := truncate(jsonBody, len(jsonBody)-1)
+
+	frames := decodedTrace(t, truncated)["frames"].([]interface{})
+	if len(frames) != 2*truncatedFrames {
+		t.Fatalf("got %d frames", len(frames))
+	}
+	if line := frames[len(frames)-1].(map[string]interface{})["lineno"].(float64); line != 999 {
+		t.Errorf("should keep the outermost frames, got line %v", line)
+	}
+}
+
+func TestTruncateStrings(t *testing.T) {
+	jsonBody := encodedError(t, strings.Repeat("long ", 10000), 3)
+	truncated := truncate(jsonBody, 4096)
+
+	if len(truncated) > 4096 {
+		t.Errorf("got %d bytes", len(truncated))
+	}
+	exception := decodedTrace(t, truncated)["exception"].(map[string]interface{})
+	if message := exception["message"].(string); !strings.HasSuffix(message, truncatedSuffix) {
+		t.Errorf("got message: %q", message)
+	}
+}
+
+func TestTruncateMinimizesBody(t *testing.T) {
+	jsonBody := encodedError(t, strings.Repeat("long ", 10000), 1000)
+	truncated := truncate(jsonBody, 512)
+
+	trace := decodedTrace(t, truncated)
+	if frames := trace["frames"].([]interface{}); len(frames) != 1 {
+		t.Errorf("got %d frames", len(frames))
+	}
+	message := trace["exception"].(map[string]interface{})["message"].(string)
+	if len([]rune(message)) != truncatedMessageLength {
+		t.Errorf("got message of length %d", len(message))
+	}
+}
+
+func TestTruncateTraceChain(t *testing.T) {
+	stack := make(Stack, 1000)
+	for i := range stack {
+		stack[i] = Frame{Filename: "deep.go", Method: "recurse", Line: i}
+	}
+	errBody, _ := errorBody(fmt.Errorf("wrapped: %w", errors.New("cause")), stack, configuration{})
+	jsonBody, _ := json.Marshal(map[string]interface{}{"data": map[string]interface{}{"body": errBody}})
+
+	var payload map[string]interface{}
+	json.Unmarshal(truncate(jsonBody, len(jsonBody)-1), &payload)
+	chain := traces(payload["data"])
+	if len(chain) != 2 {
+		t.Fatalf("got %d traces", len(chain))
+	}
+	for _, trace := range chain {
+		if frames := trace["frames"].([]interface{}); len(frames) != 2*truncatedFrames {
+			t.Errorf("got %d frames", len(frames))
+		}
+	}
+}
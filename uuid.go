@@ -0,0 +1,26 @@
+package rollbar
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// occurrenceURL is the Rollbar web app page of an occurrence, by UUID.
+const occurrenceURL = "https://rollbar.com/occurrence/uuid/?uuid="
+
+// newUUID returns a random (version 4) UUID identifying an occurrence.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// OccurrenceURL returns the URL of the Rollbar page of the occurrence with
+// the given UUID, as returned by the reporting functions.
+func OccurrenceURL(uuid string) string {
+	return occurrenceURL + uuid
+}
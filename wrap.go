@@ -0,0 +1,56 @@
+package rollbar
+
+import (
+	"fmt"
+)
+
+// stackError attaches the stack trace of where it was created to an error.
+// Only the program counters are captured: the stack trace is built when the
+// error is reported, as configured by the Client reporting it.
+type stackError struct {
+	err error
+	pcs []uintptr
+}
+
+// Wrap returns an error that wraps err and carries the stack trace of where
+// Wrap was called, so that err is reported with it rather than with the stack
+// trace of where it is eventually reported. The returned error has the same
+// message as err and, to errors.Is and errors.As, is err. Wrap returns nil if
+// err is nil, and err itself if it already carries a stack trace.
+func Wrap(err error) error {
+	if err == nil || hasStack(err) {
+		return err
+	}
+	return &stackError{err, callers(1)}
+}
+
+// NewError is like fmt.Errorf, but the returned error carries the stack trace
+// of where NewError was called, like errors returned by Wrap. (Errorf, unlike
+// fmt.Errorf, reports an error.)
+func NewError(format string, args ...interface{}) error {
+	return &stackError{fmt.Errorf(format, args...), callers(1)}
+}
+
+func (e *stackError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *stackError) Unwrap() error {
+	return e.err
+}
+
+// StackTrace returns the program counters of where the error was created,
+// like the errors of github.com/pkg/errors.
+func (e *stackError) StackTrace() []uintptr {
+	return e.pcs
+}
+
+// hasStack reports whether err carries a stack trace of its own, without
+// building it.
+func hasStack(err error) bool {
+	if _, ok := err.(Stacker); ok {
+		return true
+	}
+	return len(stackTrace(err)) > 0
+}
@@ -0,0 +1,67 @@
+package rollbar
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func wrapHere(err error) error {
+	return Wrap(err)
+}
+
+func TestWrap(t *testing.T) {
+	if Wrap(nil) != nil {
+		t.Error("should not wrap nil")
+	}
+
+	err := wrapHere(io.EOF)
+	if err.Error() != io.EOF.Error() || !errors.Is(err, io.EOF) {
+		t.Errorf("should wrap transparently, got %v", err)
+	}
+	if errorClass(err) != errorClass(io.EOF) {
+		t.Errorf("got class %s", errorClass(err))
+	}
+	stack := errorStack(err, stackOptions{})
+	if len(stack) == 0 || stack[0].Method != "rollbar.wrapHere" {
+		t.Fatalf("got %+v", stack)
+	}
+	if Wrap(err) != err {
+		t.Error("should not wrap errors with a stack trace again")
+	}
+
+	body, fingerprint := errorBody(err, BuildStack(0), configuration{})
+	trace, ok := body["trace"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("got %+v", body)
+	}
+	if trace["frames"].(Stack).Fingerprint() != stack.Fingerprint() || fingerprint != stack.Fingerprint() {
+		t.Error("should report the error with the stack trace of where it was wrapped")
+	}
+}
+
+func TestNewError(t *testing.T) {
+	err := NewError("no replica for shard %d: %w", 3, io.ErrUnexpectedEOF)
+	if err.Error() != "no replica for shard 3: unexpected EOF" || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("got %v", err)
+	}
+	if stack := errorStack(err, stackOptions{}); len(stack) == 0 || stack[0].Method != "rollbar.TestNewError" {
+		t.Errorf("got %+v", stack)
+	}
+}
+
+func TestWrapStackOptions(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New("token", WithTransport(transport), WithSync(), WithErrorWriter(nil), WithCodeCapture(false))
+	client.Error(ERR, wrapHere(io.EOF))
+
+	frames, ok := decodedTrace(t, transport.payloads[0])["frames"].([]interface{})
+	if !ok || len(frames) == 0 {
+		t.Fatalf("got %+v", frames)
+	}
+	for _, frame := range frames {
+		if code, ok := frame.(map[string]interface{})["code"]; ok {
+			t.Errorf("should build the stack trace of wrapped errors with the Client's options, got code %v", code)
+		}
+	}
+}